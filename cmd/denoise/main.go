@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/factorion/graytracer/pkg/components"
+)
+
+// LoadGuide Load a guide buffer saved next to the color, leaving it out when it wasn't saved
+func LoadGuide(filename string) *components.FloatImage {
+	guide, err := components.LoadPFM(filename)
+	if os.IsNotExist(err) {
+		fmt.Printf("No %s, denoising without it\n", filename)
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	return guide
+}
+
+// SaveImage Save the image as a Portable Float Map when the filename ends in .pfm, and as a PNG otherwise
+func SaveImage(filename string, img *components.FloatImage) error {
+	if strings.HasSuffix(strings.ToLower(filename), ".pfm") {
+		return img.SavePFM(filename)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img.ToRGBA()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func main() {
+	denoiser := components.MakeDenoiser(5)
+	var prefix, output string
+	flag.StringVar(&prefix, "buffers", "image", "Prefix of the color, albedo, normal and depth Portable Float Maps saved by graytracer")
+	flag.StringVar(&output, "out", "denoised.png", "File to save the denoised image to, a PNG or a .pfm")
+	flag.IntVar(&denoiser.Passes, "passes", denoiser.Passes, "Passes of the filter, each twice as wide as the last")
+	flag.Float64Var(&denoiser.ColorSigma, "sigma-color", denoiser.ColorSigma, "How far apart colors are treated as an edge, 0 ignores them")
+	flag.Float64Var(&denoiser.AlbedoSigma, "sigma-albedo", denoiser.AlbedoSigma, "How far apart albedos are treated as an edge, 0 ignores them")
+	flag.Float64Var(&denoiser.NormalSigma, "sigma-normal", denoiser.NormalSigma, "How far apart normals are treated as an edge, 0 ignores them")
+	flag.Float64Var(&denoiser.DepthSigma, "sigma-depth", denoiser.DepthSigma, "How far apart relative depths are treated as an edge, 0 ignores them")
+	flag.Parse()
+	start := time.Now()
+	colorFile := prefix + "-color.pfm"
+	img, err := components.LoadPFM(colorFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", colorFile, err)
+		os.Exit(1)
+	}
+	guides := components.Guides{Albedo: LoadGuide(prefix + "-albedo.pfm"), Normal: LoadGuide(prefix + "-normal.pfm"),
+		Depth: LoadGuide(prefix + "-depth.pfm")}
+	denoised, err := denoiser.Denoise(img, guides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error denoising %s: %v\n", colorFile, err)
+		os.Exit(1)
+	}
+	if err := SaveImage(output, denoised); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Denoised %s into %s : %v\n", colorFile, output, time.Since(start))
+}
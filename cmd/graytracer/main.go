@@ -8,13 +8,16 @@ import (
 	"math"
 	"os"
 	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/factorion/graytracer/pkg/components"
 	"github.com/factorion/graytracer/pkg/patterns"
 	"github.com/factorion/graytracer/pkg/primitives"
 	"github.com/factorion/graytracer/pkg/shapes"
+	"github.com/factorion/graytracer/pkg/stats"
 
 	"github.com/schollz/progressbar/v3"
 )
@@ -32,16 +35,90 @@ var ch chan (XY)
 var wg sync.WaitGroup
 var bar progressbar.ProgressBar
 var prog_count = uint64(0)
+var debugMode components.DebugMode
+var costs []uint64
+var sampler components.AdaptiveSampler
+var samplesTaken uint64
+var colors *components.FloatImage
+var guides components.Guides
+
+// collector Counts and times of the render, which every rendering goroutine merges its counts into
+var collector stats.Collector
+
+// PixelColor Calculate the color of a pixel for the debug mode, heatmap costs are kept until the largest is known.
+// The features of normal renders are kept in features when given. The pixel is traced through tracer, the world
+// counting for the goroutine
+func PixelColor(tracer *components.World, pixel XY, ray primitives.Ray, features *components.Features) patterns.RGB {
+	switch debugMode {
+	case components.DebugHeatmap:
+		costs[(int(pixel.Y)*img.Bounds().Dx())+int(pixel.X)] = tracer.Cost(ray)
+		return *patterns.MakeRGB(0, 0, 0)
+	case components.DebugNormals:
+		return tracer.NormalColor(ray)
+	case components.DebugUV:
+		return tracer.UVColor(ray)
+	case components.DebugDepth:
+		return tracer.DepthColor(ray, 5)
+	case components.DebugSamples:
+		_, count := sampler.SamplePixel(tracer, camera, pixel.X, pixel.Y, 5, nil)
+		atomic.AddUint64(&samplesTaken, uint64(count))
+		// Every pixel takes at least MinSamples, so the map spans from there to MaxSamples
+		return components.HeatColor(float64(count-sampler.MinSamples), float64(sampler.MaxSamples-sampler.MinSamples))
+	}
+	if sampler.MaxSamples > 1 {
+		color, count := sampler.SamplePixel(tracer, camera, pixel.X, pixel.Y, 5, features)
+		atomic.AddUint64(&samplesTaken, uint64(count))
+		return color
+	}
+	if features != nil {
+		color, pixelFeatures := tracer.ColorAndFeaturesAt(ray, 5)
+		*features = pixelFeatures
+		return color
+	}
+	return tracer.ColorAt(ray, 5)
+}
+
+// ColorHeatmap Color every pixel by its cost relative to the most costly pixel
+func ColorHeatmap() {
+	maximum := uint64(0)
+	for _, cost := range costs {
+		if cost > maximum {
+			maximum = cost
+		}
+	}
+	width := img.Bounds().Dx()
+	for index, cost := range costs {
+		img.Set(index%width, index/width, components.HeatColor(float64(cost), float64(maximum)).ToImageRGBA())
+	}
+	fmt.Printf("Most bounding box and intersection tests for a pixel : %d\n", maximum)
+}
+
+// WorkerCounts Make the counts of a rendering goroutine when counting, which it traces through world.Counting and
+// merges into the collector when done
+func WorkerCounts(counting bool) *stats.Counts {
+	if !counting {
+		return nil
+	}
+	return &stats.Counts{}
+}
 
 // RenderPixel Goroutine to render in a multi-threaded environment
-func RenderPixel() {
+func RenderPixel(counting bool) {
 	defer wg.Done()
+	counts := WorkerCounts(counting)
+	defer collector.Merge(counts)
+	tracer := world.Counting(counts)
 	open := true
 	xyray := XY{}
 	for open {
 		xyray, open = <-ch
 		ray := camera.RayForPixel(xyray.X, xyray.Y)
-		col := world.ColorAt(ray, 5)
+		var features *components.Features
+		if colors != nil {
+			features = &components.Features{}
+		}
+		col := PixelColor(tracer, xyray, ray, features)
+		GatherBuffers(xyray, col, features)
 		imgMutex.Lock()
 		img.Set(int(xyray.X), int(xyray.Y), col.ToImageRGBA())
 		prog_count++
@@ -53,32 +130,109 @@ func RenderPixel() {
 	}
 }
 
-// MakeHex Make a Hex group object
-func MakeHex(mat patterns.Material, transform primitives.Matrix) shapes.Shape {
+// RenderPacket Goroutine to render 2 by 2 tiles of pixels, tracing the primary rays of each tile as one packet
+func RenderPacket(width, height uint64, counting bool) {
+	defer wg.Done()
+	counts := WorkerCounts(counting)
+	defer collector.Merge(counts)
+	tracer := world.Counting(counts)
+	var packetColors []patterns.RGB
+	pixels := make([]XY, 0, shapes.PacketSize)
+	rays := make([]primitives.Ray, 0, shapes.PacketSize)
+	for tile := range ch {
+		pixels, rays = pixels[:0], rays[:0]
+		for y := tile.Y; y < tile.Y+2 && y < height; y++ {
+			for x := tile.X; x < tile.X+2 && x < width; x++ {
+				pixels = append(pixels, XY{X: x, Y: y})
+				rays = append(rays, camera.RayForPixel(x, y))
+			}
+		}
+		if colors == nil {
+			packetColors = tracer.ColorAtPacket(rays, 5)
+		} else {
+			var features []components.Features
+			packetColors, features = tracer.ColorAndFeaturesAtPacket(rays, 5)
+			for index, pixel := range pixels {
+				GatherBuffers(pixel, packetColors[index], &features[index])
+			}
+		}
+		imgMutex.Lock()
+		for index, pixel := range pixels {
+			img.Set(int(pixel.X), int(pixel.Y), packetColors[index].ToImageRGBA())
+		}
+		prog_count += uint64(len(pixels))
+		if prog_count >= 100 {
+			bar.Add(int(prog_count))
+			prog_count = 0
+		}
+		imgMutex.Unlock()
+	}
+}
+
+// GatherBuffers Keep the unclamped color and the features found while tracing a pixel when the render is denoised
+// or saved
+func GatherBuffers(pixel XY, col patterns.RGB, features *components.Features) {
+	if colors == nil {
+		return
+	}
+	imgMutex.Lock()
+	colors.Set(int(pixel.X), int(pixel.Y), col)
+	guides.Set(int(pixel.X), int(pixel.Y), *features)
+	imgMutex.Unlock()
+}
+
+// FinishBuffers Save the color and guide buffers as Portable Float Maps when a prefix is given, then denoise the
+// image when any passes are asked for
+func FinishBuffers(prefix string, passes int) {
+	if prefix != "" {
+		for _, buffer := range []struct {
+			name  string
+			image *components.FloatImage
+		}{{"color", colors}, {"albedo", guides.Albedo}, {"normal", guides.Normal}, {"depth", guides.Depth}} {
+			filename := prefix + "-" + buffer.name + ".pfm"
+			if err := buffer.image.SavePFM(filename); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving %s: %v\n", filename, err)
+			}
+		}
+	}
+	if passes <= 0 {
+		return
+	}
+	start := time.Now()
+	denoised, err := components.MakeDenoiser(passes).Denoise(colors, guides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error denoising: %v\n", err)
+		return
+	}
+	img = denoised.ToRGBA()
+	collector.DenoiseTime.Since(start)
+	fmt.Printf("Denoised : %v\n", time.Since(start))
+}
+
+// hexPrototype Hexagon shared by every hex instance
+var hexPrototype shapes.Shape
+
+// MakeHexPrototype Make a Hex group object with the default material
+func MakeHexPrototype() shapes.Shape {
 	// Hex group
 	hex := shapes.MakeGroup()
-	hex.SetTransform(transform)
 	for i := 0.0; i < 6; i++ {
 		corner := shapes.MakeSphere()
-		corner.SetMaterial(mat)
 		corner.SetTransform(primitives.RotationY(i * math.Pi / 3).Multiply(
 			primitives.Translation(0, 0, -1).Multiply(
 				primitives.Scaling(0.25, 0.25, 0.25))))
 		edge := shapes.MakeCylinder(true)
-		edge.SetMaterial(mat)
 		edge.SetTransform(primitives.RotationY(i * math.Pi / 3).Multiply(
 			primitives.Translation(0, 0, -1).Multiply(
 				primitives.RotationY(-math.Pi / 6).Multiply(
 					primitives.RotationZ(-math.Pi / 2).Multiply(
 						primitives.Scaling(0.25, 1, 0.25))))))
 		top := shapes.MakeCone(true)
-		top.SetMaterial(mat)
 		top.SetTransform(primitives.RotationY(i * math.Pi / 3).Multiply(
 			primitives.Translation(0, 1, 0).Multiply(
 				primitives.RotationX(math.Pi / 4).Multiply(
 					primitives.Scaling(0.25, math.Sqrt(2), 0.25)))))
 		bottom := shapes.MakeCone(true)
-		bottom.SetMaterial(mat)
 		bottom.SetTransform(primitives.RotationY(i * math.Pi / 3).Multiply(
 			primitives.Translation(0, -1, 0).Multiply(
 				primitives.RotationX(3 * math.Pi / 4).Multiply(
@@ -91,16 +245,128 @@ func MakeHex(mat patterns.Material, transform primitives.Matrix) shapes.Shape {
 	return hex
 }
 
+// MakeHex Make a Hex object, placing an instance of the shared hexagon
+func MakeHex(mat patterns.Material, transform primitives.Matrix) shapes.Shape {
+	if hexPrototype == nil {
+		hexPrototype = MakeHexPrototype()
+	}
+	hex := shapes.MakeInstance(hexPrototype)
+	hex.SetTransform(transform)
+	hex.SetMaterial(mat)
+	return hex
+}
+
+// LoadObj Load an OBJ file into a group centred at the origin and scaled to fit a 4 unit box,
+// exiting when the file can't be read
+func LoadObj(filename string, smooth, cache bool) shapes.Shape {
+	mats := map[string]patterns.Material{components.Default_name: patterns.MakeDefaultMaterial()}
+	start := time.Now()
+	if !cache {
+		parsed := components.ParseObjFile(filename, smooth, mats)
+		fmt.Printf("Parsed %s : %v\n", filename, time.Since(start))
+		CollectLoadTime(filename, time.Since(start), parsed.Faces)
+		return FitMeshes(parsed.Faces)
+	}
+	parsed, cached, err := components.LoadObjFile(filename, filename+".cache", smooth, mats)
+	if parsed == nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the cache of %s: %v\n", filename, err)
+	}
+	if cached {
+		fmt.Printf("Loaded %s from its cache : %v\n", filename, time.Since(start))
+		collector.ParseTime.Since(start)
+	} else {
+		fmt.Printf("Parsed %s : %v\n", filename, time.Since(start))
+		CollectLoadTime(filename, time.Since(start), parsed.Faces)
+	}
+	return FitMeshes(parsed.Faces)
+}
+
+// CollectLoadTime Print how long building the hierarchies of the meshes took, and split the time spent loading the
+// file into the parse and build times of the collector
+func CollectLoadTime(filename string, elapsed time.Duration, meshes map[string]*shapes.Mesh) {
+	var total time.Duration
+	for _, mesh := range meshes {
+		total += mesh.BuildTime()
+	}
+	fmt.Printf("Built hierarchies for %s : %v\n", filename, total)
+	collector.ParseTime.Add(elapsed - total)
+	collector.BuildTime.Add(total)
+}
+
+// FitMeshes Group meshes, centred at the origin and scaled to fit a 4 unit box
+func FitMeshes(meshes map[string]*shapes.Mesh) shapes.Shape {
+	var bounds *shapes.Bounds
+	for _, mesh := range meshes {
+		if bounds == nil {
+			bounds = mesh.GetBounds()
+		} else if meshBounds := mesh.GetBounds(); meshBounds != nil {
+			bounds.AddBounds(meshBounds)
+		}
+	}
+	model := shapes.MakeGroup()
+	if bounds != nil {
+		size := bounds.Max.Subtract(bounds.Min)
+		scale := 4 / math.Max(size.X, math.Max(size.Y, math.Max(size.Z, primitives.EPSILON)))
+		center := bounds.Min.Add(size.Scalar(0.5))
+		model.SetTransform(primitives.Scaling(scale, scale, scale).Multiply(
+			primitives.Translation(-center.X, -center.Y, -center.Z)))
+	}
+	// Meshes are added after the transform is set so the bounds of the group include it
+	names := make([]string, 0, len(meshes))
+	for name := range meshes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	group := make([]shapes.Shape, len(names))
+	for index, name := range names {
+		group[index] = meshes[name]
+	}
+	model.AddShapes(group...)
+	return model
+}
+
 func main() {
 	fmt.Println("Starting render")
 	var width, height uint64
-	var threads int
-	var fov float64
+	var threads, samples, minSamples, denoise int
+	var fov, threshold float64
+	var flatten, smooth, cache, packets, counting bool
+	var objFile, statsFile, debugName, buffers string
 	flag.IntVar(&threads, "threads", runtime.NumCPU(), "Number of threads for rendering")
 	flag.Uint64Var(&width, "width", 320, "Width of rendered image")
 	flag.Uint64Var(&height, "height", 180, "Height of rendered image")
 	flag.Float64Var(&fov, "fov", math.Pi/3, "Field of View (in Radians)")
+	flag.BoolVar(&flatten, "flatten", false, "Bake group transforms into the shapes they hold before rendering")
+	flag.StringVar(&objFile, "obj", "", "Wavefront OBJ file to add to the scene, scaled to fit a 4 unit box at the origin")
+	flag.BoolVar(&smooth, "smooth", true, "Use the vertex normals of the OBJ file")
+	flag.BoolVar(&cache, "cache", true, "Keep the parsed OBJ file and its hierarchy next to it to load faster next time")
+	flag.BoolVar(&packets, "packets", false, "Trace the primary rays of 2 by 2 tiles of pixels together")
+	flag.BoolVar(&counting, "stats", true, "Count rays and intersection tests, which slows rendering a little")
+	flag.StringVar(&statsFile, "stats-json", "", "File to write the render statistics to as JSON")
+	flag.StringVar(&debugName, "debug-mode", "", "Color pixels by heatmap, bounds, normals, uv, depth or samples instead")
+	flag.IntVar(&samples, "samples", 1, "Most samples for each pixel, more than 1 samples noisy pixels until they settle")
+	flag.IntVar(&minSamples, "min-samples", 4, "Samples for each pixel before checking whether it has settled")
+	flag.Float64Var(&threshold, "threshold", 0.01, "Largest standard error of a color channel for a pixel to be settled")
+	flag.IntVar(&denoise, "denoise", 0, "Passes of the edge-aware denoiser over the render, 0 leaves it as traced")
+	flag.StringVar(&buffers, "buffers", "", "Prefix of Portable Float Maps to save the color, albedo, normal and depth to")
 	flag.Parse()
+	var err error
+	if debugMode, err = components.ParseDebugMode(debugName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sampler = components.MakeAdaptiveSampler(minSamples, samples, threshold)
+	if debugMode == components.DebugSamples && sampler.MaxSamples <= 1 {
+		fmt.Fprintln(os.Stderr, "debug mode samples needs -samples above 1, every pixel takes one sample otherwise")
+		os.Exit(1)
+	}
+	// Packets only trace the first hits of normal renders through the center of each pixel
+	packets = packets && sampler.MaxSamples == 1 &&
+		(debugMode == components.DebugNone || debugMode == components.DebugBounds)
 	camera = components.MakeCamera(width, height, fov)
 	camera.ViewTransform(primitives.MakePoint(-6, 6, -10),
 		primitives.MakePoint(6, 0, 6),
@@ -109,6 +375,14 @@ func main() {
 	imgMutex = &sync.Mutex{}
 	start := time.Now()
 	img = image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(width), int(height)}})
+	if debugMode == components.DebugHeatmap {
+		costs = make([]uint64, width*height)
+	}
+	// Only normal renders are denoised or saved, bounds are drawn over the denoised image
+	if (denoise > 0 || buffers != "") && (debugMode == components.DebugNone || debugMode == components.DebugBounds) {
+		colors = components.MakeFloatImage(int(width), int(height))
+		guides = components.MakeGuides(int(width), int(height))
+	}
 	world = components.MakeWorld()
 	world.SetBackground(*patterns.MakeRGB(0, 0, 0))
 	light1 := components.PointLight{Intensity: patterns.MakeRGB(1, 1, 1),
@@ -203,22 +477,69 @@ func main() {
 	cube17.SetMaterial(white_mat)
 	cube17.SetTransform(primitives.Translation(-0.5, -8.5, 8).Multiply(primitives.Scaling(3.5, 3.5, 3.5).Multiply(primitives.Scaling(0.5, 0.5, 0.5).Multiply(primitives.Translation(1, -1, 1)))))
 	world.AddObject(cube17)
+	if objFile != "" {
+		world.AddObject(LoadObj(objFile, smooth, cache))
+	}
+	world.Compile(flatten)
 	fmt.Println("Creating goroutines")
 	wg.Add(threads)
 	for t := 0; t < threads; t++ {
-		go RenderPixel()
+		if packets {
+			go RenderPacket(width, height, counting)
+		} else {
+			go RenderPixel(counting)
+		}
 	}
 	fmt.Println("Starting pixel calculations")
+	renderStart := time.Now()
 	bar = *progressbar.Default(int64(width * height))
-	for y := uint64(0); y < height; y++ {
-		for x := uint64(0); x < width; x++ {
+	step := uint64(1)
+	if packets {
+		step = 2
+	}
+	for y := uint64(0); y < height; y += step {
+		for x := uint64(0); x < width; x += step {
 			ch <- XY{X: x, Y: y}
 		}
 	}
 	close(ch)
 	wg.Wait()
 	bar.Add(int(prog_count))
+	// Denoising is timed on its own
+	collector.RenderTime.Since(renderStart)
+	if colors != nil {
+		FinishBuffers(buffers, denoise)
+	}
+	switch debugMode {
+	case components.DebugHeatmap:
+		ColorHeatmap()
+	case components.DebugBounds:
+		components.DrawBounds(img, camera, world)
+	}
+	if samplesTaken > 0 {
+		fmt.Printf("Average samples per pixel : %.2f\n", float64(samplesTaken)/float64(width*height))
+	}
 	fmt.Printf("Render finished : %v\n", time.Since(start))
+	encodeStart := time.Now()
 	f, _ := os.Create("image.png")
 	png.Encode(f, img)
+	f.Close()
+	collector.EncodeTime.Since(encodeStart)
+	PrintStats(statsFile)
+}
+
+// PrintStats Print the counts and times of the render, also writing them as JSON when a file is given
+func PrintStats(filename string) {
+	report := collector.Report()
+	fmt.Print(report)
+	if filename == "" {
+		return
+	}
+	data, err := report.JSON()
+	if err == nil {
+		err = os.WriteFile(filename, data, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing statistics to %s: %v\n", filename, err)
+	}
 }
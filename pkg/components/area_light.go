@@ -0,0 +1,32 @@
+package components
+
+import (
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// AreaLight Light emitted from a surface, approximated by a grid of point lights spread over it
+type AreaLight struct {
+	Intensity      *patterns.RGB
+	Surface        shapes.Surface
+	USteps, VSteps int
+}
+
+// MakeAreaLight Make an area light sampling the surface in a grid of uSteps by vSteps point lights
+func MakeAreaLight(surface shapes.Surface, intensity *patterns.RGB, uSteps, vSteps int) *AreaLight {
+	return &AreaLight{Intensity: intensity, Surface: surface, USteps: uSteps, VSteps: vSteps}
+}
+
+// Lights Expand the area light into point lights at the center of each grid cell, sharing the intensity
+func (al *AreaLight) Lights() []PointLight {
+	lights := make([]PointLight, 0, al.USteps*al.VSteps)
+	intensity := al.Intensity.Scale(1.0 / float64(al.USteps*al.VSteps))
+	for v := 0; v < al.VSteps; v++ {
+		for u := 0; u < al.USteps; u++ {
+			position := al.Surface.SamplePoint((float64(u)+0.5)/float64(al.USteps),
+				(float64(v)+0.5)/float64(al.VSteps))
+			lights = append(lights, PointLight{Intensity: &intensity, Position: position})
+		}
+	}
+	return lights
+}
@@ -0,0 +1,55 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func TestAreaLightLights(t *testing.T) {
+	surface, err := shapes.MakeRectangle(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surface.SetTransform(primitives.Translation(0, 5, 0))
+	light := components.MakeAreaLight(surface, patterns.MakeRGB(1, 1, 1), 2, 2)
+	positions := []primitives.PV{primitives.MakePoint(-0.5, 5, -0.5), primitives.MakePoint(0.5, 5, -0.5),
+		primitives.MakePoint(-0.5, 5, 0.5), primitives.MakePoint(0.5, 5, 0.5)}
+	lights := light.Lights()
+	if len(lights) != len(positions) {
+		t.Fatalf("Expected %v lights, got %v", len(positions), len(lights))
+	}
+	for index, pointLight := range lights {
+		if !pointLight.Position.Equals(positions[index]) {
+			t.Errorf("Expected position %v, got %v", positions[index], pointLight.Position)
+		}
+		if !pointLight.Intensity.Equals(*patterns.MakeRGB(0.25, 0.25, 0.25)) {
+			t.Errorf("Expected intensity %v, got %v", patterns.MakeRGB(0.25, 0.25, 0.25), pointLight.Intensity)
+		}
+	}
+}
+
+func TestAreaLightEmitterShadow(t *testing.T) {
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 1, -5), Direction: primitives.MakeVector(0, -1, 5).Normalize()}
+	colors := []patterns.RGB{}
+	for _, geometry := range []bool{false, true} {
+		world := components.MakeWorld()
+		world.AddObject(shapes.MakePlane())
+		emitter := shapes.MakeDisk()
+		emitter.SetTransform(primitives.Translation(0, 5, 0))
+		if geometry {
+			world.AddObject(emitter)
+		}
+		world.AddAreaLight(components.MakeAreaLight(emitter, patterns.MakeRGB(1, 1, 1), 4, 4))
+		colors = append(colors, world.ColorAt(ray, 5))
+	}
+	if !colors[0].Equals(colors[1]) {
+		t.Errorf("Expected emitting surface to cast no shadow %v, got %v", colors[0], colors[1])
+	}
+	if colors[0].Equals(*patterns.MakeRGB(0.1, 0.1, 0.1)) {
+		t.Errorf("Expected surface lit by the area light, got %v", colors[0])
+	}
+}
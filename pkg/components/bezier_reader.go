@@ -0,0 +1,101 @@
+package components
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// Split a line of the patch format on commas and whitespace
+func bezierFields(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// ParseBezierFile Parse patches in Newell's teapot format: the number of patches, a line of 16 one-based
+// vertex indices for each patch, the number of vertices, then a line of x, y, z for each vertex
+func ParseBezierFile(filename string) ([]*shapes.BezierPatch, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lines := [][]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := bezierFields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Read the count at the start of a section
+	line := 0
+	count := func() (int, error) {
+		if line >= len(lines) || len(lines[line]) != 1 {
+			return 0, fmt.Errorf("%s:%d: expected a count", filename, line+1)
+		}
+		value, err := strconv.Atoi(lines[line][0])
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("%s:%d: invalid count %q", filename, line+1, lines[line][0])
+		}
+		line++
+		return value, nil
+	}
+
+	patchCount, err := count()
+	if err != nil {
+		return nil, err
+	}
+	indices := make([][16]int, patchCount)
+	for patch := range indices {
+		if line >= len(lines) || len(lines[line]) != 16 {
+			return nil, fmt.Errorf("%s:%d: expected 16 vertex indices", filename, line+1)
+		}
+		for index, field := range lines[line] {
+			if indices[patch][index], err = strconv.Atoi(field); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid vertex index %q", filename, line+1, field)
+			}
+		}
+		line++
+	}
+	vertexCount, err := count()
+	if err != nil {
+		return nil, err
+	}
+	vertices := make([]primitives.PV, vertexCount)
+	for vertex := range vertices {
+		if line >= len(lines) || len(lines[line]) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected 3 coordinates", filename, line+1)
+		}
+		var values [3]float64
+		for index, field := range lines[line] {
+			if values[index], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid coordinate %q", filename, line+1, field)
+			}
+		}
+		vertices[vertex] = primitives.MakePoint(values[0], values[1], values[2])
+		line++
+	}
+
+	patches := make([]*shapes.BezierPatch, patchCount)
+	for patch, patchIndices := range indices {
+		var points [16]primitives.PV
+		for index, vertex := range patchIndices {
+			if vertex < 1 || vertex > vertexCount {
+				return nil, fmt.Errorf("%s: patch %d uses missing vertex %d", filename, patch+1, vertex)
+			}
+			points[index] = vertices[vertex-1]
+		}
+		patches[patch] = shapes.MakeBezierPatch(points)
+	}
+	return patches, nil
+}
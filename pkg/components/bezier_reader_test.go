@@ -0,0 +1,42 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestParseBezierFile(t *testing.T) {
+	patches, err := components.ParseBezierFile("patches.bpt")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("Incorrect amount of patches, found %v, expected 2", len(patches))
+	}
+	tables := []struct {
+		patch, index int
+		point        primitives.PV
+	}{
+		{0, 0, primitives.MakePoint(0, 0, 0)},
+		{0, 5, primitives.MakePoint(1, 1, 0.5)},
+		{0, 15, primitives.MakePoint(3, 3, 0)},
+		{1, 0, primitives.MakePoint(3, 0, 0)},
+		{1, 14, primitives.MakePoint(1, 3, 0)},
+	}
+	for _, table := range tables {
+		point := patches[table.patch].Points()[table.index]
+		if !point.Equals(table.point) {
+			t.Errorf("Patch %v point %v, expected %v, got %v", table.patch, table.index, table.point, point)
+		}
+	}
+}
+
+func TestParseBezierFileErrors(t *testing.T) {
+	for _, filename := range []string{"missing.bpt", "missing_vertex.bpt", "gibberish.obj"} {
+		if _, err := components.ParseBezierFile(filename); err == nil {
+			t.Errorf("Expected an error parsing %v", filename)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package components
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// BumpDelta Distance used for the finite differences of bump and normal maps
+const BumpDelta = 0.0001
+
+// faceEyeMinimum Least a tilted normal faces the eye by, so lighting and reflections see the front of the surface
+const faceEyeMinimum = 0.01
+
+// TangentBasis Return two vectors perpendicular to the normal and each other
+func TangentBasis(normal primitives.PV) (primitives.PV, primitives.PV) {
+	axis := primitives.MakeVector(1, 0, 0)
+	if math.Abs(normal.X) > 0.9 {
+		axis = primitives.MakeVector(0, 1, 0)
+	}
+	tangent := normal.CrossProduct(axis).Normalize()
+	return tangent, normal.CrossProduct(tangent)
+}
+
+// height Sample the bump pattern as a scalar through the UV mapping of the shape, or at the point in object-space
+// for solid patterns
+func height(shape shapes.Shape, point primitives.PV) float64 {
+	bump := shape.Material().Bump
+	if patterns.IsSolid(bump) {
+		return bump.ColorAt(shape.WorldToObjectPV(point)).Intensity()
+	}
+	return bump.ColorAt(shape.UVMapping(point)).Intensity()
+}
+
+// uvTangents Estimate the direction of increasing U and V on the surface with finite differences
+func uvTangents(shape shapes.Shape, point, normal primitives.PV) (primitives.PV, primitives.PV) {
+	t0, b0 := TangentBasis(normal)
+	uv := shape.UVMapping(point)
+	uvT := shape.UVMapping(point.Add(t0.Scalar(BumpDelta)))
+	uvB := shape.UVMapping(point.Add(b0.Scalar(BumpDelta)))
+	a := (uvT.X - uv.X) / BumpDelta
+	b := (uvB.X - uv.X) / BumpDelta
+	c := (uvT.Y - uv.Y) / BumpDelta
+	d := (uvB.Y - uv.Y) / BumpDelta
+	det := (a * d) - (b * c)
+	if math.Abs(det) < primitives.EPSILON {
+		return t0, b0
+	}
+	dpdu := t0.Scalar(d / det).Add(b0.Scalar(-c / det))
+	dpdv := t0.Scalar(-b / det).Add(b0.Scalar(a / det))
+	tangent := dpdu.Subtract(normal.Scalar(normal.DotProduct(dpdu))).Normalize()
+	bitangent := normal.CrossProduct(tangent)
+	if bitangent.DotProduct(dpdv) < 0 {
+		bitangent = bitangent.Negate()
+	}
+	return tangent, bitangent
+}
+
+// PerturbNormal Apply the bump and normal maps of the shape's material to a normal
+func PerturbNormal(shape shapes.Shape, point, normal primitives.PV) primitives.PV {
+	mat := shape.Material()
+	if mat.NormalMap != nil {
+		tangent, bitangent := uvTangents(shape, point, normal)
+		sample := mat.NormalMap.ColorAt(shape.UVMapping(point))
+		normal = tangent.Scalar((2 * sample.Red()) - 1).Add(
+			bitangent.Scalar((2 * sample.Green()) - 1)).Add(
+			normal.Scalar((2 * sample.Blue()) - 1)).Normalize()
+	}
+	if mat.Bump != nil {
+		tangent, bitangent := TangentBasis(normal)
+		dt := (height(shape, point.Add(tangent.Scalar(BumpDelta))) -
+			height(shape, point.Subtract(tangent.Scalar(BumpDelta)))) / (2 * BumpDelta)
+		db := (height(shape, point.Add(bitangent.Scalar(BumpDelta))) -
+			height(shape, point.Subtract(bitangent.Scalar(BumpDelta)))) / (2 * BumpDelta)
+		normal = normal.Subtract(tangent.Scalar(mat.BumpScale * dt).Add(
+			bitangent.Scalar(mat.BumpScale * db))).Normalize()
+	}
+	return normal
+}
+
+// FaceEye Tilt a normal facing away from the eye, as a strong bump or normal map can at grazing angles, towards the
+// eye until it just faces it
+func FaceEye(normal, eyeVector primitives.PV) primitives.PV {
+	facing := normal.DotProduct(eyeVector)
+	if facing > 0 {
+		return normal
+	}
+	return normal.Subtract(eyeVector.Scalar(facing - faceEyeMinimum)).Normalize()
+}
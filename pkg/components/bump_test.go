@@ -0,0 +1,152 @@
+package components_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func TestTangentBasis(t *testing.T) {
+	tables := []struct {
+		normal primitives.PV
+	}{
+		{primitives.MakeVector(0, 1, 0)},
+		{primitives.MakeVector(1, 0, 0)},
+		{primitives.MakeVector(0.5773502691896258, 0.5773502691896258, 0.5773502691896258)},
+	}
+	for _, table := range tables {
+		tangent, bitangent := components.TangentBasis(table.normal)
+		if tangent.DotProduct(table.normal) > primitives.EPSILON ||
+			bitangent.DotProduct(table.normal) > primitives.EPSILON ||
+			tangent.DotProduct(bitangent) > primitives.EPSILON {
+			t.Errorf("Basis %v, %v is not orthogonal to %v", tangent, bitangent, table.normal)
+		}
+		if !tangent.CrossProduct(bitangent).Equals(table.normal) {
+			t.Errorf("Basis %v, %v is not right handed around %v", tangent, bitangent, table.normal)
+		}
+	}
+}
+
+func TestPerturbNormal(t *testing.T) {
+	gradient := patterns.MakeGradient(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1))
+	tables := []struct {
+		mat           patterns.Material
+		point, result primitives.PV
+	}{
+		{patterns.MakeDefaultMaterial(),
+			primitives.MakePoint(0.5, 0, 0.5), primitives.MakeVector(0, 1, 0)},
+
+		{patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), Bump: patterns.MakeRGB(0.5, 0.5, 0.5), BumpScale: 1},
+			primitives.MakePoint(0.5, 0, 0.5), primitives.MakeVector(0, 1, 0)},
+
+		{patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), Bump: gradient, BumpScale: 1},
+			primitives.MakePoint(0.5, 0, 0.5), primitives.MakeVector(-0.7071067811865476, 0.7071067811865476, 0)},
+
+		{patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), NormalMap: patterns.MakeRGB(0.5, 0.5, 1)},
+			primitives.MakePoint(0.5, 0, 0.5), primitives.MakeVector(0, 1, 0)},
+
+		{patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), NormalMap: patterns.MakeRGB(1, 0.5, 0.5)},
+			primitives.MakePoint(0.5, 0, 0.5), primitives.MakeVector(1, 0, 0)},
+
+		{patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), NormalMap: patterns.MakeRGB(0.5, 1, 0.5)},
+			primitives.MakePoint(0.5, 0, 0.5), primitives.MakeVector(0, 0, 1)},
+	}
+	for _, table := range tables {
+		plane := shapes.MakePlane()
+		plane.SetMaterial(table.mat)
+		normal := components.PerturbNormal(plane, table.point, plane.Normal(table.point, 0, 0))
+		if !primitives.MakeVector(normal.X, normal.Y, normal.Z).Equals(table.result) {
+			t.Errorf("Expected %v, got %v", table.result, normal)
+		}
+	}
+}
+
+func TestPrepareComputationsBump(t *testing.T) {
+	plane := shapes.MakePlane()
+	plane.SetMaterial(patterns.Material{Pat: patterns.MakeRGB(1, 1, 1),
+		Bump: patterns.MakeGradient(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1)), BumpScale: 1})
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.5, 1, 0.5), Direction: primitives.MakeVector(0, -1, 0)}
+	xs := plane.Intersect(ray)
+	comps := components.PrepareComputations(xs[0], ray, xs)
+	expected := primitives.MakeVector(-0.7071067811865476, 0.7071067811865476, 0)
+	if !comps.NormalVector.Equals(expected) {
+		t.Errorf("Expected normal %v, got %v", expected, comps.NormalVector)
+	}
+	if !comps.OverPoint.Equals(primitives.MakePoint(0.5, primitives.EPSILON, 0.5)) {
+		t.Errorf("Expected over point along the geometric normal, got %v", comps.OverPoint)
+	}
+	if !comps.ReflectVector.Equals(primitives.MakeVector(-1, 0, 0)) {
+		t.Errorf("Expected reflection off the bumped normal, got %v", comps.ReflectVector)
+	}
+}
+
+func TestFaceEye(t *testing.T) {
+	eye := primitives.MakeVector(0, 0, -1)
+	tables := []struct {
+		normal primitives.PV
+	}{
+		{primitives.MakeVector(0, 0, -1)},
+		{primitives.MakeVector(0, 1, 0)},
+		{primitives.MakeVector(0.6, 0, 0.8)},
+		{primitives.MakeVector(0, 0, 1)},
+	}
+	for _, table := range tables {
+		normal := components.FaceEye(table.normal, eye)
+		if normal.DotProduct(eye) <= 0 || math.Abs(normal.Magnitude()-1) > primitives.EPSILON {
+			t.Errorf("%v, expected a unit normal facing the eye, got %v", table.normal, normal)
+		}
+	}
+	if normal := components.FaceEye(primitives.MakeVector(0.6, 0, -0.8), eye); !normal.Equals(primitives.MakeVector(0.6, 0, -0.8)) {
+		t.Errorf("Expected a normal already facing the eye to be kept, got %v", normal)
+	}
+}
+
+func TestPrepareComputationsGrazingBump(t *testing.T) {
+	plane := shapes.MakePlane()
+	plane.SetMaterial(patterns.Material{Pat: patterns.MakeRGB(1, 1, 1),
+		Bump: patterns.MakeGradient(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1)), BumpScale: 10})
+	// Looking down the slope of a steep bump, which on its own tilts the normal away from the eye
+	direction := primitives.MakeVector(-1, -0.1, 0).Normalize()
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.5, 0, 0.5).Subtract(direction.Scalar(2)), Direction: direction}
+	xs := plane.Intersect(ray)
+	comps := components.PrepareComputations(xs[0], ray, xs)
+	if comps.NormalVector.DotProduct(comps.EyeVector) <= 0 {
+		t.Errorf("Expected the normal %v to face the eye %v", comps.NormalVector, comps.EyeVector)
+	}
+	if comps.ReflectVector.DotProduct(comps.NormalVector) <= 0 {
+		t.Errorf("Expected the reflection %v to leave the surface", comps.ReflectVector)
+	}
+}
+
+func TestPrepareComputationsWithoutMaps(t *testing.T) {
+	// The interpolated normal is perpendicular to the eye, which a bump would have tilted towards it
+	triangle := shapes.MakeSmoothTriangle(primitives.MakePoint(0, 1, 0), primitives.MakePoint(-1, 0, 0),
+		primitives.MakePoint(1, 0, 0), primitives.MakeVector(1, 0, 0), primitives.MakeVector(1, 0, 0),
+		primitives.MakeVector(1, 0, 0))
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 0.5, -2), Direction: primitives.MakeVector(0, 0, 1)}
+	xs := triangle.Intersect(ray)
+	comps := components.PrepareComputations(xs[0], ray, xs)
+	if expected := triangle.Normal(comps.Point, xs[0].U, xs[0].V); !comps.NormalVector.Equals(expected) {
+		t.Errorf("Expected the normal of the shape %v, got %v", expected, comps.NormalVector)
+	}
+}
+
+func TestPerturbNormalSolid(t *testing.T) {
+	// Rings around the y-axis only vary across the plane when sampled in object-space
+	wood := patterns.MakeWood(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1))
+	wood.SetRings(1)
+	wood.SetGrain(0, 1)
+	plane := shapes.MakePlane()
+	plane.SetTransform(primitives.Translation(0, 2, 0))
+	plane.SetMaterial(patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), Bump: wood, BumpScale: 1})
+	point := primitives.MakePoint(0.3, 2, 0.4)
+	normal := components.PerturbNormal(plane, point, plane.Normal(point, 0, 0))
+	expected := primitives.MakeVector(-0.6, 1, -0.8).Normalize()
+	if normal.Subtract(expected).Magnitude() > 1e-6 {
+		t.Errorf("Expected %v, got %v", expected, normal)
+	}
+}
@@ -49,9 +49,37 @@ func (c *Camera) ViewTransform(from, to, up primitives.PV) {
 
 // RayForPixel Calculate the ray for the given x, y coordinates
 func (c Camera) RayForPixel(x, y uint64) primitives.Ray {
+	return c.RayForSample(x, y, 0.5, 0.5)
+}
+
+// RayForSample Calculate the ray through a point within the pixel at x, y, with u and v from 0 to 1 across and down it
+func (c Camera) RayForSample(x, y uint64, u, v float64) primitives.Ray {
 	inverse, _ := c.transform.Inverse()
-	pixel := primitives.MakePoint(c.halfWidth - ((float64(x) + 0.5) * c.pixelSize),
-								  c.halfHeight - ((float64(y) + 0.5) * c.pixelSize), -1).Transform(inverse)
+	pixel := primitives.MakePoint(c.halfWidth - ((float64(x) + u) * c.pixelSize),
+								  c.halfHeight - ((float64(y) + v) * c.pixelSize), -1).Transform(inverse)
 	origin := primitives.MakePoint(0, 0, 0).Transform(inverse)
 	return primitives.Ray{Origin:origin, Direction:pixel.Subtract(origin).Normalize()}
 }
+
+// ProjectLine Find where the line between two world-space points falls on the image in pixel coordinates,
+// cutting off any part behind the camera. Returns false when all of it is behind the camera
+func (c Camera) ProjectLine(from, to primitives.PV) (x0, y0, x1, y1 float64, ok bool) {
+	// Anything closer than this to the plane of the camera would project too far off the image to draw
+	near := -primitives.EPSILON
+	start, end := from.Transform(c.transform), to.Transform(c.transform)
+	if start.Z > near && end.Z > near {
+		return 0, 0, 0, 0, false
+	}
+	if start.Z > near {
+		start = start.Add(end.Subtract(start).Scalar((near - start.Z) / (end.Z - start.Z)))
+	} else if end.Z > near {
+		end = end.Add(start.Subtract(end).Scalar((near - end.Z) / (start.Z - end.Z)))
+	}
+	project := func(point primitives.PV) (float64, float64) {
+		return ((c.halfWidth - (point.X / -point.Z)) / c.pixelSize) - 0.5,
+			   ((c.halfHeight - (point.Y / -point.Z)) / c.pixelSize) - 0.5
+	}
+	x0, y0 = project(start)
+	x1, y1 = project(end)
+	return x0, y0, x1, y1, true
+}
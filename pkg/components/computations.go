@@ -43,9 +43,14 @@ func PrepareComputations(i shapes.Intersection, ray primitives.Ray, xs shapes.In
 	} else {
 		comp.Inside = false
 	}
+	// Offset along the geometric normal so bumps don't cause self-shadowing
 	scaledNormal := comp.NormalVector.Scalar(primitives.EPSILON)
 	comp.OverPoint = comp.Point.Add(scaledNormal)
 	comp.UnderPoint = comp.Point.Subtract(scaledNormal)
+	// Only bumps can tilt the normal away from the eye, a normal from the shape is kept as it is
+	if mat := comp.Obj.Material(); mat.Bump != nil || mat.NormalMap != nil {
+		comp.NormalVector = FaceEye(PerturbNormal(comp.Obj, comp.Point, comp.NormalVector), comp.EyeVector)
+	}
 	comp.ReflectVector = ray.Direction.Reflect(comp.NormalVector)
 	var stack []shapes.Shape
 	for _, inter := range xs {
@@ -0,0 +1,222 @@
+package components
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+// DebugMode Alternative way to color the pixels of a render to look into how a scene is traced
+type DebugMode int
+
+const (
+	// DebugNone Render the scene normally
+	DebugNone DebugMode = iota
+	// DebugHeatmap Color by the bounding box and intersection tests needed to find the first hit
+	DebugHeatmap
+	// DebugBounds Render the scene normally and draw the bounds of groups, CSGs, instances and meshes over it
+	DebugBounds
+	// DebugNormals Color by the normal at the first hit
+	DebugNormals
+	// DebugUV Color by the texture coordinates at the first hit
+	DebugUV
+	// DebugDepth Color by the deepest bounce of the rays traced for a pixel
+	DebugDepth
+	// DebugSamples Color by the number of samples an adaptive sampler took for a pixel
+	DebugSamples
+)
+
+// debugModeNames Names of the debug modes as given on the command line
+var debugModeNames = []string{"none", "heatmap", "bounds", "normals", "uv", "depth", "samples"}
+
+// ParseDebugMode Find the debug mode with a name, an empty name means no debug mode
+func ParseDebugMode(name string) (DebugMode, error) {
+	if name == "" {
+		return DebugNone, nil
+	}
+	for mode, modeName := range debugModeNames {
+		if name == modeName {
+			return DebugMode(mode), nil
+		}
+	}
+	return DebugNone, fmt.Errorf("unknown debug mode %q, expected one of %v", name, debugModeNames)
+}
+
+// String Get the name of the debug mode
+func (mode DebugMode) String() string {
+	if mode < 0 || int(mode) >= len(debugModeNames) {
+		return fmt.Sprintf("DebugMode(%d)", int(mode))
+	}
+	return debugModeNames[mode]
+}
+
+// Cost Count the bounding box and intersection tests needed to find the closest hit along a ray, adding them to the
+// counts of the world as well when it is counting
+func (w World) Cost(ray primitives.Ray) uint64 {
+	counts := &stats.Counts{}
+	carried := w.counts
+	w.counts = counts
+	w.ClosestHit(ray)
+	carried.Add(counts)
+	return counts.Tests()
+}
+
+// HeatColor Color a value from blue for nothing through green to red for the maximum
+func HeatColor(value, maximum float64) patterns.RGB {
+	if maximum <= 0 {
+		return *patterns.MakeRGB(0, 0, 1)
+	}
+	heat := math.Max(0, math.Min(1, value/maximum))
+	if heat < 0.5 {
+		return *patterns.MakeRGB(0, heat*2, 1-(heat*2))
+	}
+	return *patterns.MakeRGB((heat-0.5)*2, 1-((heat-0.5)*2), 0)
+}
+
+// NormalColor Color the normal at the first hit, mapping each axis from -1 to 1 onto 0 to 1. Misses are black
+func (w World) NormalColor(ray primitives.Ray) patterns.RGB {
+	hit, ok := w.ClosestHit(ray)
+	if !ok {
+		return *patterns.MakeRGB(0, 0, 0)
+	}
+	normal := PrepareComputations(hit, ray, nil).NormalVector
+	return *patterns.MakeRGB((normal.X+1)/2, (normal.Y+1)/2, (normal.Z+1)/2)
+}
+
+// UVColor Color the texture coordinates at the first hit, u in red and v in green, repeating outside 0 to 1.
+// Misses are black
+func (w World) UVColor(ray primitives.Ray) patterns.RGB {
+	hit, ok := w.ClosestHit(ray)
+	if !ok {
+		return *patterns.MakeRGB(0, 0, 0)
+	}
+	uv := hit.Obj.UVMapping(ray.Position(hit.Distance))
+	return *patterns.MakeRGB(uv.X-math.Floor(uv.X), uv.Y-math.Floor(uv.Y), 0)
+}
+
+// DepthColor Color the deepest bounce reached by the rays traced for a pixel, from blue for the primary ray alone
+// to red for the last bounce allowed. Misses are black
+func (w World) DepthColor(ray primitives.Ray, remaining int) patterns.RGB {
+	if _, ok := w.ClosestHit(ray); !ok {
+		return *patterns.MakeRGB(0, 0, 0)
+	}
+	_, depth := w.DepthAt(ray, remaining)
+	return HeatColor(float64(depth), float64(remaining-1))
+}
+
+// Colors of the edges of group, CSG, instance and mesh bounds
+var (
+	groupBoundsColor    = color.RGBA{255, 255, 0, 255}
+	csgBoundsColor      = color.RGBA{0, 255, 255, 255}
+	instanceBoundsColor = color.RGBA{0, 255, 0, 255}
+	meshBoundsColor     = color.RGBA{255, 0, 255, 255}
+)
+
+// DrawBounds Draw the edges of the bounds of every group, CSG, instance and mesh in the world over an image seen by
+// the camera, groups in yellow, CSGs in cyan, instances in green and the root of the hierarchy of meshes in magenta.
+// The prototype of each instance is drawn where the instance places it. Bounds that don't fit in a finite box, such
+// as those holding planes, are skipped
+func DrawBounds(img *image.RGBA, camera *Camera, world *World) {
+	// toWorld takes the bounds of the shape, which are in the space of its parent, to world-space
+	var draw func(shape shapes.Shape, toWorld primitives.Matrix)
+	draw = func(shape shapes.Shape, toWorld primitives.Matrix) {
+		switch s := shape.(type) {
+		case *shapes.Group:
+			drawBox(img, camera, s, toWorld, groupBoundsColor)
+			for _, child := range s.Shapes() {
+				draw(child, toWorld.Multiply(s.Transform()))
+			}
+		case *shapes.CSG:
+			drawBox(img, camera, s, toWorld, csgBoundsColor)
+			draw(s.Left(), toWorld.Multiply(s.Transform()))
+			draw(s.Right(), toWorld.Multiply(s.Transform()))
+		case *shapes.Instance:
+			drawBox(img, camera, s, toWorld, instanceBoundsColor)
+			draw(s.Prototype(), toWorld.Multiply(s.Transform()))
+		case *shapes.Mesh:
+			drawBox(img, camera, s, toWorld, meshBoundsColor)
+		}
+	}
+	for _, object := range world.Objects() {
+		draw(object, shapes.ObjectToWorldMatrix(object.Parent()))
+	}
+}
+
+// drawBox Draw the twelve edges of the bounds of a shape, taken to world-space by toWorld
+func drawBox(img *image.RGBA, camera *Camera, shape shapes.Shape, toWorld primitives.Matrix, edgeColor color.RGBA) {
+	bounds := shape.GetBounds()
+	if bounds == nil {
+		return
+	}
+	for _, value := range []float64{bounds.Min.X, bounds.Min.Y, bounds.Min.Z, bounds.Max.X, bounds.Max.Y, bounds.Max.Z} {
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return
+		}
+	}
+	var corners [8]primitives.PV
+	for index := range corners {
+		corner := bounds.Min
+		if index&1 != 0 {
+			corner.X = bounds.Max.X
+		}
+		if index&2 != 0 {
+			corner.Y = bounds.Max.Y
+		}
+		if index&4 != 0 {
+			corner.Z = bounds.Max.Z
+		}
+		corners[index] = corner.Transform(toWorld)
+	}
+	// Edges join corners that differ along one axis
+	for index := range corners {
+		for _, axis := range []int{1, 2, 4} {
+			if index&axis == 0 {
+				if x0, y0, x1, y1, ok := camera.ProjectLine(corners[index], corners[index|axis]); ok {
+					drawLine(img, x0, y0, x1, y1, edgeColor)
+				}
+			}
+		}
+	}
+}
+
+// drawLine Draw a line between two points given in pixels, clipped to the image
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, lineColor color.RGBA) {
+	rect := img.Bounds()
+	// Clip to the image first so lines ending far outside it don't take long to step along
+	start, end := 0.0, 1.0
+	dx, dy := x1-x0, y1-y0
+	for _, edge := range [][2]float64{{-dx, x0 - float64(rect.Min.X)}, {dx, float64(rect.Max.X-1) - x0},
+		{-dy, y0 - float64(rect.Min.Y)}, {dy, float64(rect.Max.Y-1) - y0}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			start = math.Max(start, t)
+		} else {
+			end = math.Min(end, t)
+		}
+	}
+	if start > end {
+		return
+	}
+	x0, y0, x1, y1 = x0+(start*dx), y0+(start*dy), x0+(end*dx), y0+(end*dy)
+	steps := int(math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))))
+	for step := 0; step <= steps; step++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(step) / float64(steps)
+		}
+		img.SetRGBA(int(math.Round(x0+(t*(x1-x0)))), int(math.Round(y0+(t*(y1-y0)))), lineColor)
+	}
+}
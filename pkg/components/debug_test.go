@@ -0,0 +1,256 @@
+package components_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+func TestParseDebugMode(t *testing.T) {
+	tables := []struct {
+		name string
+		mode components.DebugMode
+		ok   bool
+	}{
+		{"", components.DebugNone, true},
+		{"none", components.DebugNone, true},
+		{"heatmap", components.DebugHeatmap, true},
+		{"bounds", components.DebugBounds, true},
+		{"normals", components.DebugNormals, true},
+		{"uv", components.DebugUV, true},
+		{"depth", components.DebugDepth, true},
+		{"samples", components.DebugSamples, true},
+		{"wireframe", components.DebugNone, false},
+	}
+	for _, table := range tables {
+		mode, err := components.ParseDebugMode(table.name)
+		if mode != table.mode || (err == nil) != table.ok {
+			t.Errorf("%q, expected %v and ok %v, got %v and %v", table.name, table.mode, table.ok, mode, err)
+		}
+		if table.ok && table.name != "" && mode.String() != table.name {
+			t.Errorf("Expected the name %v, got %v", table.name, mode)
+		}
+	}
+}
+
+func TestHeatColor(t *testing.T) {
+	tables := []struct {
+		value, maximum float64
+		color          patterns.RGB
+	}{
+		{0, 10, *patterns.MakeRGB(0, 0, 1)},
+		{5, 10, *patterns.MakeRGB(0, 1, 0)},
+		{10, 10, *patterns.MakeRGB(1, 0, 0)},
+		{20, 10, *patterns.MakeRGB(1, 0, 0)},
+		{2.5, 10, *patterns.MakeRGB(0, 0.5, 0.5)},
+		{3, 0, *patterns.MakeRGB(0, 0, 1)},
+	}
+	for _, table := range tables {
+		if result := components.HeatColor(table.value, table.maximum); !result.Equals(table.color) {
+			t.Errorf("%v of %v, expected %v, got %v", table.value, table.maximum, table.color, result)
+		}
+	}
+}
+
+// debugWorld Mirror floor with a sphere standing on it and a CSG beside it
+func debugWorld() *components.World {
+	world := components.MakeWorld()
+	mirror := shapes.MakePlane()
+	mirror.SetMaterial(patterns.Material{Pat: patterns.MakeRGB(1, 1, 1), Ambient: 0.1, Diffuse: 0.9, Reflective: 0.5})
+	mirror.SetTransform(primitives.Translation(0, -1, 0))
+	world.AddObject(mirror)
+	group := shapes.MakeGroup()
+	group.AddShape(shapes.MakeSphere())
+	world.AddObject(group)
+	csg := shapes.MakeCSG(shapes.INTERSECT, shapes.MakeSphere(), shapes.MakeCube())
+	csg.SetTransform(primitives.Translation(4, 0, 0))
+	world.AddObject(csg)
+	return world
+}
+
+func TestWorldCost(t *testing.T) {
+	world := debugWorld()
+	hit := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	miss := primitives.Ray{Origin: primitives.MakePoint(0, 5, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	hitCost, missCost := world.Cost(hit), world.Cost(miss)
+	// The hit tests the plane, the bounds of the group, the sphere and the bounds of the CSG, the groups and CSGs
+	// themselves aren't tests
+	if hitCost != 4 || missCost != 3 {
+		t.Errorf("Expected costs of 4 and 3, got %v and %v", hitCost, missCost)
+	}
+	counts := &stats.Counts{}
+	if cost := world.Counting(counts).Cost(hit); cost != 4 || counts.Tests() != 4 {
+		t.Errorf("Expected the cost added to the counts of the world, got %v and %v", cost, counts.Tests())
+	}
+	// Rays measured on several goroutines at once only count their own tests
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(ray primitives.Ray, expected uint64) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if cost := world.Cost(ray); cost != expected {
+					t.Errorf("Expected a cost of %v, got %v", expected, cost)
+					return
+				}
+			}
+		}([]primitives.Ray{hit, miss}[worker%2], []uint64{4, 3}[worker%2])
+	}
+	wg.Wait()
+}
+
+func TestDebugColors(t *testing.T) {
+	world := debugWorld()
+	front := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	sky := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 1, 0)}
+	// Down to the mirror and back up to the sphere
+	bounce := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, -1, 2.5).Normalize()}
+	tables := []struct {
+		name   string
+		color  func(primitives.Ray) patterns.RGB
+		ray    primitives.Ray
+		result patterns.RGB
+	}{
+		{"Normal", world.NormalColor, front, *patterns.MakeRGB(0.5, 0.5, 0)},
+		{"Normal of a miss", world.NormalColor, sky, *patterns.MakeRGB(0, 0, 0)},
+		{"UV", world.UVColor, front, *patterns.MakeRGB(0.5, 0.5, 0)},
+		{"UV of a miss", world.UVColor, sky, *patterns.MakeRGB(0, 0, 0)},
+		{"Depth", func(ray primitives.Ray) patterns.RGB { return world.DepthColor(ray, 5) }, front,
+			components.HeatColor(0, 4)},
+		{"Depth of a bounce", func(ray primitives.Ray) patterns.RGB { return world.DepthColor(ray, 5) }, bounce,
+			components.HeatColor(1, 4)},
+		{"Depth of a miss", func(ray primitives.Ray) patterns.RGB { return world.DepthColor(ray, 5) }, sky,
+			*patterns.MakeRGB(0, 0, 0)},
+	}
+	for _, table := range tables {
+		if result := table.color(table.ray); !result.Equals(table.result) {
+			t.Errorf("%v, expected %v, got %v", table.name, table.result, result)
+		}
+	}
+	if color, depth := world.DepthAt(bounce, 5); depth != 1 || !color.Equals(world.ColorAt(bounce, 5)) {
+		t.Errorf("Expected the color of the bounce at depth 1, got %v at %v", color, depth)
+	}
+}
+
+func TestProjectLine(t *testing.T) {
+	camera := components.MakeCamera(100, 50, math.Pi/3)
+	camera.ViewTransform(primitives.MakePoint(1, 2, -5), primitives.MakePoint(0, 0, 0), primitives.MakeVector(0, 1, 0))
+	for _, pixel := range [][2]uint64{{0, 0}, {10, 20}, {99, 49}, {50, 25}} {
+		ray := camera.RayForPixel(pixel[0], pixel[1])
+		x0, y0, x1, y1, ok := camera.ProjectLine(ray.Position(2), ray.Position(7))
+		if !ok || math.Abs(x0-float64(pixel[0])) > 1e-6 || math.Abs(y0-float64(pixel[1])) > 1e-6 ||
+			math.Abs(x1-float64(pixel[0])) > 1e-6 || math.Abs(y1-float64(pixel[1])) > 1e-6 {
+			t.Errorf("Pixel %v, got %v, %v to %v, %v", pixel, x0, y0, x1, y1)
+		}
+	}
+	behind := primitives.MakePoint(2, 4, -10)
+	if _, _, _, _, ok := camera.ProjectLine(behind, behind.Add(primitives.MakeVector(1, 0, 0))); ok {
+		t.Error("Expected a line behind the camera to be left out")
+	}
+	// A line running past the camera keeps only the part in front of it
+	ray := camera.RayForPixel(30, 30)
+	if x0, y0, _, _, ok := camera.ProjectLine(ray.Position(3), ray.Position(-3)); !ok ||
+		math.Abs(x0-30) > 1e-6 || math.Abs(y0-30) > 1e-6 {
+		t.Errorf("Expected the line to start at pixel 30, 30, got %v, %v", x0, y0)
+	}
+}
+
+func TestDrawBounds(t *testing.T) {
+	world := debugWorld()
+	camera := components.MakeCamera(80, 40, math.Pi/3)
+	camera.ViewTransform(primitives.MakePoint(2, 3, -8), primitives.MakePoint(2, 0, 0), primitives.MakeVector(0, 1, 0))
+	img := image.NewRGBA(image.Rect(0, 0, 80, 40))
+	components.DrawBounds(img, camera, world)
+	counts := map[color.RGBA]int{}
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 80; x++ {
+			counts[img.RGBAAt(x, y)]++
+		}
+	}
+	if counts[color.RGBA{255, 255, 0, 255}] == 0 || counts[color.RGBA{0, 255, 255, 255}] == 0 {
+		t.Errorf("Expected the edges of the group and the CSG, got %v", counts)
+	}
+	// A point on a corner of the group's bounds is drawn
+	x, y, _, _, _ := camera.ProjectLine(primitives.MakePoint(-1, 1, -1), primitives.MakePoint(-1, 1, -1))
+	if img.RGBAAt(int(math.Round(x)), int(math.Round(y))) != (color.RGBA{255, 255, 0, 255}) {
+		t.Errorf("Expected the corner at %v, %v to be drawn", x, y)
+	}
+}
+
+func BenchmarkWorldCost(b *testing.B) {
+	world := sphereGridWorld()
+	ray := primitives.Ray{Origin: primitives.MakePoint(4, 4, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	for i := 0; i < b.N; i++ {
+		world.Cost(ray)
+	}
+}
+
+func TestDrawBoundsNested(t *testing.T) {
+	outer, inner := shapes.MakeGroup(), shapes.MakeGroup()
+	outer.SetTransform(primitives.Translation(3, 0, 0))
+	inner.SetTransform(primitives.Translation(0, 1, 0))
+	outer.AddShape(inner)
+	inner.AddShape(shapes.MakeSphere())
+	world := components.MakeWorld()
+	world.AddObject(outer)
+	camera := components.MakeCamera(80, 40, math.Pi/3)
+	camera.ViewTransform(primitives.MakePoint(3, 3, -8), primitives.MakePoint(3, 1, 0), primitives.MakeVector(0, 1, 0))
+	img := image.NewRGBA(image.Rect(0, 0, 80, 40))
+	components.DrawBounds(img, camera, world)
+	// The inner group spans 2 to 4, 0 to 2 and -1 to 1 in world-space
+	for _, corner := range []primitives.PV{primitives.MakePoint(2, 0, -1), primitives.MakePoint(4, 2, -1),
+		primitives.MakePoint(4, 0, -1), primitives.MakePoint(2, 2, -1)} {
+		x, y, _, _, _ := camera.ProjectLine(corner, corner)
+		if img.RGBAAt(int(math.Round(x)), int(math.Round(y))) != (color.RGBA{255, 255, 0, 255}) {
+			t.Errorf("Expected the corner %v at %v, %v to be drawn", corner, x, y)
+		}
+	}
+}
+
+func TestDrawBoundsInstancesAndMeshes(t *testing.T) {
+	prototype := shapes.MakeGroup()
+	prototype.AddShape(shapes.MakeSphere())
+	placed := shapes.MakeInstance(prototype)
+	placed.SetTransform(primitives.Translation(3, 0, 0))
+	plain := shapes.MakeInstance(shapes.MakeSphere())
+	plain.SetTransform(primitives.Translation(-3, 0, 0))
+	buffer := &shapes.VertexBuffer{Vertices: []primitives.PV{primitives.MakePoint(-1, -1, 0),
+		primitives.MakePoint(1, -1, 0), primitives.MakePoint(1, 1, 0)}}
+	mesh := shapes.MakeMesh(buffer, []shapes.MeshFace{
+		{Vertices: [3]int32{0, 1, 2}, Normals: [3]int32{-1, -1, -1}, UVs: [3]int32{-1, -1, -1}}})
+	mesh.SetTransform(primitives.Translation(0, 3, 0))
+	world := components.MakeWorld()
+	world.AddObject(placed)
+	world.AddObject(plain)
+	world.AddObject(mesh)
+	camera := components.MakeCamera(100, 100, math.Pi/2)
+	camera.ViewTransform(primitives.MakePoint(0, 1, -8), primitives.MakePoint(0, 1, 0), primitives.MakeVector(0, 1, 0))
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	components.DrawBounds(img, camera, world)
+	tables := []struct {
+		corner primitives.PV
+		color  color.RGBA
+	}{
+		// The prototype group is drawn where the instance places it
+		{primitives.MakePoint(2, -1, -1), color.RGBA{255, 255, 0, 255}},
+		{primitives.MakePoint(4, 1, -1), color.RGBA{255, 255, 0, 255}},
+		{primitives.MakePoint(-4, -1, -1), color.RGBA{0, 255, 0, 255}},
+		{primitives.MakePoint(-2, 1, -1), color.RGBA{0, 255, 0, 255}},
+		{primitives.MakePoint(-1, 2, 0), color.RGBA{255, 0, 255, 255}},
+		{primitives.MakePoint(1, 4, 0), color.RGBA{255, 0, 255, 255}},
+	}
+	for _, table := range tables {
+		x, y, _, _, _ := camera.ProjectLine(table.corner, table.corner)
+		if drawn := img.RGBAAt(int(math.Round(x)), int(math.Round(y))); drawn != table.color {
+			t.Errorf("Expected the corner %v at %v, %v to be %v, got %v", table.corner, x, y, table.color, drawn)
+		}
+	}
+}
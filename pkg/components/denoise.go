@@ -0,0 +1,151 @@
+package components
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Features Albedo, normal and depth of the first hit seen through a pixel, which show the denoiser where edges are.
+// Misses take the background as their albedo, with no normal and a depth of 0
+type Features struct {
+	Albedo patterns.RGB
+	Normal primitives.PV
+	Depth  float64
+}
+
+// hitFeatures Gather the features of a hit from its computations
+func hitFeatures(comp Computations) Features {
+	return Features{Albedo: comp.Obj.Material().Pat.ColorAt(comp.Obj.UVMapping(comp.Point)),
+		Normal: comp.NormalVector, Depth: comp.Distance}
+}
+
+// Guides Albedo, normal and depth buffers of a render, the normal in the three channels and the depth in all of them.
+// Any buffer may be left out
+type Guides struct {
+	Albedo, Normal, Depth *FloatImage
+}
+
+// MakeGuides Make empty guide buffers for an image
+func MakeGuides(width, height int) Guides {
+	return Guides{Albedo: MakeFloatImage(width, height), Normal: MakeFloatImage(width, height),
+		Depth: MakeFloatImage(width, height)}
+}
+
+// Set Store the features of a pixel in every buffer
+func (g Guides) Set(x, y int, features Features) {
+	g.Albedo.Set(x, y, features.Albedo)
+	g.Normal.Set(x, y, *patterns.MakeRGB(features.Normal.X, features.Normal.Y, features.Normal.Z))
+	g.Depth.Set(x, y, *patterns.MakeRGB(features.Depth, features.Depth, features.Depth))
+}
+
+// Denoiser Edge-avoiding À-Trous wavelet filter. Each pass blurs with a 5 by 5 kernel whose taps are twice as far
+// apart as in the pass before, weighting every tap by how alike its color and guides are to those of the pixel, so
+// noise is smoothed within surfaces but not across their edges
+type Denoiser struct {
+	// Passes Number of passes, the kernel spans 4 << (Passes-1) pixels in the last
+	Passes int
+	// ColorSigma, AlbedoSigma, NormalSigma and DepthSigma How far apart colors, albedos, normals and relative depths
+	// can be before a tap is mostly ignored. The color sigma halves every pass as the image gets smoother.
+	// A sigma of 0 leaves its buffer out
+	ColorSigma, AlbedoSigma, NormalSigma, DepthSigma float64
+}
+
+// MakeDenoiser Make a denoiser running a number of passes, with sigmas that suit colors from 0 to 1
+func MakeDenoiser(passes int) Denoiser {
+	return Denoiser{Passes: passes, ColorSigma: 0.5, AlbedoSigma: 0.1, NormalSigma: 0.3, DepthSigma: 0.05}
+}
+
+// denoiseKernel Weights of the B3 spline the taps of each pass are spread by
+var denoiseKernel = [5]float64{1.0 / 16, 1.0 / 4, 3.0 / 8, 1.0 / 4, 1.0 / 16}
+
+// Denoise Filter a noisy image guided by the buffers given, which must be the same size
+func (d Denoiser) Denoise(img *FloatImage, guides Guides) (*FloatImage, error) {
+	for _, guide := range []struct {
+		name   string
+		buffer *FloatImage
+	}{{"albedo", guides.Albedo}, {"normal", guides.Normal}, {"depth", guides.Depth}} {
+		if guide.buffer != nil && (guide.buffer.Width != img.Width || guide.buffer.Height != img.Height) {
+			return nil, fmt.Errorf("%s buffer is %dx%d, expected %dx%d", guide.name, guide.buffer.Width,
+				guide.buffer.Height, img.Width, img.Height)
+		}
+	}
+	current := &FloatImage{Width: img.Width, Height: img.Height, Pix: append([]float64(nil), img.Pix...)}
+	next := MakeFloatImage(img.Width, img.Height)
+	for pass := 0; pass < d.Passes; pass++ {
+		step := 1 << pass
+		colorSigma := d.ColorSigma / float64(step)
+		// Rows only read the previous pass, so they can be filtered in any order
+		var wg sync.WaitGroup
+		workers := runtime.GOMAXPROCS(0)
+		for worker := 0; worker < workers; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for y := worker; y < img.Height; y += workers {
+					for x := 0; x < img.Width; x++ {
+						d.filter(current, next, guides, x, y, step, colorSigma)
+					}
+				}
+			}(worker)
+		}
+		wg.Wait()
+		current, next = next, current
+	}
+	return current, nil
+}
+
+// filter Average the taps around a pixel step pixels apart into the next pass
+func (d Denoiser) filter(current, next *FloatImage, guides Guides, x, y, step int, colorSigma float64) {
+	p := ((y * current.Width) + x) * 3
+	var sum [3]float64
+	total := 0.0
+	for ky, kernelY := range denoiseKernel {
+		qy := y + ((ky - 2) * step)
+		if qy < 0 || qy >= current.Height {
+			continue
+		}
+		for kx, kernelX := range denoiseKernel {
+			qx := x + ((kx - 2) * step)
+			if qx < 0 || qx >= current.Width {
+				continue
+			}
+			q := ((qy * current.Width) + qx) * 3
+			// Every term is scaled by its sigma and summed, so one exponential weighs them all
+			distance := 0.0
+			if colorSigma > 0 {
+				distance += squaredDistance(current.Pix, p, q) / (colorSigma * colorSigma)
+			}
+			if guides.Albedo != nil && d.AlbedoSigma > 0 {
+				distance += squaredDistance(guides.Albedo.Pix, p, q) / (d.AlbedoSigma * d.AlbedoSigma)
+			}
+			if guides.Normal != nil && d.NormalSigma > 0 {
+				distance += squaredDistance(guides.Normal.Pix, p, q) / (d.NormalSigma * d.NormalSigma)
+			}
+			if guides.Depth != nil && d.DepthSigma > 0 {
+				depthP, depthQ := guides.Depth.Pix[p], guides.Depth.Pix[q]
+				if farthest := math.Max(math.Abs(depthP), math.Abs(depthQ)); farthest > 0 {
+					relative := (depthP - depthQ) / farthest
+					distance += (relative * relative) / (d.DepthSigma * d.DepthSigma)
+				}
+			}
+			weight := kernelX * kernelY * math.Exp(-distance)
+			sum[0] += weight * current.Pix[q]
+			sum[1] += weight * current.Pix[q+1]
+			sum[2] += weight * current.Pix[q+2]
+			total += weight
+		}
+	}
+	// The pixel itself always has a weight, so the total is never 0
+	next.Pix[p], next.Pix[p+1], next.Pix[p+2] = sum[0]/total, sum[1]/total, sum[2]/total
+}
+
+// squaredDistance Squared distance between the three channels at two indices of a buffer
+func squaredDistance(pix []float64, p, q int) float64 {
+	r, g, b := pix[p]-pix[q], pix[p+1]-pix[q+1], pix[p+2]-pix[q+2]
+	return (r * r) + (g * g) + (b * b)
+}
@@ -0,0 +1,163 @@
+package components_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+// featuresEqual Compare two sets of features with an amount for approximation
+func featuresEqual(a, b components.Features) bool {
+	return a.Albedo.Equals(b.Albedo) && a.Normal.Equals(b.Normal) && math.Abs(a.Depth-b.Depth) < primitives.EPSILON
+}
+
+func TestWorldColorAndFeaturesAt(t *testing.T) {
+	world := components.MakeWorld()
+	world.SetBackground(*patterns.MakeRGB(0.1, 0.2, 0.3))
+	world.AddLight(components.PointLight{Intensity: patterns.MakeRGB(1, 1, 1),
+		Position: primitives.MakePoint(-10, 10, -10)})
+	sphere := shapes.MakeSphere()
+	sphere.SetMaterial(patterns.Material{Pat: patterns.MakeRGB(0.8, 0.2, 0.1), Ambient: 0.1, Diffuse: 0.9})
+	world.AddObject(sphere)
+	front := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	up := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 1, 0)}
+	color, hit := world.ColorAndFeaturesAt(front, 5)
+	if !hit.Albedo.Equals(*patterns.MakeRGB(0.8, 0.2, 0.1)) || !hit.Normal.Equals(primitives.MakeVector(0, 0, -1)) ||
+		math.Abs(hit.Depth-4) > primitives.EPSILON {
+		t.Errorf("Expected the features of the front of the sphere, got %+v", hit)
+	}
+	if expected := world.ColorAt(front, 5); !color.Equals(expected) {
+		t.Errorf("Expected the color seen by ColorAt %v, got %v", expected, color)
+	}
+	_, miss := world.ColorAndFeaturesAt(up, 5)
+	if !miss.Albedo.Equals(*patterns.MakeRGB(0.1, 0.2, 0.3)) || miss.Normal != (primitives.PV{}) || miss.Depth != 0 {
+		t.Errorf("Expected the background without a normal or depth, got %+v", miss)
+	}
+	// Packets find the same features
+	colors, features := world.ColorAndFeaturesAtPacket([]primitives.Ray{front, up}, 5)
+	if !featuresEqual(features[0], hit) || !featuresEqual(features[1], miss) || !colors[0].Equals(color) {
+		t.Errorf("Expected the features of each ray, got %+v", features)
+	}
+	// A single sample through the center of a pixel sees the same features as its ray
+	camera := components.MakeCamera(11, 11, math.Pi/3)
+	camera.ViewTransform(primitives.MakePoint(0, 0, -5), primitives.MakePoint(0, 0, 0), primitives.MakeVector(0, 1, 0))
+	var sampled components.Features
+	components.MakeAdaptiveSampler(1, 1, 0).SamplePixel(world, camera, 5, 5, 5, &sampled)
+	if !featuresEqual(sampled, hit) {
+		t.Errorf("Expected the sample to see %+v, got %+v", hit, sampled)
+	}
+	// Gathering the features doesn't trace anything more
+	plain, gathered := &stats.Counts{}, &stats.Counts{}
+	world.Counting(plain).ColorAt(front, 5)
+	world.Counting(gathered).ColorAndFeaturesAt(front, 5)
+	if !reflect.DeepEqual(plain, gathered) {
+		t.Errorf("Expected the same counts as ColorAt %+v, got %+v", plain, gathered)
+	}
+	guides := components.MakeGuides(2, 1)
+	guides.Set(1, 0, hit)
+	if albedo, normal, depth := guides.Albedo.At(1, 0), guides.Normal.At(1, 0), guides.Depth.At(1, 0); !albedo.Equals(hit.Albedo) ||
+		!normal.Equals(*patterns.MakeRGB(0, 0, -1)) || !depth.Equals(*patterns.MakeRGB(4, 4, 4)) {
+		t.Errorf("Expected the features in the buffers, got %v, %v and %v", albedo, normal, depth)
+	}
+}
+
+// edgeImage Noisy image of a dark left half and a bright right half, with the albedo of each half as its guide
+func edgeImage(size int, noise float64) (*components.FloatImage, components.Guides) {
+	random := rand.New(rand.NewSource(7))
+	img := components.MakeFloatImage(size, size)
+	guides := components.Guides{Albedo: components.MakeFloatImage(size, size)}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			value := 0.2
+			if x >= size/2 {
+				value = 0.8
+			}
+			guides.Albedo.Set(x, y, *patterns.MakeRGB(value, value, value))
+			img.Set(x, y, *patterns.MakeRGB(value+((random.Float64()-0.5)*noise), value+((random.Float64()-0.5)*noise),
+				value+((random.Float64()-0.5)*noise)))
+		}
+	}
+	return img, guides
+}
+
+// edgeError Root mean square difference of an image from the halves it is meant to show
+func edgeError(img *components.FloatImage) float64 {
+	total := 0.0
+	for index, value := range img.Pix {
+		expected := 0.2
+		if (index/3)%img.Width >= img.Width/2 {
+			expected = 0.8
+		}
+		total += (value - expected) * (value - expected)
+	}
+	return math.Sqrt(total / float64(len(img.Pix)))
+}
+
+func TestDenoise(t *testing.T) {
+	img, guides := edgeImage(32, 0.2)
+	denoised, err := components.MakeDenoiser(5).Denoise(img, guides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, after := edgeError(img), edgeError(denoised)
+	if after > before/3 {
+		t.Errorf("Expected the noise to be mostly removed, went from %v to %v", before, after)
+	}
+	// The pixels either side of the edge keep to their own half
+	for y := 0; y < 32; y++ {
+		if left, right := denoised.At(15, y), denoised.At(16, y); left.Red() > 0.3 || right.Red() < 0.7 {
+			t.Errorf("Row %v, expected the edge to stay sharp, got %v and %v", y, left, right)
+		}
+	}
+	// Without a guide or the colors to go by the edge is blurred away
+	blur := components.Denoiser{Passes: 5}
+	if blurred, _ := blur.Denoise(img, components.Guides{}); blurred.At(15, 16).Red() < 0.3 {
+		t.Errorf("Expected a plain blur to cross the edge, got %v", blurred.At(15, 16))
+	}
+	if img.Pix[0] == denoised.Pix[0] {
+		t.Error("Expected the noisy image to be left as it was")
+	}
+}
+
+func TestDenoiseFlat(t *testing.T) {
+	img := components.MakeFloatImage(9, 7)
+	for index := range img.Pix {
+		img.Pix[index] = 0.25 * float64(1+(index%3))
+	}
+	for _, passes := range []int{0, 1, 5} {
+		denoised, err := components.MakeDenoiser(passes).Denoise(img, components.MakeGuides(9, 7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for index, value := range denoised.Pix {
+			if math.Abs(value-img.Pix[index]) > 1e-12 {
+				t.Errorf("%v passes, value %v, expected %v, got %v", passes, index, img.Pix[index], value)
+				break
+			}
+		}
+		if &denoised.Pix[0] == &img.Pix[0] {
+			t.Errorf("%v passes, expected a new image", passes)
+		}
+	}
+	if _, err := components.MakeDenoiser(1).Denoise(img, components.Guides{Depth: components.MakeFloatImage(7, 9)}); err == nil {
+		t.Error("Expected an error for a guide of another size")
+	}
+}
+
+func BenchmarkDenoise(b *testing.B) {
+	img, guides := edgeImage(128, 0.2)
+	guides.Normal = components.MakeFloatImage(128, 128)
+	guides.Depth = components.MakeFloatImage(128, 128)
+	denoiser := components.MakeDenoiser(5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		denoiser.Denoise(img, guides)
+	}
+}
@@ -0,0 +1,184 @@
+package components
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// objCacheVersion Version of the cache header and group list, raised whenever either changes
+const objCacheVersion uint32 = 1
+
+// objCacheMagic Marks the start of an OBJ cache
+var objCacheMagic = [4]byte{'G', 'T', 'O', 'C'}
+
+// objCacheHeader Identifies the OBJ contents and settings a cache was built from
+type objCacheHeader struct {
+	Magic   [4]byte
+	Version uint32
+	Hash    [sha256.Size]byte
+	Smooth  bool
+}
+
+// LoadObjFile Parse an OBJ file like ParseObjFile, loading the meshes and their hierarchies from cacheFilename
+// instead when it was written from the same file contents and settings, and writing it after parsing otherwise.
+// Returns whether the cache was used, and the parsed file alongside any error writing the cache
+func LoadObjFile(filename, cacheFilename string, smooth bool, mats map[string]patterns.Material) (*parsed_obj, bool, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, false, err
+	}
+	header := objCacheHeader{Magic: objCacheMagic, Version: objCacheVersion, Hash: sha256.Sum256(contents),
+		Smooth: smooth}
+	// A missing, stale or damaged cache is simply built again
+	if result, err := readObjCache(cacheFilename, header, mats); err == nil {
+		return result, true, nil
+	}
+	result, materials := parseObj(filename, smooth, mats)
+	return result, false, writeObjCache(cacheFilename, header, result, materials)
+}
+
+// readObjCache Load the groups of an OBJ from a cache written with the same header
+func readObjCache(cacheFilename string, header objCacheHeader, mats map[string]patterns.Material) (*parsed_obj, error) {
+	f, err := os.Open(cacheFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var cached objCacheHeader
+	if err := binary.Read(r, binary.LittleEndian, &cached); err != nil {
+		return nil, err
+	}
+	if cached != header {
+		return nil, errors.New("cache was written for another file, version or settings")
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	names := make([][2]string, 0, minCount(count))
+	for len(names) < int(count) {
+		group, err := readCacheString(r)
+		if err != nil {
+			return nil, err
+		}
+		material, err := readCacheString(r)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, [2]string{group, material})
+	}
+	buffer, meshes, err := shapes.ReadMeshes(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(meshes) != len(names) {
+		return nil, errors.New("cache has a different number of groups and meshes")
+	}
+	result := &parsed_obj{Vertices: buffer.Vertices, Normals: buffer.Normals, UVs: buffer.UVs,
+		Faces: make(map[string]*shapes.Mesh)}
+	for index, mesh := range meshes {
+		mesh.SetMaterial(mats[names[index][1]])
+		result.Faces[names[index][0]] = mesh
+	}
+	return result, nil
+}
+
+// writeObjCache Write the groups of a parsed OBJ to a temporary file, only replacing the cache once it is complete
+func writeObjCache(cacheFilename string, header objCacheHeader, result *parsed_obj, materials map[string]string) error {
+	f, err := os.CreateTemp(filepath.Dir(cacheFilename), filepath.Base(cacheFilename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	// Temporary files are only readable by their owner, the cache is as readable as any other output
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := writeObjCacheTo(f, header, result, materials); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), cacheFilename)
+}
+
+// writeObjCacheTo Write the header, the group and material names, then the meshes in the same order
+func writeObjCacheTo(w io.Writer, header objCacheHeader, result *parsed_obj, materials map[string]string) error {
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	groups := make([]string, 0, len(result.Faces))
+	for group := range result.Faces {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	if err := binary.Write(bw, binary.LittleEndian, uint32(len(groups))); err != nil {
+		return err
+	}
+	meshes := make([]*shapes.Mesh, len(groups))
+	for index, group := range groups {
+		if err := writeCacheString(bw, group); err != nil {
+			return err
+		}
+		if err := writeCacheString(bw, materials[group]); err != nil {
+			return err
+		}
+		meshes[index] = result.Faces[group]
+	}
+	buffer := &shapes.VertexBuffer{Vertices: result.Vertices, Normals: result.Normals, UVs: result.UVs}
+	if len(meshes) > 0 {
+		buffer = meshes[0].Buffer()
+	}
+	if err := shapes.WriteMeshes(bw, buffer, meshes); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeCacheString Write the length of a string followed by its bytes
+func writeCacheString(w io.Writer, value string) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(value))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, value)
+	return err
+}
+
+// readCacheString Read a string written by writeCacheString
+func readCacheString(r io.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	// Names come from single lines of the OBJ, anything longer means the cache is damaged
+	if length > bufio.MaxScanTokenSize {
+		return "", errors.New("cache has a name longer than a line")
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// minCount Limit the space reserved for a count read from a cache that may be damaged
+func minCount(count uint32) int {
+	if count > 1024 {
+		return 1024
+	}
+	return int(count)
+}
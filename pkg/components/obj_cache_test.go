@@ -0,0 +1,148 @@
+package components_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// writeGridObj Write a grid of squares split between two materials, with normals and texture coordinates
+func writeGridObj(t testing.TB, filename string, size int) {
+	var obj strings.Builder
+	for z := 0; z <= size; z++ {
+		for x := 0; x <= size; x++ {
+			fmt.Fprintf(&obj, "v %d 0 %d\nvn 0 1 0\nvt %v %v\n", x, z, float64(x)/float64(size), float64(z)/float64(size))
+		}
+	}
+	for z := 0; z < size; z++ {
+		if z == 0 {
+			obj.WriteString("usemtl red\n")
+		} else if z == size/2 {
+			obj.WriteString("usemtl blue\n")
+		}
+		for x := 0; x < size; x++ {
+			corner := (z * (size + 1)) + x + 1
+			fmt.Fprintf(&obj, "f %d/%d/%d %d/%d/%d %d/%d/%d %d/%d/%d\n", corner, corner, corner, corner+1, corner+1,
+				corner+1, corner+size+2, corner+size+2, corner+size+2, corner+size+1, corner+size+1, corner+size+1)
+		}
+	}
+	if err := os.WriteFile(filename, []byte(obj.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// compareObjs Check that two parsed files have the same groups, materials and hits
+func compareObjs(t *testing.T, name string, expected, result map[string]*shapes.Mesh) {
+	if len(result) != len(expected) {
+		t.Errorf("%v, expected %v groups, got %v", name, len(expected), len(result))
+		return
+	}
+	for group, mesh := range expected {
+		cached, ok := result[group]
+		if !ok || cached.Len() != mesh.Len() {
+			t.Errorf("%v, expected group %v with %v faces", name, group, mesh.Len())
+			continue
+		}
+		if cached.Material() != mesh.Material() {
+			t.Errorf("%v, expected group %v to keep its material", name, group)
+		}
+		for x := 0.1; x < 8; x += 0.7 {
+			for z := 0.1; z < 8; z += 0.7 {
+				ray := primitives.Ray{Origin: primitives.MakePoint(x, 1, z), Direction: primitives.MakeVector(0.1, -1, 0.05)}
+				expectedHits, hits := mesh.Intersect(ray), cached.Intersect(ray)
+				if len(hits) != len(expectedHits) {
+					t.Errorf("%v, ray %v, expected %v, got %v", name, ray, expectedHits, hits)
+					continue
+				}
+				for index, hit := range hits {
+					point := ray.Position(hit.Distance)
+					if hit.Distance != expectedHits[index].Distance ||
+						!hit.Obj.UVMapping(point).Equals(expectedHits[index].Obj.UVMapping(point)) {
+						t.Errorf("%v, ray %v, expected %v, got %v", name, ray, expectedHits[index], hit)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLoadObjFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "grid.obj")
+	cacheFilename := filepath.Join(dir, "grid.cache")
+	writeGridObj(t, filename, 8)
+	colors := map[string]patterns.Material{DEFAULT: patterns.MakeDefaultMaterial(),
+		"red": {Pat: patterns.MakeRGB(1, 0, 0)}, "blue": {Pat: patterns.MakeRGB(0, 0, 1)}}
+	steps := []struct {
+		name   string
+		change func()
+		smooth bool
+		cached bool
+	}{
+		{"First load", func() {}, true, false},
+		{"Second load", func() {}, true, true},
+		{"Other settings", func() {}, false, false},
+		{"Back to smooth", func() {}, true, false},
+		{"Changed file", func() { writeGridObj(t, filename, 7) }, true, false},
+		{"Truncated cache", func() {
+			contents, _ := os.ReadFile(cacheFilename)
+			os.WriteFile(cacheFilename, contents[:len(contents)/2], 0644)
+		}, true, false},
+		{"Other version", func() {
+			contents, _ := os.ReadFile(cacheFilename)
+			contents[4]++
+			os.WriteFile(cacheFilename, contents, 0644)
+		}, true, false},
+		{"Rebuilt cache", func() {}, true, true},
+	}
+	for _, step := range steps {
+		step.change()
+		result, cached, err := components.LoadObjFile(filename, cacheFilename, step.smooth, colors)
+		if err != nil {
+			t.Fatalf("%v, unexpected error %v", step.name, err)
+		}
+		if cached != step.cached {
+			t.Errorf("%v, expected the cache to be used %v, got %v", step.name, step.cached, cached)
+		}
+		expected := components.ParseObjFile(filename, step.smooth, colors)
+		compareObjs(t, step.name, expected.Faces, result.Faces)
+		if len(result.Vertices) != len(expected.Vertices) || len(result.Normals) != len(expected.Normals) ||
+			len(result.UVs) != len(expected.UVs) {
+			t.Errorf("%v, expected the vertex buffer of the file", step.name)
+		}
+	}
+	if _, _, err := components.LoadObjFile(filepath.Join(dir, "missing.obj"), cacheFilename, true, colors); err == nil {
+		t.Error("Expected an error loading a missing file")
+	}
+}
+
+func BenchmarkParseObjFile(b *testing.B) {
+	dir := b.TempDir()
+	filename := filepath.Join(dir, "grid.obj")
+	writeGridObj(b, filename, 128)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		components.ParseObjFile(filename, true, mats)
+	}
+}
+
+func BenchmarkLoadCachedObjFile(b *testing.B) {
+	dir := b.TempDir()
+	filename := filepath.Join(dir, "grid.obj")
+	cacheFilename := filepath.Join(dir, "grid.cache")
+	writeGridObj(b, filename, 128)
+	if _, _, err := components.LoadObjFile(filename, cacheFilename, true, mats); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		components.LoadObjFile(filename, cacheFilename, true, mats)
+	}
+}
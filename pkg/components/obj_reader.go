@@ -17,7 +17,8 @@ var Default_name string = "DEFAULT"
 type parsed_obj struct {
 	Vertices []primitives.PV
 	Normals  []primitives.PV
-	Faces    map[string][]*shapes.Triangle
+	UVs      []primitives.PV
+	Faces    map[string]*shapes.Mesh
 }
 
 // Parse up to three values separated by a /
@@ -42,16 +43,37 @@ func ParseInts(value_string string) []int64 {
 	return parsed_ints
 }
 
-// Parse vertices and triangles from Wavefront OBJ file
+// Convert a one-based OBJ index, or a negative one counting back from the last element read so far, into a buffer
+// index, or -1 when it is missing or out of range
+func bufferIndex(index int64, length int) int32 {
+	if index < 0 {
+		index += int64(length) + 1
+	}
+	if (index <= 0) || (index > int64(length)) {
+		return -1
+	}
+	return int32(index - 1)
+}
+
+// Parse vertices and triangles from Wavefront OBJ file into meshes sharing one vertex buffer
 func ParseObjFile(filename string, smooth bool, mats map[string]patterns.Material) *parsed_obj {
+	result, _ := parseObj(filename, smooth, mats)
+	return result
+}
+
+// Parse an OBJ file, also returning the name of the material given to each group
+func parseObj(filename string, smooth bool, mats map[string]patterns.Material) (*parsed_obj, map[string]string) {
 	name := Default_name
 	mat_groups := make(map[string]uint64)
-	material := mats[Default_name]
+	material := Default_name
 	total_triangles := int64(0)
 	result := &parsed_obj{
 		Vertices: make([]primitives.PV, 0),
 		Normals:  make([]primitives.PV, 0),
-		Faces:    make(map[string][]*shapes.Triangle)}
+		UVs:      make([]primitives.PV, 0),
+		Faces:    make(map[string]*shapes.Mesh)}
+	faces := make(map[string][]shapes.MeshFace)
+	materials := make(map[string]string)
 
 	// Open wavefront OBJ file for parsing
 	f, err := os.Open(filename)
@@ -98,24 +120,57 @@ func ParseObjFile(filename string, smooth bool, mats map[string]patterns.Materia
 				os.Exit(1)
 			}
 			result.Normals = append(result.Normals, primitives.MakeVector(x, y, z))
+		case "vt":
+			// Parse and verify the u and optional v values of a texture coordinate
+			if field_length < 2 {
+				fmt.Fprintf(os.Stderr, "Insufficient values for a texture coordinate in line: %s", line)
+				os.Exit(1)
+			}
+			u, u_err := strconv.ParseFloat(fields[1], 64)
+			v := 0.0
+			var v_err error
+			if field_length > 2 {
+				v, v_err = strconv.ParseFloat(fields[2], 64)
+			}
+			if (u_err != nil) || (v_err != nil) {
+				fmt.Fprintf(os.Stderr, "Error converting numbers in line: %s", line)
+				os.Exit(1)
+			}
+			result.UVs = append(result.UVs, primitives.MakePoint(u, v, 0))
 		case "f":
+			if field_length < 4 {
+				fmt.Fprintf(os.Stderr, "Insufficient values for a face in line: %s", line)
+				os.Exit(1)
+			}
 			field1 := ParseInts(fields[1])
 			field2 := ParseInts(fields[2])
 			for _, p3 := range fields[3:] {
 				field3 := ParseInts(p3)
-				var triangle *shapes.Triangle
-				if field1[2] > 0 && field2[2] > 0 && field3[2] > 0 && smooth {
-					triangle = shapes.MakeSmoothTriangle(result.Vertices[field1[0]-1],
-						result.Vertices[field2[0]-1], result.Vertices[field3[0]-1],
-						result.Normals[field1[2]-1], result.Normals[field2[2]-1],
-						result.Normals[field3[2]-1])
-				} else {
-					triangle = shapes.MakeTriangle(result.Vertices[field1[0]-1],
-						result.Vertices[field2[0]-1],
-						result.Vertices[field3[0]-1])
+				// Bad vertex and normal indices are caught here rather than when a ray hits the face
+				face := shapes.MeshFace{
+					Vertices: [3]int32{bufferIndex(field1[0], len(result.Vertices)),
+						bufferIndex(field2[0], len(result.Vertices)), bufferIndex(field3[0], len(result.Vertices))},
+					Normals: [3]int32{-1, -1, -1},
+					UVs:     [3]int32{-1, -1, -1}}
+				if face.Vertices[0] < 0 || face.Vertices[1] < 0 || face.Vertices[2] < 0 {
+					fmt.Fprintf(os.Stderr, "Invalid vertex index in line: %s", line)
+					os.Exit(1)
+				}
+				if field1[2] != 0 && field2[2] != 0 && field3[2] != 0 && smooth {
+					face.Normals = [3]int32{bufferIndex(field1[2], len(result.Normals)),
+						bufferIndex(field2[2], len(result.Normals)), bufferIndex(field3[2], len(result.Normals))}
+					if face.Normals[0] < 0 || face.Normals[1] < 0 || face.Normals[2] < 0 {
+						fmt.Fprintf(os.Stderr, "Invalid vertex normal index in line: %s", line)
+						os.Exit(1)
+					}
+				}
+				uvs := [3]int32{bufferIndex(field1[1], len(result.UVs)), bufferIndex(field2[1], len(result.UVs)),
+					bufferIndex(field3[1], len(result.UVs))}
+				if uvs[0] >= 0 && uvs[1] >= 0 && uvs[2] >= 0 {
+					face.UVs = uvs
 				}
-				triangle.SetMaterial(material)
-				result.Faces[name] = append(result.Faces[name], triangle)
+				faces[name] = append(faces[name], face)
+				materials[name] = material
 				total_triangles++
 				field2 = field3
 			}
@@ -131,12 +186,12 @@ func ParseObjFile(filename string, smooth bool, mats map[string]patterns.Materia
 				os.Exit(1)
 			}
 			name = fields[1]
-			if mat, ok := mats[name]; ok {
-				material = mat
+			if _, ok := mats[name]; ok {
+				material = name
 			} else {
-				material = mats[Default_name]
+				material = Default_name
 			}
-			if _, ok := result.Faces[name]; ok {
+			if _, ok := faces[name]; ok {
 				mat_groups[name] += 1
 				name = name + strconv.FormatUint(mat_groups[name], 10)
 			} else {
@@ -144,5 +199,12 @@ func ParseObjFile(filename string, smooth bool, mats map[string]patterns.Materia
 			}
 		}
 	}
-	return result
+	// Every group shares the vertex buffer and only keeps its own face indices
+	buffer := &shapes.VertexBuffer{Vertices: result.Vertices, Normals: result.Normals, UVs: result.UVs}
+	for group, group_faces := range faces {
+		mesh := shapes.MakeMesh(buffer, group_faces)
+		mesh.SetMaterial(mats[materials[group]])
+		result.Faces[group] = mesh
+	}
+	return result, materials
 }
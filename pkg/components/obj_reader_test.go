@@ -1,10 +1,15 @@
 package components_test
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/factorion/graytracer/pkg/components"
 	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
 )
 
 var DEFAULT string = components.Default_name
@@ -38,19 +43,19 @@ func TestFaces(t *testing.T) {
 		t.Errorf("Incorrect amount of vertices, found %v, expected 4", len(parsed_obj.Vertices))
 		t.Errorf("Vertices: %v", parsed_obj.Vertices)
 	}
-	if len(parsed_obj.Faces[DEFAULT]) != 2 {
-		t.Errorf("Incorrect amount of faces, found %v, expected 2", len(parsed_obj.Faces[DEFAULT]))
+	if parsed_obj.Faces[DEFAULT].Len() != 2 {
+		t.Errorf("Incorrect amount of faces, found %v, expected 2", parsed_obj.Faces[DEFAULT].Len())
 		t.Errorf("Faces: %v", parsed_obj.Faces[DEFAULT])
 	}
-	if !(parsed_obj.Faces[DEFAULT][0].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Point2.Equals(parsed_obj.Vertices[1])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Point3.Equals(parsed_obj.Vertices[2])) {
-		t.Errorf("Incorrect vertices on first triangle: %v", parsed_obj.Faces[DEFAULT][0])
+	if !(parsed_obj.Faces[DEFAULT].Face(0).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(0).Points()[1].Equals(parsed_obj.Vertices[1])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(0).Points()[2].Equals(parsed_obj.Vertices[2])) {
+		t.Errorf("Incorrect vertices on first triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
 	}
-	if !(parsed_obj.Faces[DEFAULT][1].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Point2.Equals(parsed_obj.Vertices[2])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Point3.Equals(parsed_obj.Vertices[3])) {
-		t.Errorf("Incorrect vertices on second triangle: %v", parsed_obj.Faces[DEFAULT][0])
+	if !(parsed_obj.Faces[DEFAULT].Face(1).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(1).Points()[1].Equals(parsed_obj.Vertices[2])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(1).Points()[2].Equals(parsed_obj.Vertices[3])) {
+		t.Errorf("Incorrect vertices on second triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
 	}
 }
 
@@ -60,24 +65,24 @@ func TestPolygons(t *testing.T) {
 		t.Errorf("Incorrect amount of vertices, found %v, expected 5", len(parsed_obj.Vertices))
 		t.Errorf("Vertices: %v", parsed_obj.Vertices)
 	}
-	if len(parsed_obj.Faces[DEFAULT]) != 3 {
-		t.Errorf("Incorrect amount of faces, found %v, expected 3", len(parsed_obj.Faces[DEFAULT]))
+	if parsed_obj.Faces[DEFAULT].Len() != 3 {
+		t.Errorf("Incorrect amount of faces, found %v, expected 3", parsed_obj.Faces[DEFAULT].Len())
 		t.Errorf("Faces: %v", parsed_obj.Faces[DEFAULT])
 	}
-	if !(parsed_obj.Faces[DEFAULT][0].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Point2.Equals(parsed_obj.Vertices[1])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Point3.Equals(parsed_obj.Vertices[2])) {
-		t.Errorf("Incorrect vertices on first triangle: %v", parsed_obj.Faces[DEFAULT][0])
+	if !(parsed_obj.Faces[DEFAULT].Face(0).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(0).Points()[1].Equals(parsed_obj.Vertices[1])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(0).Points()[2].Equals(parsed_obj.Vertices[2])) {
+		t.Errorf("Incorrect vertices on first triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
 	}
-	if !(parsed_obj.Faces[DEFAULT][1].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Point2.Equals(parsed_obj.Vertices[2])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Point3.Equals(parsed_obj.Vertices[3])) {
-		t.Errorf("Incorrect vertices on second triangle: %v", parsed_obj.Faces[DEFAULT][0])
+	if !(parsed_obj.Faces[DEFAULT].Face(1).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(1).Points()[1].Equals(parsed_obj.Vertices[2])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(1).Points()[2].Equals(parsed_obj.Vertices[3])) {
+		t.Errorf("Incorrect vertices on second triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
 	}
-	if !(parsed_obj.Faces[DEFAULT][2].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][2].Point2.Equals(parsed_obj.Vertices[3])) ||
-		!(parsed_obj.Faces[DEFAULT][2].Point3.Equals(parsed_obj.Vertices[4])) {
-		t.Errorf("Incorrect vertices on third triangle: %v", parsed_obj.Faces[DEFAULT][0])
+	if !(parsed_obj.Faces[DEFAULT].Face(2).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(2).Points()[1].Equals(parsed_obj.Vertices[3])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(2).Points()[2].Equals(parsed_obj.Vertices[4])) {
+		t.Errorf("Incorrect vertices on third triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
 	}
 }
 
@@ -89,23 +94,42 @@ func TestGroups(t *testing.T) {
 		t.Errorf("Incorrect amount of vertices, found %v, expected 4", len(parsed_obj.Vertices))
 		t.Errorf("Vertices: %v", parsed_obj.Vertices)
 	}
-	if len(parsed_obj.Faces[group1]) != 1 {
-		t.Errorf("Incorrect amount of faces, found %v, expected 1", len(parsed_obj.Faces[group1]))
+	if parsed_obj.Faces[group1].Len() != 1 {
+		t.Errorf("Incorrect amount of faces, found %v, expected 1", parsed_obj.Faces[group1].Len())
 		t.Errorf("Faces: %v", parsed_obj.Faces[group1])
 	}
-	if len(parsed_obj.Faces[group2]) != 1 {
-		t.Errorf("Incorrect amount of faces, found %v, expected 1", len(parsed_obj.Faces[group2]))
+	if parsed_obj.Faces[group2].Len() != 1 {
+		t.Errorf("Incorrect amount of faces, found %v, expected 1", parsed_obj.Faces[group2].Len())
 		t.Errorf("Faces: %v", parsed_obj.Faces[group2])
 	}
-	if !(parsed_obj.Faces[group1][0].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[group1][0].Point2.Equals(parsed_obj.Vertices[1])) ||
-		!(parsed_obj.Faces[group1][0].Point3.Equals(parsed_obj.Vertices[2])) {
-		t.Errorf("Incorrect vertices on %v triangle: %v", group1, parsed_obj.Faces[group1][0])
+	if !(parsed_obj.Faces[group1].Face(0).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[group1].Face(0).Points()[1].Equals(parsed_obj.Vertices[1])) ||
+		!(parsed_obj.Faces[group1].Face(0).Points()[2].Equals(parsed_obj.Vertices[2])) {
+		t.Errorf("Incorrect vertices on %v triangle: %v", group1, parsed_obj.Faces[group1].Face(0).Points())
 	}
-	if !(parsed_obj.Faces[group2][0].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[group2][0].Point2.Equals(parsed_obj.Vertices[2])) ||
-		!(parsed_obj.Faces[group2][0].Point3.Equals(parsed_obj.Vertices[3])) {
-		t.Errorf("Incorrect vertices on %v triangle: %v", group2, parsed_obj.Faces[group2][0])
+	if !(parsed_obj.Faces[group2].Face(0).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[group2].Face(0).Points()[1].Equals(parsed_obj.Vertices[2])) ||
+		!(parsed_obj.Faces[group2].Face(0).Points()[2].Equals(parsed_obj.Vertices[3])) {
+		t.Errorf("Incorrect vertices on %v triangle: %v", group2, parsed_obj.Faces[group2].Face(0).Points())
+	}
+}
+
+func TestGroupsBakedShareBuffer(t *testing.T) {
+	parsed_obj := components.ParseObjFile("groups.obj", false, mats)
+	first, second := parsed_obj.Faces["FirstGroup"], parsed_obj.Faces["SecondGroup"]
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Translation(0, 0, 5))
+	group.AddShape(first)
+	group.AddShape(second)
+	world := components.MakeWorld()
+	world.AddObject(group)
+	world.Compile(true)
+	if first.Buffer() != second.Buffer() {
+		t.Errorf("Expected the baked meshes to share a vertex buffer")
+	}
+	expected := parsed_obj.Vertices[0].Add(primitives.MakeVector(0, 0, 5))
+	if point := first.Face(0).Points()[0]; !point.Equals(expected) {
+		t.Errorf("Expected the baked vertex %v, got %v", expected, point)
 	}
 }
 
@@ -127,28 +151,94 @@ func TestSmoothTriangles(t *testing.T) {
 		t.Errorf("Incorrect amount of vertex normals, found %v, expected 3", len(parsed_obj.Normals))
 		t.Errorf("Vertex Normals: %v", parsed_obj.Normals)
 	}
-	if len(parsed_obj.Faces[DEFAULT]) != 2 {
-		t.Errorf("Incorrect amount of faces, found %v, expected 3", len(parsed_obj.Faces[DEFAULT]))
+	if parsed_obj.Faces[DEFAULT].Len() != 2 {
+		t.Errorf("Incorrect amount of faces, found %v, expected 3", parsed_obj.Faces[DEFAULT].Len())
 		t.Errorf("Faces: %v", parsed_obj.Faces[DEFAULT])
 	}
-	if !(parsed_obj.Faces[DEFAULT][0].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Point2.Equals(parsed_obj.Vertices[1])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Point3.Equals(parsed_obj.Vertices[2])) {
-		t.Errorf("Incorrect vertices on first triangle: %v", parsed_obj.Faces[DEFAULT][0])
-	}
-	if !(parsed_obj.Faces[DEFAULT][0].Normal1.Equals(parsed_obj.Normals[2])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Normal2.Equals(parsed_obj.Normals[0])) ||
-		!(parsed_obj.Faces[DEFAULT][0].Normal3.Equals(parsed_obj.Normals[1])) {
-		t.Errorf("Incorrect vertex normals on first triangle: %v", parsed_obj.Faces[DEFAULT][0])
-	}
-	if !(parsed_obj.Faces[DEFAULT][1].Point1.Equals(parsed_obj.Vertices[0])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Point2.Equals(parsed_obj.Vertices[1])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Point3.Equals(parsed_obj.Vertices[2])) {
-		t.Errorf("Incorrect vertices on second triangle: %v", parsed_obj.Faces[DEFAULT][0])
-	}
-	if !(parsed_obj.Faces[DEFAULT][1].Normal1.Equals(parsed_obj.Normals[2])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Normal2.Equals(parsed_obj.Normals[0])) ||
-		!(parsed_obj.Faces[DEFAULT][1].Normal3.Equals(parsed_obj.Normals[1])) {
-		t.Errorf("Incorrect vertex normals on second triangle: %v", parsed_obj.Faces[DEFAULT][0])
+	if !(parsed_obj.Faces[DEFAULT].Face(0).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(0).Points()[1].Equals(parsed_obj.Vertices[1])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(0).Points()[2].Equals(parsed_obj.Vertices[2])) {
+		t.Errorf("Incorrect vertices on first triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
+	}
+	normals0, smooth0 := parsed_obj.Faces[DEFAULT].Face(0).VertexNormals()
+	if !smooth0 || !(normals0[0].Equals(parsed_obj.Normals[2])) ||
+		!(normals0[1].Equals(parsed_obj.Normals[0])) ||
+		!(normals0[2].Equals(parsed_obj.Normals[1])) {
+		t.Errorf("Incorrect vertex normals on first triangle: %v", normals0)
+	}
+	if !(parsed_obj.Faces[DEFAULT].Face(1).Points()[0].Equals(parsed_obj.Vertices[0])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(1).Points()[1].Equals(parsed_obj.Vertices[1])) ||
+		!(parsed_obj.Faces[DEFAULT].Face(1).Points()[2].Equals(parsed_obj.Vertices[2])) {
+		t.Errorf("Incorrect vertices on second triangle: %v", parsed_obj.Faces[DEFAULT].Face(0).Points())
+	}
+	normals1, smooth1 := parsed_obj.Faces[DEFAULT].Face(1).VertexNormals()
+	if !smooth1 || !(normals1[0].Equals(parsed_obj.Normals[2])) ||
+		!(normals1[1].Equals(parsed_obj.Normals[0])) ||
+		!(normals1[2].Equals(parsed_obj.Normals[1])) {
+		t.Errorf("Incorrect vertex normals on second triangle: %v", normals1)
+	}
+}
+
+func TestTextureCoordinates(t *testing.T) {
+	parsed_obj := components.ParseObjFile("textured.obj", false, mats)
+	if len(parsed_obj.UVs) != 3 {
+		t.Errorf("Incorrect amount of texture coordinates, found %v, expected 3", len(parsed_obj.UVs))
+		t.Errorf("Texture Coordinates: %v", parsed_obj.UVs)
+	}
+	if parsed_obj.Faces[DEFAULT].Len() != 1 {
+		t.Fatalf("Incorrect amount of faces, found %v, expected 1", parsed_obj.Faces[DEFAULT].Len())
+	}
+	uv := parsed_obj.Faces[DEFAULT].Face(0).UVMapping(parsed_obj.Vertices[2])
+	if !uv.Equals(primitives.MakePoint(0.5, 1, 0)) {
+		t.Errorf("Incorrect texture coordinate at third vertex: %v", uv)
+	}
+}
+
+func TestRelativeIndices(t *testing.T) {
+	parsed_obj := components.ParseObjFile("relative.obj", true, mats)
+	if parsed_obj.Faces[DEFAULT].Len() != 1 {
+		t.Fatalf("Incorrect amount of faces, found %v, expected 1", parsed_obj.Faces[DEFAULT].Len())
+	}
+	face := parsed_obj.Faces[DEFAULT].Face(0)
+	points := face.Points()
+	for index := range points {
+		if !points[index].Equals(parsed_obj.Vertices[index]) {
+			t.Errorf("Incorrect vertex %v, found %v, expected %v", index, points[index], parsed_obj.Vertices[index])
+		}
+	}
+	normals, smooth := face.VertexNormals()
+	if !smooth || !normals[0].Equals(parsed_obj.Normals[0]) || !normals[1].Equals(parsed_obj.Normals[1]) ||
+		!normals[2].Equals(parsed_obj.Normals[1]) {
+		t.Errorf("Incorrect vertex normals: %v", normals)
+	}
+	if uv := face.UVMapping(parsed_obj.Vertices[2]); !uv.Equals(primitives.MakePoint(0.5, 1, 0)) {
+		t.Errorf("Incorrect texture coordinate at third vertex: %v", uv)
+	}
+}
+
+func TestInvalidIndices(t *testing.T) {
+	// Parsing exits on a bad file, so each file is parsed by the test binary run again
+	if filename := os.Getenv("GRAYTRACER_OBJ_FILE"); filename != "" {
+		components.ParseObjFile(filename, true, mats)
+		return
+	}
+	tables := []struct {
+		name, contents string
+	}{
+		{"Vertex past the end", "v 0 0 0\nv 1 0 0\nv 1 1 0\nf 1 2 4\n"},
+		{"Vertex before the start", "v 0 0 0\nv 1 0 0\nv 1 1 0\nf -4 2 3\n"},
+		{"Normal past the end", "v 0 0 0\nv 1 0 0\nv 1 1 0\nvn 0 0 1\nf 1//1 2//1 3//2\n"},
+		{"Too few vertices", "v 0 0 0\nv 1 0 0\nf 1 2\n"},
+	}
+	for _, table := range tables {
+		filename := filepath.Join(t.TempDir(), "invalid.obj")
+		if err := os.WriteFile(filename, []byte(table.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestInvalidIndices$")
+		cmd.Env = append(os.Environ(), "GRAYTRACER_OBJ_FILE="+filename)
+		if output, err := cmd.CombinedOutput(); err == nil {
+			t.Errorf("%v, expected parsing to fail, got %s", table.name, output)
+		}
 	}
 }
@@ -0,0 +1,134 @@
+package components
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+)
+
+// FloatImage Image of unclamped colors, three floats to a pixel in rows from the top, so bright and noisy values
+// survive until the image is filtered or saved
+type FloatImage struct {
+	Width, Height int
+	Pix           []float64
+}
+
+// MakeFloatImage Make a black float image
+func MakeFloatImage(width, height int) *FloatImage {
+	return &FloatImage{Width: width, Height: height, Pix: make([]float64, width*height*3)}
+}
+
+// At Get the color of a pixel
+func (f *FloatImage) At(x, y int) patterns.RGB {
+	index := ((y * f.Width) + x) * 3
+	return *patterns.MakeRGB(f.Pix[index], f.Pix[index+1], f.Pix[index+2])
+}
+
+// Set Set the color of a pixel
+func (f *FloatImage) Set(x, y int, color patterns.RGB) {
+	index := ((y * f.Width) + x) * 3
+	f.Pix[index], f.Pix[index+1], f.Pix[index+2] = color.Red(), color.Green(), color.Blue()
+}
+
+// ToRGBA Convert to an image, clamping every channel from 0 to 1
+func (f *FloatImage) ToRGBA() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			img.SetRGBA(x, y, f.At(x, y).ToImageRGBA())
+		}
+	}
+	return img
+}
+
+// WritePFM Write the image as a little endian color Portable Float Map, which stores rows from the bottom
+func (f *FloatImage) WritePFM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "PF\n%d %d\n-1.0\n", f.Width, f.Height); err != nil {
+		return err
+	}
+	row := make([]float32, f.Width*3)
+	for y := f.Height - 1; y >= 0; y-- {
+		for index, value := range f.Pix[y*f.Width*3 : (y+1)*f.Width*3] {
+			row[index] = float32(value)
+		}
+		if err := binary.Write(bw, binary.LittleEndian, row); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// SavePFM Write the image to a Portable Float Map file
+func (f *FloatImage) SavePFM(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := f.WritePFM(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// maxPFMPixels Largest image read from a Portable Float Map, so a damaged header can't ask for all the memory
+const maxPFMPixels = 1 << 26
+
+// ReadPFM Read a color or greyscale Portable Float Map of either byte order, greyscale values fill every channel
+func ReadPFM(r io.Reader) (*FloatImage, error) {
+	br := bufio.NewReader(r)
+	var magic string
+	var width, height int
+	var scale float64
+	if _, err := fmt.Fscan(br, &magic, &width, &height, &scale); err != nil {
+		return nil, fmt.Errorf("invalid PFM header: %v", err)
+	}
+	channels := 3
+	switch magic {
+	case "PF":
+	case "Pf":
+		channels = 1
+	default:
+		return nil, fmt.Errorf("invalid PFM header: unknown type %q", magic)
+	}
+	if width <= 0 || height <= 0 || width > maxPFMPixels/height || scale == 0 || math.IsNaN(scale) {
+		return nil, fmt.Errorf("invalid PFM header: %dx%d with scale %v", width, height, scale)
+	}
+	// A single whitespace character separates the header from the pixels
+	if _, err := br.ReadByte(); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+	f := MakeFloatImage(width, height)
+	row := make([]float32, width*channels)
+	for y := height - 1; y >= 0; y-- {
+		if err := binary.Read(br, order, row); err != nil {
+			return nil, err
+		}
+		pixels := f.Pix[y*width*3 : (y+1)*width*3]
+		for index := range pixels {
+			pixels[index] = float64(row[(index/3)*channels+(index%3)%channels])
+		}
+	}
+	return f, nil
+}
+
+// LoadPFM Read a Portable Float Map file
+func LoadPFM(filename string) (*FloatImage, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadPFM(file)
+}
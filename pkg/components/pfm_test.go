@@ -0,0 +1,105 @@
+package components_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+)
+
+func TestFloatImage(t *testing.T) {
+	img := components.MakeFloatImage(3, 2)
+	img.Set(2, 1, *patterns.MakeRGB(0.5, 2, -1))
+	if color := img.At(2, 1); !color.Equals(*patterns.MakeRGB(0.5, 2, -1)) {
+		t.Errorf("Expected the color set, got %v", color)
+	}
+	if color := img.At(1, 1); !color.Equals(*patterns.MakeRGB(0, 0, 0)) {
+		t.Errorf("Expected black, got %v", color)
+	}
+	rgba := img.ToRGBA()
+	if rgba.Bounds().Dx() != 3 || rgba.Bounds().Dy() != 2 || rgba.RGBAAt(2, 1) != patterns.MakeRGB(0.5, 1, 0).ToImageRGBA() {
+		t.Errorf("Expected the color clamped, got %v", rgba.RGBAAt(2, 1))
+	}
+}
+
+func TestPFM(t *testing.T) {
+	img := components.MakeFloatImage(4, 3)
+	for index := range img.Pix {
+		img.Pix[index] = float64(index) / 4
+	}
+	img.Pix[5] = 1e6
+	var buffer bytes.Buffer
+	if err := img.WritePFM(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buffer.Bytes(), []byte("PF\n4 3\n-1.0\n")) {
+		t.Errorf("Expected a little endian color header, got %q", buffer.Bytes()[:12])
+	}
+	// Rows are stored from the bottom
+	var first float32
+	binary.Read(bytes.NewReader(buffer.Bytes()[12:]), binary.LittleEndian, &first)
+	if first != float32(img.Pix[2*4*3]) {
+		t.Errorf("Expected the bottom row first, got %v", first)
+	}
+	read, err := components.ReadPFM(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Width != 4 || read.Height != 3 {
+		t.Fatalf("Expected 4x3, got %vx%v", read.Width, read.Height)
+	}
+	for index := range img.Pix {
+		if read.Pix[index] != img.Pix[index] {
+			t.Errorf("Value %v, expected %v, got %v", index, img.Pix[index], read.Pix[index])
+		}
+	}
+	filename := filepath.Join(t.TempDir(), "image.pfm")
+	if err := img.SavePFM(filename); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := components.LoadPFM(filename); err != nil || loaded.Pix[5] != 1e6 {
+		t.Errorf("Expected the saved image back, got %v", err)
+	}
+}
+
+func TestReadPFMGreyscale(t *testing.T) {
+	var buffer bytes.Buffer
+	buffer.WriteString("Pf\n2 2\n1.0\n")
+	// Big endian, bottom row first
+	binary.Write(&buffer, binary.BigEndian, []float32{3, 4, 1, 2})
+	img, err := components.ReadPFM(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, value := range []float64{1, 2, 3, 4} {
+		if color := img.At(index%2, index/2); !color.Equals(*patterns.MakeRGB(value, value, value)) {
+			t.Errorf("Pixel %v, expected %v in every channel, got %v", index, value, color)
+		}
+	}
+}
+
+func TestReadPFMErrors(t *testing.T) {
+	tables := []struct {
+		name, contents string
+	}{
+		{"Empty", ""},
+		{"Unknown type", "P6\n2 2\n255\n"},
+		{"No size", "PF\nwide\n"},
+		{"Negative size", "PF\n-2 2\n-1.0\n"},
+		{"Huge size", "PF\n100000 100000\n-1.0\n"},
+		{"Zero scale", "PF\n2 2\n0\n"},
+		{"Truncated", "PF\n2 2\n-1.0\n" + strings.Repeat("\x00", 20)},
+	}
+	for _, table := range tables {
+		if _, err := components.ReadPFM(strings.NewReader(table.contents)); err == nil {
+			t.Errorf("%v, expected an error", table.name)
+		}
+	}
+	if _, err := components.LoadPFM(filepath.Join(t.TempDir(), "missing.pfm")); err == nil {
+		t.Error("Expected an error loading a missing file")
+	}
+}
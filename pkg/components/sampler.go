@@ -0,0 +1,111 @@
+package components
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// AdaptiveSampler Traces more samples through pixels whose color keeps changing between samples, stopping once
+// the mean settles so flat areas only take a few
+type AdaptiveSampler struct {
+	// MinSamples Samples taken for every pixel before checking whether it has settled
+	MinSamples int
+	// MaxSamples Most samples taken for any pixel
+	MaxSamples int
+	// Threshold Largest standard error of the mean of any channel for a pixel to count as settled
+	Threshold float64
+}
+
+// MakeAdaptiveSampler Make a sampler taking between minSamples and maxSamples for each pixel, a sampler taking one
+// sample traces the center of each pixel like Camera.RayForPixel
+func MakeAdaptiveSampler(minSamples, maxSamples int, threshold float64) AdaptiveSampler {
+	if maxSamples < 1 {
+		maxSamples = 1
+	}
+	if minSamples < 1 {
+		minSamples = 1
+	}
+	if minSamples > maxSamples {
+		minSamples = maxSamples
+	}
+	return AdaptiveSampler{MinSamples: minSamples, MaxSamples: maxSamples, Threshold: math.Max(threshold, 0)}
+}
+
+// SampleOffset Position within a pixel of a sample, the first is at the center and the rest follow a Halton
+// sequence so any number of samples are spread evenly and every render takes the same ones
+func SampleOffset(index int) (float64, float64) {
+	if index == 0 {
+		return 0.5, 0.5
+	}
+	return radicalInverse(index, 2), radicalInverse(index, 3)
+}
+
+// radicalInverse Mirror the digits of an index in a base around the point, giving a fraction from 0 to 1
+func radicalInverse(index, base int) float64 {
+	result, scale := 0.0, 1.0/float64(base)
+	for ; index > 0; index /= base {
+		result += float64(index%base) * scale
+		scale /= float64(base)
+	}
+	return result
+}
+
+// SamplePixel Average samples of a pixel until every channel has settled or MaxSamples are taken,
+// returning the mean color and the number of samples. The mean features of the samples are kept in features when
+// it is given
+func (s AdaptiveSampler) SamplePixel(world *World, camera *Camera, x, y uint64, remaining int,
+	features *Features) (patterns.RGB, int) {
+	var mean, squares [3]float64
+	// Albedo, normal and depth of every sample added up
+	var albedo, normal [3]float64
+	depth := 0.0
+	count := 0
+	for count < s.MaxSamples || count == 0 {
+		u, v := SampleOffset(count)
+		ray := camera.RayForSample(x, y, u, v)
+		var color patterns.RGB
+		if features != nil {
+			var sample Features
+			color, sample = world.ColorAndFeaturesAt(ray, remaining)
+			albedo[0], albedo[1], albedo[2] = albedo[0]+sample.Albedo.Red(), albedo[1]+sample.Albedo.Green(),
+				albedo[2]+sample.Albedo.Blue()
+			normal[0], normal[1], normal[2] = normal[0]+sample.Normal.X, normal[1]+sample.Normal.Y,
+				normal[2]+sample.Normal.Z
+			depth += sample.Depth
+		} else {
+			color = world.ColorAt(ray, remaining)
+		}
+		count++
+		// Welford's method keeps the running mean and sum of squared differences without losing precision
+		for channel, value := range [3]float64{color.Red(), color.Green(), color.Blue()} {
+			delta := value - mean[channel]
+			mean[channel] += delta / float64(count)
+			squares[channel] += delta * (value - mean[channel])
+		}
+		if count >= s.MinSamples && s.settled(squares, count) {
+			break
+		}
+	}
+	if features != nil {
+		scale := 1 / float64(count)
+		*features = Features{Albedo: *patterns.MakeRGB(albedo[0]*scale, albedo[1]*scale, albedo[2]*scale),
+			Normal: primitives.MakeVector(normal[0]*scale, normal[1]*scale, normal[2]*scale), Depth: depth * scale}
+	}
+	return *patterns.MakeRGB(mean[0], mean[1], mean[2]), count
+}
+
+// settled Check if the standard error of the mean of every channel is within the threshold
+func (s AdaptiveSampler) settled(squares [3]float64, count int) bool {
+	if count < 2 {
+		return false
+	}
+	for _, sum := range squares {
+		variance := sum / float64(count-1)
+		if math.Sqrt(variance/float64(count)) > s.Threshold {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package components_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+)
+
+func TestMakeAdaptiveSampler(t *testing.T) {
+	tables := []struct {
+		min, max                 int
+		threshold                float64
+		expectedMin, expectedMax int
+		expectedThreshold        float64
+	}{
+		{4, 16, 0.01, 4, 16, 0.01},
+		{0, 0, -1, 1, 1, 0},
+		{8, 4, 0.1, 4, 4, 0.1},
+		{-2, 3, 0, 1, 3, 0},
+	}
+	for _, table := range tables {
+		sampler := components.MakeAdaptiveSampler(table.min, table.max, table.threshold)
+		if sampler.MinSamples != table.expectedMin || sampler.MaxSamples != table.expectedMax ||
+			sampler.Threshold != table.expectedThreshold {
+			t.Errorf("%v, %v, %v, expected %v, %v, %v, got %v", table.min, table.max, table.threshold, table.expectedMin,
+				table.expectedMax, table.expectedThreshold, sampler)
+		}
+	}
+}
+
+func TestSampleOffset(t *testing.T) {
+	tables := []struct {
+		index int
+		u, v  float64
+	}{
+		{0, 0.5, 0.5},
+		{1, 0.5, 1.0 / 3},
+		{2, 0.25, 2.0 / 3},
+		{3, 0.75, 1.0 / 9},
+		{4, 0.125, 4.0 / 9},
+		{5, 0.625, 7.0 / 9},
+	}
+	for _, table := range tables {
+		if u, v := components.SampleOffset(table.index); math.Abs(u-table.u) > 1e-12 || math.Abs(v-table.v) > 1e-12 {
+			t.Errorf("Sample %v, expected %v, %v, got %v, %v", table.index, table.u, table.v, u, v)
+		}
+	}
+}
+
+func TestRayForSample(t *testing.T) {
+	_, camera := packetWorld(t)
+	for _, pixel := range [][2]uint64{{0, 0}, {10, 20}, {63, 35}} {
+		if ray, center := camera.RayForSample(pixel[0], pixel[1], 0.5, 0.5), camera.RayForPixel(pixel[0], pixel[1]); ray != center {
+			t.Errorf("Pixel %v, expected %v, got %v", pixel, center, ray)
+		}
+		// The corner of a pixel is shared with the one diagonally before it
+		if ray, corner := camera.RayForSample(pixel[0]+1, pixel[1]+1, 0, 0), camera.RayForSample(pixel[0], pixel[1], 1, 1); !ray.Direction.Equals(corner.Direction) {
+			t.Errorf("Pixel %v, expected the corners to match, got %v and %v", pixel, ray, corner)
+		}
+	}
+}
+
+func TestSamplePixel(t *testing.T) {
+	world, camera := packetWorld(t)
+	single := components.MakeAdaptiveSampler(1, 1, 0)
+	for _, pixel := range [][2]uint64{{0, 0}, {32, 18}, {40, 28}} {
+		color, count := single.SamplePixel(world, camera, pixel[0], pixel[1], 5, nil)
+		if expected := world.ColorAt(camera.RayForPixel(pixel[0], pixel[1]), 5); count != 1 || !color.Equals(expected) {
+			t.Errorf("Pixel %v, expected %v from one sample, got %v from %v", pixel, expected, color, count)
+		}
+	}
+	sampler := components.MakeAdaptiveSampler(4, 64, 0.005)
+	// The sky above the scene is black wherever it is sampled
+	if color, count := sampler.SamplePixel(world, camera, 0, 0, 5, nil); count != 4 || color.Red() != 0 {
+		t.Errorf("Expected the sky to settle after 4 samples, got %v from %v", color, count)
+	}
+	most := 0
+	for y := uint64(0); y < 36; y += 3 {
+		for x := uint64(0); x < 64; x += 3 {
+			_, count := sampler.SamplePixel(world, camera, x, y, 5, nil)
+			if count < sampler.MinSamples || count > sampler.MaxSamples {
+				t.Errorf("Pixel %v, %v took %v samples", x, y, count)
+			}
+			if count > most {
+				most = count
+			}
+		}
+	}
+	if most <= sampler.MinSamples {
+		t.Errorf("Expected the edges of the shapes to take more samples, got at most %v", most)
+	}
+}
+
+func BenchmarkUniformSampling(b *testing.B) {
+	world, camera := packetWorld(b)
+	sampler := components.MakeAdaptiveSampler(16, 16, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for x := uint64(0); x < 64; x += 8 {
+			sampler.SamplePixel(world, camera, x, 28, 5, nil)
+		}
+	}
+}
+
+func BenchmarkAdaptiveSampling(b *testing.B) {
+	world, camera := packetWorld(b)
+	sampler := components.MakeAdaptiveSampler(4, 16, 0.01)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for x := uint64(0); x < 64; x += 8 {
+			sampler.SamplePixel(world, camera, x, 28, 5, nil)
+		}
+	}
+}
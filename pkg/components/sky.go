@@ -0,0 +1,139 @@
+package components
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Sky Background that can vary with the direction of a ray that hits nothing
+type Sky interface {
+	ColorAt(primitives.PV) patterns.RGB
+}
+
+// GradientSky Vertical gradient from the horizon color up to the zenith color
+type GradientSky struct {
+	Horizon, Zenith, Ground patterns.RGB
+	Exponent                float64
+}
+
+// MakeGradientSky Make a linear gradient sky, the ground takes the horizon color
+func MakeGradientSky(horizon, zenith patterns.RGB) *GradientSky {
+	return &GradientSky{Horizon: horizon, Zenith: zenith, Ground: horizon, Exponent: 1}
+}
+
+// ColorAt Return the color of the sky in the given direction
+func (gs GradientSky) ColorAt(direction primitives.PV) patterns.RGB {
+	height := direction.Normalize().Y
+	if height < 0 {
+		return gs.Ground
+	}
+	return gs.Horizon.Add(gs.Zenith.Subtract(gs.Horizon).Scale(math.Pow(height, gs.Exponent)))
+}
+
+// DaylightSky Preetham analytic daylight model for a clear sky
+type DaylightSky struct {
+	sun                      primitives.PV
+	exposure, sunRadius      float64
+	sunColor                 patterns.RGB
+	zenithX, zenithY         float64
+	perezLum, perezX, perezY [5]float64
+	normLum, normX, normY    float64
+}
+
+// MakeDaylightSky Make a daylight sky from the direction towards the sun and the
+// atmospheric turbidity (2 is a very clear sky, 10 is hazy)
+func MakeDaylightSky(sunDirection primitives.PV, turbidity float64) *DaylightSky {
+	ds := &DaylightSky{exposure: 0.5, sunRadius: 0.02, sunColor: *patterns.MakeRGB(1, 1, 1)}
+	ds.SetSun(sunDirection, turbidity)
+	return ds
+}
+
+// MakeDaylightSkyFromLight Make a daylight sky whose sun sits in the direction of a light
+func MakeDaylightSkyFromLight(light PointLight, turbidity float64) *DaylightSky {
+	ds := MakeDaylightSky(light.Position.Subtract(primitives.MakePoint(0, 0, 0)), turbidity)
+	ds.sunColor = *light.Intensity
+	return ds
+}
+
+// SetSun Update the sun direction and turbidity, recalculating the model coefficients
+func (ds *DaylightSky) SetSun(sunDirection primitives.PV, turbidity float64) {
+	sun := sunDirection
+	sun.W = 0
+	ds.sun = sun.Normalize()
+	t := turbidity
+	// Sun angle from the zenith, kept just above the horizon to avoid a singular model
+	thetaS := math.Acos(math.Max(ds.sun.Y, 0.001))
+	theta2 := thetaS * thetaS
+	theta3 := theta2 * thetaS
+	// Zenith chromaticity, luminance is relative to the zenith so only exposure scales it
+	ds.zenithX = (t*t)*((0.00166*theta3)-(0.00375*theta2)+(0.00209*thetaS)) +
+		t*((-0.02903*theta3)+(0.06377*theta2)-(0.03202*thetaS)+0.00394) +
+		((0.11693 * theta3) - (0.21196 * theta2) + (0.06052 * thetaS) + 0.25886)
+	ds.zenithY = (t*t)*((0.00275*theta3)-(0.00610*theta2)+(0.00317*thetaS)) +
+		t*((-0.04214*theta3)+(0.08970*theta2)-(0.04153*thetaS)+0.00516) +
+		((0.15346 * theta3) - (0.26756 * theta2) + (0.06670 * thetaS) + 0.26688)
+	// Perez distribution coefficients for luminance and both chromaticities
+	ds.perezLum = [5]float64{(0.1787 * t) - 1.4630, (-0.3554 * t) + 0.4275, (-0.0227 * t) + 5.3251,
+		(0.1206 * t) - 2.5771, (-0.0670 * t) + 0.3703}
+	ds.perezX = [5]float64{(-0.0193 * t) - 0.2592, (-0.0665 * t) + 0.0008, (-0.0004 * t) + 0.2125,
+		(-0.0641 * t) - 0.8989, (-0.0033 * t) + 0.0452}
+	ds.perezY = [5]float64{(-0.0167 * t) - 0.2608, (-0.0950 * t) + 0.0092, (-0.0079 * t) + 0.2102,
+		(-0.0441 * t) - 1.6537, (-0.0109 * t) + 0.0529}
+	ds.normLum = perez(ds.perezLum, 0, thetaS)
+	ds.normX = perez(ds.perezX, 0, thetaS)
+	ds.normY = perez(ds.perezY, 0, thetaS)
+}
+
+// SetExposure Set the brightness of the sky at the zenith
+func (ds *DaylightSky) SetExposure(exposure float64) {
+	ds.exposure = exposure
+}
+
+// SetSunDisk Set the angular radius (in Radians) and color of the visible sun, a radius of 0 hides it
+func (ds *DaylightSky) SetSunDisk(radius float64, color patterns.RGB) {
+	ds.sunRadius = radius
+	ds.sunColor = color
+}
+
+// Sun Get the normalized direction towards the sun
+func (ds *DaylightSky) Sun() primitives.PV {
+	return ds.sun
+}
+
+// SunLight Make a point light far enough along the sun direction to act as directional sunlight
+func (ds *DaylightSky) SunLight(distance float64, intensity *patterns.RGB) PointLight {
+	return PointLight{Intensity: intensity,
+		Position: primitives.MakePoint(0, 0, 0).Add(ds.sun.Scalar(distance))}
+}
+
+// perez Perez sky distribution function for a view angle theta and sun angle gamma
+func perez(coefficients [5]float64, theta, gamma float64) float64 {
+	cosGamma := math.Cos(gamma)
+	return (1 + coefficients[0]*math.Exp(coefficients[1]/math.Cos(theta))) *
+		(1 + coefficients[2]*math.Exp(coefficients[3]*gamma) + coefficients[4]*cosGamma*cosGamma)
+}
+
+// ColorAt Return the color of the sky in the given direction
+func (ds DaylightSky) ColorAt(direction primitives.PV) patterns.RGB {
+	direction.W = 0
+	direction = direction.Normalize()
+	cosGamma := math.Max(-1, math.Min(1, direction.DotProduct(ds.sun)))
+	gamma := math.Acos(cosGamma)
+	// Directions below the horizon reuse the horizon color
+	theta := math.Acos(math.Max(direction.Y, 0.001))
+	luminance := ds.exposure * perez(ds.perezLum, theta, gamma) / ds.normLum
+	x := ds.zenithX * perez(ds.perezX, theta, gamma) / ds.normX
+	y := ds.zenithY * perez(ds.perezY, theta, gamma) / ds.normY
+	// xyY to XYZ to linear sRGB
+	cieX := (x / y) * luminance
+	cieZ := ((1 - x - y) / y) * luminance
+	sky := *patterns.MakeRGB(math.Max(0, (3.2406*cieX)-(1.5372*luminance)-(0.4986*cieZ)),
+		math.Max(0, (-0.9689*cieX)+(1.8758*luminance)+(0.0415*cieZ)),
+		math.Max(0, (0.0557*cieX)-(0.2040*luminance)+(1.0570*cieZ)))
+	if gamma < ds.sunRadius {
+		return sky.Add(ds.sunColor)
+	}
+	return sky
+}
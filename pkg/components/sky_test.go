@@ -0,0 +1,114 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/components"
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestGradientSkyColorAt(t *testing.T) {
+	tables := []struct {
+		sky       *components.GradientSky
+		direction primitives.PV
+		result    *patterns.RGB
+	}{
+		{components.MakeGradientSky(*patterns.MakeRGB(1, 1, 1), *patterns.MakeRGB(0, 0, 1)),
+			primitives.MakeVector(0, 1, 0), patterns.MakeRGB(0, 0, 1)},
+
+		{components.MakeGradientSky(*patterns.MakeRGB(1, 1, 1), *patterns.MakeRGB(0, 0, 1)),
+			primitives.MakeVector(0, 0, 1), patterns.MakeRGB(1, 1, 1)},
+
+		{components.MakeGradientSky(*patterns.MakeRGB(1, 1, 1), *patterns.MakeRGB(0, 0, 1)),
+			primitives.MakeVector(0, 1, 1), patterns.MakeRGB(0.2928932188134524, 0.2928932188134524, 1)},
+
+		{components.MakeGradientSky(*patterns.MakeRGB(1, 1, 1), *patterns.MakeRGB(0, 0, 1)),
+			primitives.MakeVector(0, -1, 0), patterns.MakeRGB(1, 1, 1)},
+	}
+	for _, table := range tables {
+		result := table.sky.ColorAt(table.direction)
+		if !result.Equals(*table.result) {
+			t.Errorf("Direction: %v, Expected %v, got %v", table.direction, table.result, result)
+		}
+	}
+}
+
+func TestDaylightSkyColorAt(t *testing.T) {
+	tables := []struct {
+		sun, direction primitives.PV
+		turbidity      float64
+		result         *patterns.RGB
+	}{
+		{primitives.MakeVector(0, 1, 1), primitives.MakeVector(0, 1, 0), 3,
+			patterns.MakeRGB(0.31584869596170295, 0.5061107135025925, 0.9819816327584414)},
+
+		{primitives.MakeVector(0, 1, 1), primitives.MakeVector(0, 1, 1), 3,
+			patterns.MakeRGB(1.6863689129857955, 1.9706452480820948, 2.622631997519911)},
+
+		{primitives.MakeVector(0, 1, 1), primitives.MakeVector(0, 0.1, -1), 3,
+			patterns.MakeRGB(0.499979045613713, 0.5377293167616155, 0.6444033452940984)},
+	}
+	for _, table := range tables {
+		sky := components.MakeDaylightSky(table.sun, table.turbidity)
+		sky.SetSunDisk(0, *patterns.MakeRGB(1, 1, 1))
+		result := sky.ColorAt(table.direction)
+		if !result.Equals(*table.result) {
+			t.Errorf("Direction: %v, Expected %v, got %v", table.direction, table.result, result)
+		}
+	}
+}
+
+func TestDaylightSkySunDisk(t *testing.T) {
+	sky := components.MakeDaylightSky(primitives.MakeVector(0, 1, 1), 3)
+	sky.SetSunDisk(0, *patterns.MakeRGB(1, 1, 1))
+	withoutSun := sky.ColorAt(primitives.MakeVector(0, 1, 1))
+	sky.SetSunDisk(0.01, *patterns.MakeRGB(1, 1, 1))
+	withSun := sky.ColorAt(primitives.MakeVector(0, 1, 1))
+	expected := withoutSun.Add(*patterns.MakeRGB(1, 1, 1))
+	if !withSun.Equals(expected) {
+		t.Errorf("Expected %v, got %v", expected, withSun)
+	}
+}
+
+func TestDaylightSkyFromLight(t *testing.T) {
+	light := components.PointLight{Intensity: patterns.MakeRGB(1, 0.9, 0.8), Position: primitives.MakePoint(0, 10, 0)}
+	sky := components.MakeDaylightSkyFromLight(light, 2)
+	if !sky.Sun().Equals(primitives.MakeVector(0, 1, 0)) {
+		t.Errorf("Expected sun direction %v, got %v", primitives.MakeVector(0, 1, 0), sky.Sun())
+	}
+	sun := sky.SunLight(1000, light.Intensity)
+	if !sun.Position.Equals(primitives.MakePoint(0, 1000, 0)) {
+		t.Errorf("Expected sun position %v, got %v", primitives.MakePoint(0, 1000, 0), sun.Position)
+	}
+}
+
+func TestWorldSky(t *testing.T) {
+	tables := []struct {
+		sky    components.Sky
+		ray    primitives.Ray
+		result *patterns.RGB
+	}{
+		{nil,
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 1, 0)},
+			patterns.MakeRGB(0, 0, 0)},
+
+		{patterns.MakeRGB(0.5, 0.5, 0.5),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 1, 0)},
+			patterns.MakeRGB(0.5, 0.5, 0.5)},
+
+		{components.MakeGradientSky(*patterns.MakeRGB(1, 1, 1), *patterns.MakeRGB(0, 0, 1)),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 1, 0)},
+			patterns.MakeRGB(0, 0, 1)},
+	}
+	for _, table := range tables {
+		world := &components.World{}
+		if table.sky != nil {
+			world.SetSky(table.sky)
+		}
+		result := world.ColorAt(table.ray, 5)
+		if !result.Equals(*table.result) {
+			t.Errorf("Expected %v, got %v", table.result, result)
+		}
+	}
+}
@@ -3,16 +3,20 @@ package components
 import (
 	"math"
 	"sort"
+	"sync"
 	"github.com/factorion/graytracer/pkg/primitives"
 	"github.com/factorion/graytracer/pkg/patterns"
 	"github.com/factorion/graytracer/pkg/shapes"
+	"github.com/factorion/graytracer/pkg/stats"
 )
 
 // World Container for objects
 type World struct {
 	objects []shapes.Shape
 	lights []PointLight
-	background patterns.RGB
+	background Sky
+	// counts Rays and tests traced through this world, nil unless counting
+	counts *stats.Counts
 }
 
 // MakeWorld Make an empty world and a black background
@@ -20,6 +24,11 @@ func MakeWorld() *World {
 	return &World{objects: []shapes.Shape{}, lights: []PointLight{}, background: *patterns.MakeRGB(0, 0, 0)}
 }
 
+// Objects Get the shapes added to the world
+func (w World) Objects() []shapes.Shape {
+	return w.objects
+}
+
 // AddObject Add a shape object to the world
 func (w *World) AddObject(shape shapes.Shape) {
 	w.objects = append(w.objects, shape)
@@ -30,33 +39,127 @@ func (w *World) AddLight(light PointLight) {
 	w.lights = append(w.lights, light)
 }
 
+// AddAreaLight Add the point lights sampling an area light to the world
+func (w *World) AddAreaLight(light *AreaLight) {
+	w.lights = append(w.lights, light.Lights()...)
+}
+
 // SetBackground Set the background color
 func (w *World) SetBackground(color patterns.RGB) {
 	w.background = color
 }
 
+// SetSky Set a background that varies with the direction of the ray
+func (w *World) SetSky(sky Sky) {
+	w.background = sky
+}
+
+// Compile Cache the matrices to world-space of every object so normals don't walk up through their groups,
+// flattening first moves group transforms down and bakes them into triangles. Call again after changing objects
+func (w *World) Compile(flatten bool) {
+	if flatten {
+		shapes.Flatten(w.objects...)
+	}
+	shapes.Compile(w.objects...)
+}
+
+// Counting Get a copy of the world sharing its objects and lights that counts every ray and test it traces into
+// counts. Counts are not safe to share, so each goroutine tracing the world counts through its own copy
+func (w World) Counting(counts *stats.Counts) *World {
+	w.counts = counts
+	return &w
+}
+
+// Background Calculate the background color seen along a ray that hits nothing
+func (w World) Background(ray primitives.Ray) patterns.RGB {
+	if w.background == nil {
+		return *patterns.MakeRGB(0, 0, 0)
+	}
+	return w.background.ColorAt(ray.Direction)
+}
+
+// Buffers reused between rays so tracing doesn't allocate a new list of hits for every ray
+var intersectionPool = sync.Pool{New: func() interface{} {
+	hits := make(shapes.Intersections, 0, 16)
+	return &hits
+}}
+
 // Intersect Calculate the intersections from the ray to world objects
 func (w World) Intersect(ray primitives.Ray) shapes.Intersections {
-	var i shapes.Intersections
-	for _, s := range w.objects {
-		i = append(i, s.Intersect(ray)...)
-	}
+	i := w.AppendIntersections(ray, nil)
 	sort.Sort(i)
 	return i
 }
 
+// AppendIntersections Add the unsorted intersections from the ray to world objects to the end of hits
+func (w World) AppendIntersections(ray primitives.Ray, hits shapes.Intersections) shapes.Intersections {
+	for _, s := range w.objects {
+		hits = shapes.AppendIntersectionsCounted(s, ray, hits, w.counts)
+	}
+	return hits
+}
+
+// ClosestHit Find the closest intersection in front of the ray without keeping the others
+func (w World) ClosestHit(ray primitives.Ray) (shapes.Intersection, bool) {
+	buffer := intersectionPool.Get().(*shapes.Intersections)
+	*buffer = w.AppendIntersections(ray, (*buffer)[:0])
+	intersection, hit := buffer.Closest()
+	intersectionPool.Put(buffer)
+	return intersection, hit
+}
+
+// Transmittance Calculate how much light gets through the objects along a ray before maxDistance, each
+// object hit scales it by its transparency and the search stops at the first opaque one
+func (w World) Transmittance(ray primitives.Ray, maxDistance float64) float64 {
+	shade := 1.0
+	// Only transparent objects need remembering, so they are counted once when hit on both sides
+	var shadowShapes map[shapes.Shape]bool
+	visit := func(i shapes.Intersection) bool {
+		if _, exists := shadowShapes[i.Obj]; exists {
+			return true
+		}
+		transparency := i.Obj.Material().Transparency
+		if transparency == 0 {
+			shade = 0
+			return false
+		}
+		if shadowShapes == nil {
+			shadowShapes = make(map[shapes.Shape]bool)
+		}
+		shadowShapes[i.Obj] = true
+		shade *= transparency
+		return true
+	}
+	for _, s := range w.objects {
+		if !shapes.IntersectAnyCounted(s, ray, maxDistance, visit, w.counts) {
+			break
+		}
+	}
+	return shade
+}
+
 // ReflectedColor Calculate the color of the reflected ray
 func (w World) ReflectedColor(comps Computations, remaining int) patterns.RGB {
+	return w.reflectedColor(comps, remaining, 0, nil)
+}
+
+// reflectedColor Calculate the color of the ray reflected from a hit by a ray at depth
+func (w World) reflectedColor(comps Computations, remaining, depth int, deepest *int) patterns.RGB {
 	reflective := comps.Obj.Material().Reflective
 	if reflective == 0 {
 		return *patterns.MakeRGB(0, 0, 0)
 	}
 	reflectRay := primitives.Ray{Origin:comps.OverPoint, Direction:comps.ReflectVector}
-	return w.ColorAt(reflectRay, remaining - 1).Scale(reflective)
+	return w.colorAt(reflectRay, remaining - 1, depth + 1, stats.ReflectionRay, deepest, nil).Scale(reflective)
 }
 
 // RefractedColor Calculate the color of the refracted ray
 func (w World) RefractedColor(comps Computations, remaining int) patterns.RGB {
+	return w.refractedColor(comps, remaining, 0, nil)
+}
+
+// refractedColor Calculate the color of the ray refracted through a hit by a ray at depth
+func (w World) refractedColor(comps Computations, remaining, depth int, deepest *int) patterns.RGB {
 	transparency := comps.Obj.Material().Transparency
 	if transparency == 0 {
 		return *patterns.MakeRGB(0, 0, 0)
@@ -71,46 +174,148 @@ func (w World) RefractedColor(comps Computations, remaining int) patterns.RGB {
 	cost := math.Sqrt(1 - sin2t)
 	direction := comps.NormalVector.Scalar((nRatio * cosi) - cost).Subtract(comps.EyeVector.Scalar(nRatio))
 	refractRay := primitives.Ray{Origin:comps.UnderPoint, Direction:direction}
-	return w.ColorAt(refractRay, remaining - 1).Scale(transparency)
+	return w.colorAt(refractRay, remaining - 1, depth + 1, stats.RefractionRay, deepest, nil).Scale(transparency)
 }
 
 // ColorAt Calculate the color of a possible intersection hit
 func (w World) ColorAt(ray primitives.Ray, remaining int) patterns.RGB {
+	return w.colorAt(ray, remaining, 0, stats.PrimaryRay, nil, nil)
+}
+
+// DepthAt Calculate the color of a possible intersection hit like ColorAt, also returning the deepest bounce reached
+// by any ray traced for it
+func (w World) DepthAt(ray primitives.Ray, remaining int) (patterns.RGB, int) {
+	deepest := 0
+	color := w.colorAt(ray, remaining, 0, stats.PrimaryRay, &deepest, nil)
+	return color, deepest
+}
+
+// ColorAndFeaturesAt Calculate the color of a possible intersection hit like ColorAt, also returning the features
+// of the first hit that guide the denoiser
+func (w World) ColorAndFeaturesAt(ray primitives.Ray, remaining int) (patterns.RGB, Features) {
+	features := Features{}
+	color := w.colorAt(ray, remaining, 0, stats.PrimaryRay, nil, &features)
+	return color, features
+}
+
+// colorAt Calculate the color seen along a ray at depth bounces from the camera, counting it as the kind given,
+// raising deepest to the depth and keeping the features of the hit when given
+func (w World) colorAt(ray primitives.Ray, remaining, depth int, kind stats.RayKind, deepest *int,
+	features *Features) patterns.RGB {
 	surface := *patterns.MakeRGB(0, 0, 0)
 	if remaining <= 0 {
 		return surface
 	}
-	intersections := w.Intersect(ray)
-	intersection, hit := intersections.Hit()
+	w.counts.AddRay(kind)
+	w.counts.ObserveDepth(uint64(depth))
+	if deepest != nil && depth > *deepest {
+		*deepest = depth
+	}
+	buffer := intersectionPool.Get().(*shapes.Intersections)
+	*buffer = w.AppendIntersections(ray, (*buffer)[:0])
+	intersection, hit := buffer.Closest()
 	if !hit {
-		return w.background
+		intersectionPool.Put(buffer)
+		background := w.Background(ray)
+		if features != nil {
+			*features = Features{Albedo: background}
+		}
+		return background
+	}
+	// Refractive indices are only needed through transparent surfaces, which need every hit in order
+	var intersections shapes.Intersections
+	if intersection.Obj.Material().Transparency > 0 {
+		sort.Sort(*buffer)
+		intersections = *buffer
 	}
 	comp := PrepareComputations(intersection, ray, intersections)
+	intersectionPool.Put(buffer)
+	if features != nil {
+		*features = hitFeatures(comp)
+	}
+	return w.shade(comp, remaining, depth, deepest)
+}
+
+// ClosestPacket Find the closest hit in front of each ray of a packet, tracing them together through the objects
+func (w World) ClosestPacket(packet *shapes.RayPacket) shapes.PacketHits {
+	hits := shapes.PacketHits{}
+	buffer := intersectionPool.Get().(*shapes.Intersections)
+	for _, s := range w.objects {
+		shapes.ClosestPacket(s, packet, &hits, buffer, w.counts)
+	}
+	intersectionPool.Put(buffer)
+	return hits
+}
+
+// ColorAtPacket Calculate the colors seen along coherent rays, such as neighbouring primary rays, finding the first
+// hits of every shapes.PacketSize of them together. Secondary rays are traced one at a time by ColorAt
+func (w World) ColorAtPacket(rays []primitives.Ray, remaining int) []patterns.RGB {
+	return w.colorAtPacket(rays, remaining, nil)
+}
+
+// ColorAndFeaturesAtPacket Calculate the colors seen along coherent rays like ColorAtPacket, also returning the
+// features of the first hit of each ray that guide the denoiser
+func (w World) ColorAndFeaturesAtPacket(rays []primitives.Ray, remaining int) ([]patterns.RGB, []Features) {
+	features := make([]Features, len(rays))
+	return w.colorAtPacket(rays, remaining, features), features
+}
+
+// colorAtPacket Calculate the colors seen along coherent rays, keeping the features of their first hits when
+// features is given
+func (w World) colorAtPacket(rays []primitives.Ray, remaining int, features []Features) []patterns.RGB {
+	colors := make([]patterns.RGB, len(rays))
+	if remaining <= 0 {
+		return colors
+	}
+	for index, packet := range shapes.MakeRayPackets(rays) {
+		hits := w.ClosestPacket(&packet)
+		start := index * shapes.PacketSize
+		for lane := 0; lane < shapes.PacketSize && packet.Active[lane]; lane++ {
+			ray := rays[start+lane]
+			var rayFeatures *Features
+			if features != nil {
+				rayFeatures = &features[start+lane]
+			}
+			if !hits.Found[lane] {
+				w.counts.AddRay(stats.PrimaryRay)
+				w.counts.ObserveDepth(0)
+				colors[start+lane] = w.Background(ray)
+				if rayFeatures != nil {
+					*rayFeatures = Features{Albedo: colors[start+lane]}
+				}
+			} else if hits.Hits[lane].Obj.Material().Transparency > 0 {
+				// Refractive indices need every hit along the ray in order
+				colors[start+lane] = w.colorAt(ray, remaining, 0, stats.PrimaryRay, nil, rayFeatures)
+			} else {
+				w.counts.AddRay(stats.PrimaryRay)
+				w.counts.ObserveDepth(0)
+				comp := PrepareComputations(hits.Hits[lane], ray, nil)
+				if rayFeatures != nil {
+					*rayFeatures = hitFeatures(comp)
+				}
+				colors[start+lane] = w.shade(comp, remaining, 0, nil)
+			}
+		}
+	}
+	return colors
+}
+
+// shade Calculate the color at a prepared hit by a ray at depth from the lights, reflection and refraction
+func (w World) shade(comp Computations, remaining, depth int, deepest *int) patterns.RGB {
+	surface := *patterns.MakeRGB(0, 0, 0)
 	for _, light := range w.lights {
-		shade := 1.0
 		shadowVector := light.Position.Subtract(comp.OverPoint)
 		distance := shadowVector.Magnitude()
 		shadowRay := primitives.Ray{Origin:comp.OverPoint,
 									Direction:shadowVector.Normalize()}
-		shadowIntersections := w.Intersect(shadowRay)
-		_, shadowHit := shadowIntersections.Hit()
-		if shadowHit {
-			shadowShapes := make(map[shapes.Shape]bool)
-			for _, shadeIntersection := range shadowIntersections {
-				if shadeIntersection.Distance > distance {
-					break
-				}
-				if _, exists := shadowShapes[shadeIntersection.Obj]; !exists && shadeIntersection.Distance > 0 {
-					shadowShapes[shadeIntersection.Obj] = true
-					shade *= shadeIntersection.Obj.Material().Transparency
-				}
-			}
-		}
+		w.counts.AddRay(stats.ShadowRay)
+		// An emitting surface added as geometry must not shadow its own lights
+		shade := w.Transmittance(shadowRay, distance-primitives.EPSILON)
 		surface = surface.Add(Lighting(comp.Obj, light, comp.Point,
 							  comp.EyeVector, comp.NormalVector, shade))
 	}
-	reflected := w.ReflectedColor(comp, remaining)
-	refracted := w.RefractedColor(comp, remaining)
+	reflected := w.reflectedColor(comp, remaining, depth, deepest)
+	refracted := w.refractedColor(comp, remaining, depth, deepest)
 	material := comp.Obj.Material()
 	if material.Reflective > 0 && material.Transparency > 0 {
 		reflectance := comp.Schlick()
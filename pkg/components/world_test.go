@@ -2,11 +2,14 @@ package components_test
 
 import (
 	"math"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"github.com/factorion/graytracer/pkg/primitives"
 	"github.com/factorion/graytracer/pkg/components"
 	"github.com/factorion/graytracer/pkg/patterns"
 	"github.com/factorion/graytracer/pkg/shapes"
+	"github.com/factorion/graytracer/pkg/stats"
 )
 
 func TestWorldIntersect(t *testing.T) {
@@ -45,6 +48,115 @@ func TestWorldIntersect(t *testing.T) {
 	}
 }
 
+func TestWorldClosestHit(t *testing.T) {
+	world := &components.World{}
+	for _, z := range []float64{6, -3, 2} {
+		sphere := shapes.MakeSphere()
+		sphere.SetTransform(primitives.Translation(0, 0, z))
+		world.AddObject(sphere)
+	}
+	tables := []struct {
+		ray primitives.Ray
+		hit bool
+		distance float64
+	}{
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, -10), Direction:primitives.MakeVector(0, 0, 1)}, true, 6},
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, 0), Direction:primitives.MakeVector(0, 0, 1)}, true, 1},
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, 10), Direction:primitives.MakeVector(0, 0, 1)}, false, 0},
+		{primitives.Ray{Origin:primitives.MakePoint(0, 5, 0), Direction:primitives.MakeVector(0, 0, 1)}, false, 0},
+	}
+	for _, table := range tables {
+		hit, ok := world.ClosestHit(table.ray)
+		expected, expectedOk := world.Intersect(table.ray).Hit()
+		if ok != table.hit || ok != expectedOk {
+			t.Errorf("Ray %v, expected hit %v, got %v", table.ray, table.hit, ok)
+			continue
+		}
+		if ok && (hit.Distance != table.distance || hit != expected) {
+			t.Errorf("Ray %v, expected %v, got %v", table.ray, expected, hit)
+		}
+	}
+}
+
+func TestWorldCompile(t *testing.T) {
+	makeWorld := func() *components.World {
+		world := &components.World{}
+		world.AddLight(components.PointLight{Intensity:patterns.MakeRGB(1, 1, 1),
+			Position:primitives.MakePoint(-10, 10, -10)})
+		group := shapes.MakeGroup()
+		group.SetTransform(primitives.Translation(0, 1, 0).Multiply(primitives.Scaling(1, 2, 1)))
+		inner := shapes.MakeGroup()
+		inner.SetTransform(primitives.RotationY(0.5))
+		sphere := shapes.MakeSphere()
+		sphere.SetTransform(primitives.Translation(1, 0, 0))
+		triangle := shapes.MakeTriangle(primitives.MakePoint(-2, -1, 2), primitives.MakePoint(2, -1, 2),
+			primitives.MakePoint(0, 2, 2))
+		inner.AddShape(sphere)
+		inner.AddShape(triangle)
+		group.AddShape(inner)
+		world.AddObject(group)
+		return world
+	}
+	expected := makeWorld()
+	for _, flatten := range []bool{false, true} {
+		world := makeWorld()
+		world.Compile(flatten)
+		for x := -2.0; x <= 2; x += 0.25 {
+			for y := -1.0; y <= 3; y += 0.25 {
+				ray := primitives.Ray{Origin:primitives.MakePoint(x, y, -5), Direction:primitives.MakeVector(0, 0, 1)}
+				if result := world.ColorAt(ray, 5); !result.Equals(expected.ColorAt(ray, 5)) {
+					t.Errorf("Flatten %v, ray %v, expected %v, got %v", flatten, ray, expected.ColorAt(ray, 5), result)
+				}
+			}
+		}
+	}
+}
+
+func TestWorldTransmittance(t *testing.T) {
+	glass := patterns.MakeDefaultMaterial()
+	glass.Transparency = 0.5
+	opaque := patterns.MakeDefaultMaterial()
+	glassSphere := func(z float64) shapes.Shape {
+		sphere := shapes.MakeSphere()
+		sphere.SetTransform(primitives.Translation(0, 0, z))
+		sphere.SetMaterial(glass)
+		return sphere
+	}
+	group := shapes.MakeGroup()
+	group.AddShape(glassSphere(5))
+	wall := shapes.MakeCube()
+	wall.SetTransform(primitives.Translation(0, 0, 10).Multiply(primitives.Scaling(10, 10, 0.1)))
+	wall.SetMaterial(opaque)
+	group.AddShape(wall)
+	instance := shapes.MakeInstance(shapes.MakeSphere())
+	instance.SetTransform(primitives.Translation(0, 0, 5))
+	instance.SetMaterial(glass)
+	tables := []struct {
+		objects     []shapes.Shape
+		maxDistance float64
+		shade       float64
+	}{
+		{[]shapes.Shape{}, 100, 1},
+		{[]shapes.Shape{glassSphere(5)}, 100, 0.5},
+		{[]shapes.Shape{glassSphere(5), glassSphere(8)}, 100, 0.25},
+		{[]shapes.Shape{glassSphere(5), glassSphere(8)}, 6.5, 0.5},
+		{[]shapes.Shape{glassSphere(-5)}, 100, 1},
+		{[]shapes.Shape{group}, 100, 0},
+		{[]shapes.Shape{group}, 9, 0.5},
+		{[]shapes.Shape{instance}, 100, 0.5},
+	}
+	ray := primitives.Ray{Origin:primitives.MakePoint(0, 0, 0), Direction:primitives.MakeVector(0, 0, 1)}
+	for index, table := range tables {
+		world := components.MakeWorld()
+		for _, object := range table.objects {
+			world.AddObject(object)
+		}
+		if shade := world.Transmittance(ray, table.maxDistance); math.Abs(shade - table.shade) > primitives.EPSILON {
+			t.Errorf("Table %v, expected shade %v, got %v", index, table.shade, shade)
+		}
+	}
+}
+
 func TestReflectedColor(t *testing.T) {
 	tables := []struct {
 		shapes []shapes.Shape
@@ -413,3 +525,199 @@ func Benchmark64BoundingBoxes(b * testing.B) {
 		_ = world.ColorAt(ray, 5)
 	}
 }
+
+// sphereGridWorld World of 4096 spheres split into 64 groups
+func sphereGridWorld() *components.World {
+	world := &components.World{}
+	world.AddLight(components.PointLight{Intensity: patterns.MakeRGB(1, 1, 1),
+		Position: primitives.MakePoint(-30, 60, -30)})
+	var groups [64]*shapes.Group
+	for index := 0; index < 64; index++ {
+		groups[index] = shapes.MakeGroup()
+		world.AddObject(groups[index])
+	}
+	for x := 0.0; x < 16; x++ {
+		for y := 0.0; y < 16; y++ {
+			for z := 0.0; z < 16; z++ {
+				sphere := shapes.MakeSphere()
+				sphere.SetTransform(primitives.Translation(x * 4, y * 4, z * 4))
+				groups[(int(x / 4) * 16) + (int(y / 4) * 4) + (int(z / 4))].AddShape(sphere)
+			}
+		}
+	}
+	return world
+}
+
+func BenchmarkWorldIntersect(b *testing.B) {
+	world := sphereGridWorld()
+	ray := primitives.Ray{Origin:primitives.MakePoint(4, 4, -5), Direction:primitives.MakeVector(0, 0, 1)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = world.Intersect(ray).Hit()
+	}
+}
+
+func BenchmarkWorldClosestHit(b *testing.B) {
+	world := sphereGridWorld()
+	ray := primitives.Ray{Origin:primitives.MakePoint(4, 4, -5), Direction:primitives.MakeVector(0, 0, 1)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = world.ClosestHit(ray)
+	}
+}
+
+// packetWorld Lit world with a reflective plane, an opaque and a glass sphere and an OBJ grid
+func packetWorld(t testing.TB) (*components.World, *components.Camera) {
+	filename := filepath.Join(t.TempDir(), "grid.obj")
+	writeGridObj(t, filename, 32)
+	world := components.MakeWorld()
+	world.AddLight(components.PointLight{Intensity:patterns.MakeRGB(1, 1, 1), Position:primitives.MakePoint(-10, 10, -10)})
+	floor := shapes.MakePlane()
+	floor.SetMaterial(patterns.Material{Pat:patterns.MakeRGB(0.5, 0.5, 0.5), Ambient:0.1, Diffuse:0.9, Reflective:0.3})
+	world.AddObject(floor)
+	glass := shapes.MakeSphere()
+	glass.SetTransform(primitives.Translation(-1, 1, 0))
+	glass.SetMaterial(patterns.Material{Pat:patterns.MakeRGB(0.2, 0.2, 0.2), Ambient:0.1, Diffuse:0.5, Specular:1,
+		Shininess:200, Reflective:0.9, Transparency:0.9, RefractiveIndex:1.5})
+	world.AddObject(glass)
+	opaque := shapes.MakeSphere()
+	opaque.SetTransform(primitives.Translation(1.5, 0.5, -0.5).Multiply(primitives.Scaling(0.5, 0.5, 0.5)))
+	world.AddObject(opaque)
+	model := shapes.MakeGroup()
+	model.SetTransform(primitives.Translation(-0.5, 0.01, -2).Multiply(primitives.Scaling(0.1, 0.1, 0.1)))
+	for _, mesh := range components.ParseObjFile(filename, true, mats).Faces {
+		model.AddShape(mesh)
+	}
+	world.AddObject(model)
+	world.Compile(false)
+	camera := components.MakeCamera(64, 36, math.Pi / 3)
+	camera.ViewTransform(primitives.MakePoint(0, 2, -5), primitives.MakePoint(0, 0.5, 0), primitives.MakeVector(0, 1, 0))
+	return world, camera
+}
+
+func TestWorldColorAtPacket(t *testing.T) {
+	world, camera := packetWorld(t)
+	faces := 0
+	for y := uint64(0); y < 36; y += 2 {
+		// The last packet of each row only holds two rays
+		for x := uint64(0); x < 64; x += 3 {
+			rays := []primitives.Ray{}
+			for dy := uint64(0); dy < 2; dy++ {
+				for dx := uint64(0); dx < 2 && x + dx < 64; dx++ {
+					rays = append(rays, camera.RayForPixel(x + dx, y + dy))
+				}
+			}
+			colors := world.ColorAtPacket(rays, 5)
+			packet, _ := shapes.MakeRayPacket(rays)
+			hits := world.ClosestPacket(&packet)
+			for lane := range rays {
+				if _, ok := hits.Hits[lane].Obj.(shapes.MeshTriangle); ok {
+					faces++
+				}
+			}
+			for index, ray := range rays {
+				if expected := world.ColorAt(ray, 5); colors[index].ToImageRGBA() != expected.ToImageRGBA() ||
+					!colors[index].Equals(expected) {
+					t.Errorf("Ray %v, expected %v, got %v", ray, expected, colors[index])
+				}
+			}
+		}
+	}
+	if faces == 0 {
+		t.Error("Expected some packets to hit the faces of the mesh")
+	}
+	// More rays than fit in a packet are split over several
+	rays := []primitives.Ray{}
+	for x := uint64(28); x < 36; x++ {
+		rays = append(rays, camera.RayForPixel(x, 18))
+	}
+	colors := world.ColorAtPacket(rays, 5)
+	if len(colors) != len(rays) {
+		t.Fatalf("Expected %v colors, got %v", len(rays), len(colors))
+	}
+	for index, ray := range rays {
+		if expected := world.ColorAt(ray, 5); !colors[index].Equals(expected) {
+			t.Errorf("Ray %v of %v, expected %v, got %v", index, len(rays), expected, colors[index])
+		}
+	}
+	if colors := world.ColorAtPacket([]primitives.Ray{camera.RayForPixel(32, 18)}, 0); !colors[0].Equals(
+		*patterns.MakeRGB(0, 0, 0)) {
+		t.Errorf("Expected black without any remaining bounces, got %v", colors[0])
+	}
+}
+
+func BenchmarkPrimaryRays(b *testing.B) {
+	world, camera := packetWorld(b)
+	rays := []primitives.Ray{camera.RayForPixel(40, 28), camera.RayForPixel(41, 28), camera.RayForPixel(40, 29),
+		camera.RayForPixel(41, 29)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, ray := range rays {
+			world.ColorAt(ray, 5)
+		}
+	}
+}
+
+func BenchmarkPrimaryPackets(b *testing.B) {
+	world, camera := packetWorld(b)
+	rays := []primitives.Ray{camera.RayForPixel(40, 28), camera.RayForPixel(41, 28), camera.RayForPixel(40, 29),
+		camera.RayForPixel(41, 29)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		world.ColorAtPacket(rays, 5)
+	}
+}
+
+func TestWorldStats(t *testing.T) {
+	world := components.MakeWorld()
+	world.AddLight(components.PointLight{Intensity:patterns.MakeRGB(1, 1, 1), Position:primitives.MakePoint(-10, 10, -10)})
+	mirror := shapes.MakePlane()
+	mirror.SetMaterial(patterns.Material{Pat:patterns.MakeRGB(1, 1, 1), Ambient:0.1, Diffuse:0.9, Reflective:0.5})
+	mirror.SetTransform(primitives.Translation(0, -1, 0))
+	world.AddObject(mirror)
+	world.AddObject(shapes.MakeSphere())
+	tables := []struct {
+		ray primitives.Ray
+		remaining int
+		report stats.Report
+	}{
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, -5), Direction:primitives.MakeVector(0, 0, 1)}, 5,
+			stats.Report{PrimaryRays:1, ShadowRays:1, ShapeTests:map[string]uint64{"Plane":2, "Sphere":2}}},
+		// Down to the mirror and back up to the sphere
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, -5), Direction:primitives.MakeVector(0, -1, 2.5).Normalize()}, 5,
+			stats.Report{PrimaryRays:1, ShadowRays:2, ReflectionRays:1, MaxDepth:1,
+				ShapeTests:map[string]uint64{"Plane":4, "Sphere":4}}},
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, -5), Direction:primitives.MakeVector(0, -1, 2.5).Normalize()}, 1,
+			stats.Report{PrimaryRays:1, ShadowRays:1, ShapeTests:map[string]uint64{"Plane":2, "Sphere":2}}},
+		{primitives.Ray{Origin:primitives.MakePoint(0, 0, -5), Direction:primitives.MakeVector(0, -1, 2.5).Normalize()}, 0,
+			stats.Report{ShapeTests:map[string]uint64{}}},
+	}
+	for index, table := range tables {
+		collector := &stats.Collector{}
+		counts := &stats.Counts{}
+		world.Counting(counts).ColorAt(table.ray, table.remaining)
+		collector.Merge(counts)
+		report := collector.Report()
+		if report.PrimaryRays != table.report.PrimaryRays || report.ShadowRays != table.report.ShadowRays ||
+			report.ReflectionRays != table.report.ReflectionRays || report.RefractionRays != table.report.RefractionRays ||
+			report.MaxDepth != table.report.MaxDepth || !reflect.DeepEqual(report.ShapeTests, table.report.ShapeTests) {
+			t.Errorf("Table %v, expected %+v, got %+v", index, table.report, report)
+		}
+	}
+	// Rays traced together are counted once each
+	collector := &stats.Collector{}
+	counts := &stats.Counts{}
+	world.Counting(counts).ColorAtPacket([]primitives.Ray{tables[0].ray, tables[1].ray}, 5)
+	collector.Merge(counts)
+	if report := collector.Report(); report.PrimaryRays != 2 || report.ShadowRays != 3 || report.ReflectionRays != 1 ||
+		report.MaxDepth != 1 {
+		t.Errorf("Expected the rays of the first two tables, got %+v", report)
+	}
+	// The world the counting copy was made from counts nothing
+	rays, tests := counts.Rays, counts.Tests()
+	world.ColorAt(tables[1].ray, 5)
+	world.ColorAtPacket([]primitives.Ray{tables[0].ray, tables[1].ray}, 5)
+	if counts.Rays != rays || counts.Tests() != tests {
+		t.Errorf("Expected nothing more to be counted, got %+v", counts)
+	}
+}
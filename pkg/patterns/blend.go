@@ -0,0 +1,63 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Blend Weighted average of two patterns
+type Blend struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+	weight             float64
+}
+
+// MakeBlend Blend two patterns, a weight of 0 is only the first pattern and 1 only the second
+func MakeBlend(p1, p2 Pattern, weight float64) *Blend {
+	return &Blend{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2, weight: weight}
+}
+
+// ColorAt Return color at specific point
+func (b Blend) ColorAt(point primitives.PV) RGB {
+	patternPoint := b.PatternPoint(point)
+	color1 := b.pattern1.ColorAt(patternPoint)
+	color2 := b.pattern2.ColorAt(patternPoint)
+	return color1.Scale(1 - b.weight).Add(color2.Scale(b.weight))
+}
+
+// Solid Solid when either of the blended patterns is
+func (b Blend) Solid() bool {
+	return IsSolid(b.pattern1) || IsSolid(b.pattern2)
+}
+
+// Mask Choose between two patterns using the intensity of a third pattern
+type Mask struct {
+	PatternBase
+	pattern1, pattern2, mask Pattern
+}
+
+// MakeMask Show the first pattern where the mask is black and the second where it is white
+func MakeMask(p1, p2, mask Pattern) *Mask {
+	return &Mask{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2, mask: mask}
+}
+
+// ColorAt Return color at specific point
+func (m Mask) ColorAt(point primitives.PV) RGB {
+	patternPoint := m.PatternPoint(point)
+	weight := math.Max(0, math.Min(1, m.mask.ColorAt(patternPoint).Intensity()))
+	if weight == 0 {
+		return m.pattern1.ColorAt(patternPoint)
+	}
+	if weight == 1 {
+		return m.pattern2.ColorAt(patternPoint)
+	}
+	color1 := m.pattern1.ColorAt(patternPoint)
+	color2 := m.pattern2.ColorAt(patternPoint)
+	return color1.Scale(1 - weight).Add(color2.Scale(weight))
+}
+
+// Solid Solid when either of the patterns or the mask is
+func (m Mask) Solid() bool {
+	return IsSolid(m.pattern1) || IsSolid(m.pattern2) || IsSolid(m.mask)
+}
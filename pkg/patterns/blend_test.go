@@ -0,0 +1,72 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestBlendColorAt(t *testing.T) {
+	tables := []struct {
+		b      *patterns.Blend
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{patterns.MakeBlend(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), 0),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(1, 1, 1)},
+
+		{patterns.MakeBlend(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), 1),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0, 0, 0)},
+
+		{patterns.MakeBlend(patterns.MakeRGB(1, 0.5, 0), patterns.MakeRGB(0, 0.5, 1), 0.25),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0.75, 0.5, 0.25)},
+
+		{patterns.MakeBlend(patterns.MakeStripe(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			patterns.MakeRGB(0, 0, 1), 0.5),
+			primitives.MakePoint(1.5, 0, 0),
+			patterns.MakeRGB(0, 0, 0.5)},
+	}
+	for _, table := range tables {
+		result := table.b.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
+
+func TestMaskColorAt(t *testing.T) {
+	tables := []struct {
+		m      *patterns.Mask
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{patterns.MakeMask(patterns.MakeRGB(1, 0, 0), patterns.MakeRGB(0, 0, 1),
+			patterns.MakeStripe(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1))),
+			primitives.MakePoint(0.5, 0, 0),
+			patterns.MakeRGB(1, 0, 0)},
+
+		{patterns.MakeMask(patterns.MakeRGB(1, 0, 0), patterns.MakeRGB(0, 0, 1),
+			patterns.MakeStripe(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1))),
+			primitives.MakePoint(1.5, 0, 0),
+			patterns.MakeRGB(0, 0, 1)},
+
+		{patterns.MakeMask(patterns.MakeRGB(1, 0, 0), patterns.MakeRGB(0, 0, 1),
+			patterns.MakeGradient(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1))),
+			primitives.MakePoint(0.25, 0, 0),
+			patterns.MakeRGB(0.75, 0, 0.25)},
+
+		{patterns.MakeMask(patterns.MakeRGB(1, 0, 0), patterns.MakeRGB(0, 0, 1), patterns.MakeRGB(2, 2, 2)),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0, 0, 1)},
+	}
+	for _, table := range tables {
+		result := table.m.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
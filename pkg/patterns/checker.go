@@ -24,3 +24,28 @@ func (c Checker) ColorAt(point primitives.PV) RGB {
 	}
 	return c.pattern2.ColorAt(patternPoint)
 }
+
+// Checker3D Solid checker pattern alternating along all three axes
+type Checker3D struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+}
+
+// MakeChecker3D Return a solid 3D Checker pattern with two colors
+func MakeChecker3D(p1, p2 Pattern) *Checker3D {
+	return &Checker3D{PatternBase:MakePatternBase(), pattern1:p1, pattern2:p2}
+}
+
+// ColorAt Return color at specific point
+func (c Checker3D) ColorAt(point primitives.PV) RGB {
+	patternPoint := c.PatternPoint(point)
+	if (int(math.Floor(patternPoint.X)) + int(math.Floor(patternPoint.Y)) + int(math.Floor(patternPoint.Z))) % 2 == 0 {
+		return c.pattern1.ColorAt(patternPoint)
+	}
+	return c.pattern2.ColorAt(patternPoint)
+}
+
+// Solid Sampled at points in object-space since it varies along all three axes
+func (c Checker3D) Solid() bool {
+	return true
+}
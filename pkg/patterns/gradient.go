@@ -1,25 +1,38 @@
 package patterns
 
 import (
+	"errors"
 	"math"
 	"github.com/factorion/graytracer/pkg/primitives"
 )
 
-// Gradient Basic gradient pattern based on x-axis value
+// Gradient Basic gradient pattern based on x-axis value, or the value along another direction
 type Gradient struct {
 	PatternBase
 	pattern1, pattern2 Pattern
+	direction primitives.PV
 }
 
 // MakeGradient Return a basic gradient pattern with two colors
 func MakeGradient(p1, p2 Pattern) *Gradient {
-	return &Gradient{PatternBase:PatternBase{transform:primitives.MakeIdentityMatrix(4)}, pattern1:p1, pattern2:p2}
+	return &Gradient{PatternBase:PatternBase{transform:primitives.MakeIdentityMatrix(4)}, pattern1:p1, pattern2:p2,
+					 direction:primitives.MakeVector(1, 0, 0)}
+}
+
+// SetDirection Set the axis or direction the gradient runs along, a zero vector has no direction and is an error
+func (g *Gradient) SetDirection(direction primitives.PV) error {
+	direction.W = 0
+	if direction.Magnitude() < primitives.EPSILON {
+		return errors.New("direction must not be a zero vector")
+	}
+	g.direction = direction.Normalize()
+	return nil
 }
 
 // ColorAt Return color at specific point
 func (g Gradient) ColorAt(point primitives.PV) RGB {
 	patternPoint := g.PatternPoint(point)
-	pX := patternPoint.X
+	pX := AlongDirection(patternPoint, g.direction)
 	color1 := g.pattern1.ColorAt(patternPoint)
 	color2 := g.pattern2.ColorAt(patternPoint)
 	return color1.Add(color2.Subtract(color1).Scale(pX - math.Floor(pX)))
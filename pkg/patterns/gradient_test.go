@@ -45,3 +45,42 @@ func TestGradientColorAt(t *testing.T) {
 		}
 	}
 }
+
+func TestGradientDirection(t *testing.T) {
+	tables := []struct {
+		direction primitives.PV
+		point primitives.PV
+		result *patterns.RGB
+	}{
+		{primitives.MakeVector(0, 1, 0),
+		 primitives.MakePoint(0.75, 0.25, 0),
+		 patterns.MakeRGB(0.75, 0.75, 0.75)},
+
+		{primitives.MakeVector(0, 0, 1),
+		 primitives.MakePoint(0.75, 0.25, 0.5),
+		 patterns.MakeRGB(0.5, 0.5, 0.5)},
+
+		{primitives.MakeVector(0, 0, -1),
+		 primitives.MakePoint(0, 0, 0.25),
+		 patterns.MakeRGB(0.25, 0.25, 0.25)},
+	}
+	for _, table := range tables {
+		g := patterns.MakeGradient(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+		if err := g.SetDirection(table.direction); err != nil {
+			t.Fatal(err)
+		}
+		result := g.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+	// A zero vector is rejected, leaving the direction along the x-axis
+	g := patterns.MakeGradient(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+	if err := g.SetDirection(primitives.MakeVector(0, 0, 0)); err == nil {
+		t.Error("Expected an error for a zero direction")
+	}
+	result, expected := g.ColorAt(primitives.MakePoint(0.25, 5, 5)), g.ColorAt(primitives.MakePoint(0.25, 0, 0))
+	if !result.Equals(expected) {
+		t.Errorf("Expected the direction to stay along the x-axis, got %v and %v", result, expected)
+	}
+}
@@ -0,0 +1,72 @@
+package patterns
+
+import (
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// ImageTexture Pattern sampled from an image using the UV coordinates in the X and Y of a point
+type ImageTexture struct {
+	PatternBase
+	width, height int
+	pixels        []RGB
+}
+
+// MakeImageTexture Make a texture from an image, storing the pixels as linear colors
+func MakeImageTexture(img image.Image) *ImageTexture {
+	bounds := img.Bounds()
+	it := &ImageTexture{PatternBase: MakePatternBase(), width: bounds.Dx(), height: bounds.Dy(),
+		pixels: make([]RGB, bounds.Dx()*bounds.Dy())}
+	for y := 0; y < it.height; y++ {
+		for x := 0; x < it.width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			it.pixels[(y*it.width)+x] = *MakeRGB(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff)
+		}
+	}
+	return it
+}
+
+// LoadImageTexture Decode a PNG or JPEG file into a texture
+func LoadImageTexture(filename string) (*ImageTexture, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return MakeImageTexture(img), nil
+}
+
+// pixel Get a pixel, wrapping the coordinates around the edges of the image
+func (it ImageTexture) pixel(x, y int) RGB {
+	x = ((x % it.width) + it.width) % it.width
+	y = ((y % it.height) + it.height) % it.height
+	return it.pixels[(y*it.width)+x]
+}
+
+// ColorAt Bilinearly sample the image, U runs left to right and V bottom to top, repeating every unit
+func (it ImageTexture) ColorAt(point primitives.PV) RGB {
+	if it.width == 0 || it.height == 0 {
+		return *MakeRGB(0, 0, 0)
+	}
+	patternPoint := it.PatternPoint(point)
+	u := patternPoint.X - math.Floor(patternPoint.X)
+	v := patternPoint.Y - math.Floor(patternPoint.Y)
+	x := (u * float64(it.width)) - 0.5
+	y := ((1 - v) * float64(it.height)) - 0.5
+	x0 := math.Floor(x)
+	y0 := math.Floor(y)
+	fx := x - x0
+	fy := y - y0
+	top := it.pixel(int(x0), int(y0)).Scale(1 - fx).Add(it.pixel(int(x0)+1, int(y0)).Scale(fx))
+	bottom := it.pixel(int(x0), int(y0)+1).Scale(1 - fx).Add(it.pixel(int(x0)+1, int(y0)+1).Scale(fx))
+	return top.Scale(1 - fy).Add(bottom.Scale(fy))
+}
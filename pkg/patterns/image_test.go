@@ -0,0 +1,42 @@
+package patterns_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestImageTextureColorAt(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	img.Set(0, 1, color.RGBA{0, 0, 255, 255})
+	img.Set(1, 1, color.RGBA{255, 255, 255, 255})
+	tables := []struct {
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{primitives.MakePoint(0.25, 0.75, 0), patterns.MakeRGB(1, 0, 0)},
+		{primitives.MakePoint(0.75, 0.75, 0), patterns.MakeRGB(0, 1, 0)},
+		{primitives.MakePoint(0.25, 0.25, 0), patterns.MakeRGB(0, 0, 1)},
+		{primitives.MakePoint(1.75, -0.75, 0), patterns.MakeRGB(1, 1, 1)},
+		{primitives.MakePoint(0.5, 0.75, 0), patterns.MakeRGB(0.5, 0.5, 0)},
+		{primitives.MakePoint(0.5, 0.5, 0), patterns.MakeRGB(0.5, 0.5, 0.5)},
+	}
+	texture := patterns.MakeImageTexture(img)
+	for _, table := range tables {
+		result := texture.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
+
+func TestLoadImageTexture(t *testing.T) {
+	if _, err := patterns.LoadImageTexture("missing.png"); err == nil {
+		t.Error("Expected an error loading a missing image")
+	}
+}
@@ -0,0 +1,58 @@
+package patterns
+
+import (
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// LayerOperation How the colors of layered patterns are combined
+type LayerOperation int
+
+const (
+	ADD LayerOperation = iota
+	MULTIPLY
+)
+
+// Layers Stack of patterns combined with a single operation
+type Layers struct {
+	PatternBase
+	op     LayerOperation
+	layers []Pattern
+}
+
+// MakeLayers Combine any number of patterns by adding or multiplying their colors
+func MakeLayers(op LayerOperation, layers ...Pattern) *Layers {
+	return &Layers{PatternBase: MakePatternBase(), op: op, layers: layers}
+}
+
+// AddLayer Add another pattern on top of the stack
+func (l *Layers) AddLayer(layer Pattern) {
+	l.layers = append(l.layers, layer)
+}
+
+// ColorAt Return color at specific point
+func (l Layers) ColorAt(point primitives.PV) RGB {
+	patternPoint := l.PatternPoint(point)
+	if len(l.layers) == 0 {
+		return *MakeRGB(0, 0, 0)
+	}
+	result := l.layers[0].ColorAt(patternPoint)
+	for _, layer := range l.layers[1:] {
+		switch l.op {
+		case ADD:
+			result = result.Add(layer.ColorAt(patternPoint))
+		case MULTIPLY:
+			result = result.Multiply(layer.ColorAt(patternPoint))
+		}
+	}
+	return result
+}
+
+// Solid Solid when any of the layers is
+func (l Layers) Solid() bool {
+	for _, layer := range l.layers {
+		if IsSolid(layer) {
+			return true
+		}
+	}
+	return false
+}
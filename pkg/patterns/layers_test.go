@@ -0,0 +1,48 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestLayersColorAt(t *testing.T) {
+	tables := []struct {
+		l      *patterns.Layers
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{patterns.MakeLayers(patterns.ADD),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0, 0, 0)},
+
+		{patterns.MakeLayers(patterns.ADD, patterns.MakeRGB(0.5, 0.25, 0), patterns.MakeRGB(0.25, 0.25, 0.5)),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0.75, 0.5, 0.5)},
+
+		{patterns.MakeLayers(patterns.MULTIPLY, patterns.MakeRGB(0.5, 0.25, 1), patterns.MakeRGB(0.5, 1, 0)),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0.25, 0.25, 0)},
+
+		{patterns.MakeLayers(patterns.MULTIPLY, patterns.MakeRGB(1, 0.5, 1),
+			patterns.MakeStripe(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)), patterns.MakeRGB(0.5, 0.5, 0.5)),
+			primitives.MakePoint(0.5, 0, 0),
+			patterns.MakeRGB(0.5, 0.25, 0.5)},
+	}
+	for _, table := range tables {
+		result := table.l.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
+
+func TestLayersAddLayer(t *testing.T) {
+	layers := patterns.MakeLayers(patterns.ADD, patterns.MakeRGB(0.25, 0, 0))
+	layers.AddLayer(patterns.MakeRGB(0, 0.5, 0))
+	result := layers.ColorAt(primitives.MakePoint(0, 0, 0))
+	if !result.Equals(*patterns.MakeRGB(0.25, 0.5, 0)) {
+		t.Errorf("Expected %v, got %v", patterns.MakeRGB(0.25, 0.5, 0), result)
+	}
+}
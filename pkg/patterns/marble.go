@@ -0,0 +1,47 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Marble Veins along the x-axis distorted by turbulence
+type Marble struct {
+	PatternBase
+	pattern1, pattern2  Pattern
+	frequency, strength float64
+	octaves             int
+}
+
+// MakeMarble Make a marble pattern with veins of the second pattern running through the first
+func MakeMarble(p1, p2 Pattern) *Marble {
+	return &Marble{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2,
+		frequency: math.Pi, strength: 5, octaves: 4}
+}
+
+// SetFrequency Set how many veins appear per unit along the x-axis
+func (m *Marble) SetFrequency(frequency float64) {
+	m.frequency = frequency
+}
+
+// SetTurbulence Set the strength and number of octaves of the turbulence distorting the veins
+func (m *Marble) SetTurbulence(strength float64, octaves int) {
+	m.strength = strength
+	m.octaves = octaves
+}
+
+// ColorAt Return color at specific point
+func (m Marble) ColorAt(point primitives.PV) RGB {
+	patternPoint := m.PatternPoint(point)
+	turbulence := Turbulence(Perlin, patternPoint, m.octaves)
+	blend := (math.Sin(m.frequency*(patternPoint.X+(m.strength*turbulence))) + 1) / 2
+	color1 := m.pattern1.ColorAt(patternPoint)
+	color2 := m.pattern2.ColorAt(patternPoint)
+	return color1.Add(color2.Subtract(color1).Scale(blend))
+}
+
+// Solid Sampled at points in object-space since it varies along all three axes
+func (m Marble) Solid() bool {
+	return true
+}
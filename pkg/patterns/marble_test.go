@@ -0,0 +1,30 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestMarbleColorAt(t *testing.T) {
+	tables := []struct {
+		strength float64
+		point    primitives.PV
+		result   *patterns.RGB
+	}{
+		{0, primitives.MakePoint(0, 0, 0), patterns.MakeRGB(0.5, 0.5, 0.5)},
+		{0, primitives.MakePoint(0.5, 0, 0), patterns.MakeRGB(0, 0, 0)},
+		{0, primitives.MakePoint(1.5, 0, 0), patterns.MakeRGB(1, 1, 1)},
+		{5, primitives.MakePoint(0.5, 0.3, 0.7),
+			patterns.MakeRGB(0.20723830339444105, 0.20723830339444105, 0.20723830339444105)},
+	}
+	for _, table := range tables {
+		marble := patterns.MakeMarble(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+		marble.SetTurbulence(table.strength, 4)
+		result := marble.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package patterns
 
-// Material Basic Phong material
+// Material Basic Phong material, with optional bump or tangent-space normal maps
 type Material struct {
 	Pat Pattern
 	Ambient, Diffuse, Specular, Shininess, Reflective, Transparency, RefractiveIndex float64
+	Bump, NormalMap Pattern
+	BumpScale float64
 }
 
 // MakeDefaultMaterial Create a basic material
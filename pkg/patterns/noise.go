@@ -0,0 +1,219 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// NoiseFunction Scalar 3D noise function returning values roughly in the range [-1, 1]
+type NoiseFunction func(primitives.PV) float64
+
+// Ken Perlin's reference permutation, repeated to avoid wrapping the index
+var permutation = [512]int{}
+
+func init() {
+	reference := [256]int{151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225,
+		140, 36, 103, 30, 69, 142, 8, 99, 37, 240, 21, 10, 23, 190, 6, 148, 247, 120, 234, 75, 0, 26,
+		197, 62, 94, 252, 219, 203, 117, 35, 11, 32, 57, 177, 33, 88, 237, 149, 56, 87, 174, 20, 125,
+		136, 171, 168, 68, 175, 74, 165, 71, 134, 139, 48, 27, 166, 77, 146, 158, 231, 83, 111, 229,
+		122, 60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244, 102, 143, 54, 65, 25, 63, 161,
+		1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169, 200, 196, 135, 130, 116, 188, 159, 86,
+		164, 100, 109, 198, 173, 186, 3, 64, 52, 217, 226, 250, 124, 123, 5, 202, 38, 147, 118, 126,
+		255, 82, 85, 212, 207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42, 223, 183, 170, 213, 119,
+		248, 152, 2, 44, 154, 163, 70, 221, 153, 101, 155, 167, 43, 172, 9, 129, 22, 39, 253, 19, 98,
+		108, 110, 79, 113, 224, 232, 178, 185, 112, 104, 218, 246, 97, 228, 251, 34, 242, 193, 238,
+		210, 144, 12, 191, 179, 162, 241, 81, 51, 145, 235, 249, 14, 239, 107, 49, 192, 214, 31, 181,
+		199, 106, 157, 184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254, 138, 236, 205, 93, 222,
+		114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180}
+	for i := 0; i < 512; i++ {
+		permutation[i] = reference[i%256]
+	}
+}
+
+// fade Quintic smoothing curve 6t^5 - 15t^4 + 10t^3
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// lerp Linear interpolation from a to b
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad Dot product of a pseudo-random gradient with the distance vector
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// Perlin Improved Perlin gradient noise at a point
+func Perlin(point primitives.PV) float64 {
+	floorX := math.Floor(point.X)
+	floorY := math.Floor(point.Y)
+	floorZ := math.Floor(point.Z)
+	cx := int(floorX) & 255
+	cy := int(floorY) & 255
+	cz := int(floorZ) & 255
+	x := point.X - floorX
+	y := point.Y - floorY
+	z := point.Z - floorZ
+	u := fade(x)
+	v := fade(y)
+	w := fade(z)
+	a := permutation[cx] + cy
+	aa := permutation[a] + cz
+	ab := permutation[a+1] + cz
+	b := permutation[cx+1] + cy
+	ba := permutation[b] + cz
+	bb := permutation[b+1] + cz
+	return lerp(w, lerp(v, lerp(u, grad(permutation[aa], x, y, z),
+		grad(permutation[ba], x-1, y, z)),
+		lerp(u, grad(permutation[ab], x, y-1, z),
+			grad(permutation[bb], x-1, y-1, z))),
+		lerp(v, lerp(u, grad(permutation[aa+1], x, y, z-1),
+			grad(permutation[ba+1], x-1, y, z-1)),
+			lerp(u, grad(permutation[ab+1], x, y-1, z-1),
+				grad(permutation[bb+1], x-1, y-1, z-1))))
+}
+
+// Gradient directions to the edges of a cube used by simplex noise
+var simplexGradients = [12][3]float64{{1, 1, 0}, {-1, 1, 0}, {1, -1, 0}, {-1, -1, 0},
+	{1, 0, 1}, {-1, 0, 1}, {1, 0, -1}, {-1, 0, -1},
+	{0, 1, 1}, {0, -1, 1}, {0, 1, -1}, {0, -1, -1}}
+
+// simplexCorner Contribution of a single simplex corner
+func simplexCorner(hash int, x, y, z float64) float64 {
+	t := 0.6 - (x * x) - (y * y) - (z * z)
+	if t < 0 {
+		return 0
+	}
+	g := simplexGradients[hash%12]
+	t *= t
+	return t * t * ((g[0] * x) + (g[1] * y) + (g[2] * z))
+}
+
+// Simplex Simplex gradient noise at a point, cheaper than Perlin with fewer directional artifacts
+func Simplex(point primitives.PV) float64 {
+	const skew = 1.0 / 3.0
+	const unskew = 1.0 / 6.0
+	s := (point.X + point.Y + point.Z) * skew
+	i := math.Floor(point.X + s)
+	j := math.Floor(point.Y + s)
+	k := math.Floor(point.Z + s)
+	t := (i + j + k) * unskew
+	x0 := point.X - (i - t)
+	y0 := point.Y - (j - t)
+	z0 := point.Z - (k - t)
+	// Find which of the six tetrahedra the point is in
+	var i1, j1, k1, i2, j2, k2 int
+	if x0 >= y0 {
+		if y0 >= z0 {
+			i1, j1, k1, i2, j2, k2 = 1, 0, 0, 1, 1, 0
+		} else if x0 >= z0 {
+			i1, j1, k1, i2, j2, k2 = 1, 0, 0, 1, 0, 1
+		} else {
+			i1, j1, k1, i2, j2, k2 = 0, 0, 1, 1, 0, 1
+		}
+	} else {
+		if y0 < z0 {
+			i1, j1, k1, i2, j2, k2 = 0, 0, 1, 0, 1, 1
+		} else if x0 < z0 {
+			i1, j1, k1, i2, j2, k2 = 0, 1, 0, 0, 1, 1
+		} else {
+			i1, j1, k1, i2, j2, k2 = 0, 1, 0, 1, 1, 0
+		}
+	}
+	x1 := x0 - float64(i1) + unskew
+	y1 := y0 - float64(j1) + unskew
+	z1 := z0 - float64(k1) + unskew
+	x2 := x0 - float64(i2) + (2 * unskew)
+	y2 := y0 - float64(j2) + (2 * unskew)
+	z2 := z0 - float64(k2) + (2 * unskew)
+	x3 := x0 - 1 + (3 * unskew)
+	y3 := y0 - 1 + (3 * unskew)
+	z3 := z0 - 1 + (3 * unskew)
+	ii := int(i) & 255
+	jj := int(j) & 255
+	kk := int(k) & 255
+	n0 := simplexCorner(permutation[ii+permutation[jj+permutation[kk]]], x0, y0, z0)
+	n1 := simplexCorner(permutation[ii+i1+permutation[jj+j1+permutation[kk+k1]]], x1, y1, z1)
+	n2 := simplexCorner(permutation[ii+i2+permutation[jj+j2+permutation[kk+k2]]], x2, y2, z2)
+	n3 := simplexCorner(permutation[ii+1+permutation[jj+1+permutation[kk+1]]], x3, y3, z3)
+	// Scale the result to cover roughly [-1, 1]
+	return 32 * (n0 + n1 + n2 + n3)
+}
+
+// FBM Fractal Brownian motion, sums octaves of noise with increasing frequency and decreasing amplitude
+func FBM(noise NoiseFunction, point primitives.PV, octaves int, lacunarity, gain float64) float64 {
+	sum := 0.0
+	amplitude := 1.0
+	frequency := 1.0
+	for octave := 0; octave < octaves; octave++ {
+		sum += amplitude * noise(primitives.MakePoint(point.X*frequency, point.Y*frequency, point.Z*frequency))
+		frequency *= lacunarity
+		amplitude *= gain
+	}
+	return sum
+}
+
+// Turbulence Sum of the absolute value of octaves of noise, giving sharp creases at the zero crossings
+func Turbulence(noise NoiseFunction, point primitives.PV, octaves int) float64 {
+	sum := 0.0
+	amplitude := 1.0
+	frequency := 1.0
+	for octave := 0; octave < octaves; octave++ {
+		sum += amplitude * math.Abs(noise(primitives.MakePoint(point.X*frequency, point.Y*frequency, point.Z*frequency)))
+		frequency *= 2
+		amplitude *= 0.5
+	}
+	return sum
+}
+
+// Noise Blend between two patterns using fractal noise
+type Noise struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+	noise              NoiseFunction
+	octaves            int
+}
+
+// MakeNoise Make a noise pattern blending two patterns with octaves of Perlin noise
+func MakeNoise(p1, p2 Pattern, octaves int) *Noise {
+	return &Noise{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2, noise: Perlin, octaves: octaves}
+}
+
+// SetNoiseFunction Replace the noise function used by the pattern
+func (n *Noise) SetNoiseFunction(noise NoiseFunction) {
+	n.noise = noise
+}
+
+// ColorAt Return color at specific point
+func (n Noise) ColorAt(point primitives.PV) RGB {
+	patternPoint := n.PatternPoint(point)
+	blend := math.Max(0, math.Min(1, (FBM(n.noise, patternPoint, n.octaves, 2, 0.5)+1)/2))
+	color1 := n.pattern1.ColorAt(patternPoint)
+	color2 := n.pattern2.ColorAt(patternPoint)
+	return color1.Add(color2.Subtract(color1).Scale(blend))
+}
+
+// Solid Sampled at points in object-space since it varies along all three axes
+func (n Noise) Solid() bool {
+	return true
+}
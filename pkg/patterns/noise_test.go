@@ -0,0 +1,92 @@
+package patterns_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestPerlin(t *testing.T) {
+	tables := []struct {
+		point  primitives.PV
+		result float64
+	}{
+		{primitives.MakePoint(0, 0, 0), 0},
+		{primitives.MakePoint(1, 2, 3), 0},
+		{primitives.MakePoint(3.14, 42, 7), 0.13691995878400012},
+	}
+	for _, table := range tables {
+		result := patterns.Perlin(table.point)
+		if math.Abs(result-table.result) > primitives.EPSILON {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
+
+func TestNoiseRange(t *testing.T) {
+	tables := []struct {
+		name  string
+		noise patterns.NoiseFunction
+	}{
+		{"Perlin", patterns.Perlin},
+		{"Simplex", patterns.Simplex},
+	}
+	for _, table := range tables {
+		for i := 0.0; i < 1000; i++ {
+			point := primitives.MakePoint(math.Sin(i)*50, math.Cos(i*1.3)*50, i*0.37)
+			value := table.noise(point)
+			if value < -1 || value > 1 {
+				t.Errorf("%s noise out of range at %v: %v", table.name, point, value)
+			}
+			if value != table.noise(point) {
+				t.Errorf("%s noise is not deterministic at %v", table.name, point)
+			}
+		}
+	}
+}
+
+func TestFBM(t *testing.T) {
+	point := primitives.MakePoint(3.14, 42, 7)
+	single := patterns.FBM(patterns.Perlin, point, 1, 2, 0.5)
+	if math.Abs(single-patterns.Perlin(point)) > primitives.EPSILON {
+		t.Errorf("Expected a single octave to equal the noise, got %v", single)
+	}
+	double := patterns.FBM(patterns.Perlin, point, 2, 2, 0.5)
+	expected := patterns.Perlin(point) + (0.5 * patterns.Perlin(primitives.MakePoint(6.28, 84, 14)))
+	if math.Abs(double-expected) > primitives.EPSILON {
+		t.Errorf("Expected %v, got %v", expected, double)
+	}
+}
+
+func TestTurbulence(t *testing.T) {
+	for i := 0.0; i < 100; i++ {
+		point := primitives.MakePoint(i*0.31, i*0.17, i*0.53)
+		if value := patterns.Turbulence(patterns.Perlin, point, 4); value < 0 {
+			t.Errorf("Expected non-negative turbulence at %v, got %v", point, value)
+		}
+	}
+}
+
+func TestNoiseColorAt(t *testing.T) {
+	tables := []struct {
+		n      *patterns.Noise
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{patterns.MakeNoise(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), 1),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(0.5, 0.5, 0.5)},
+
+		{patterns.MakeNoise(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), 3),
+			primitives.MakePoint(0.5, 0.3, 0.7),
+			patterns.MakeRGB(0.6652469321600001, 0.6652469321600001, 0.6652469321600001)},
+	}
+	for _, table := range tables {
+		result := table.n.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
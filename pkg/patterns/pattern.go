@@ -25,12 +25,30 @@ func (pb PatternBase) PatternPoint(point primitives.PV) primitives.PV {
 	return point.Transform(inverse)
 }
 
+// AlongDirection Distance of a point along a normalized direction through the origin
+func AlongDirection(point, direction primitives.PV) float64 {
+	return (point.X * direction.X) + (point.Y * direction.Y) + (point.Z * direction.Z)
+}
+
 // Pattern Patterns are represented with a GetColorAt function
 type Pattern interface {
 	ColorAt(primitives.PV) RGB
 	SetTransform(primitives.Matrix)
 }
 
+// SolidPattern Pattern defined throughout space rather than over a surface, which is sampled at points in the
+// object-space of a shape instead of its UV coordinates
+type SolidPattern interface {
+	Pattern
+	Solid() bool
+}
+
+// IsSolid Check if a pattern is defined throughout space
+func IsSolid(pattern Pattern) bool {
+	solid, ok := pattern.(SolidPattern)
+	return ok && solid.Solid()
+}
+
 // TestPattern Basic pattern used for testing
 type TestPattern struct {
 	PatternBase
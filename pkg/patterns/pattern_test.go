@@ -30,3 +30,25 @@ func TestPatternTransformation(t *testing.T) {
 		}
 	}
 }
+
+func TestIsSolid(t *testing.T) {
+	white, black := patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)
+	tables := []struct {
+		pattern patterns.Pattern
+		solid   bool
+	}{
+		{white, false},
+		{patterns.MakeStripe(white, black), false},
+		{patterns.MakeChecker3D(white, black), true},
+		{patterns.MakeWood(white, black), true},
+		{patterns.MakeBlend(white, patterns.MakeMarble(white, black), 0.5), true},
+		{patterns.MakeBlend(white, black, 0.5), false},
+		{patterns.MakeLayers(patterns.ADD, white, patterns.MakeNoise(white, black, 2)), true},
+		{patterns.MakePerturbed(patterns.MakeStripe(white, black), 0.1), false},
+	}
+	for _, table := range tables {
+		if solid := patterns.IsSolid(table.pattern); solid != table.solid {
+			t.Errorf("Pattern %T, expected solid %v, got %v", table.pattern, table.solid, solid)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package patterns
+
+import (
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Perturbed Jitters the point passed to another pattern with noise
+type Perturbed struct {
+	PatternBase
+	pattern Pattern
+	scale   float64
+	noise   NoiseFunction
+	octaves int
+}
+
+// MakePerturbed Perturb a pattern by up to scale units using Perlin noise
+func MakePerturbed(p Pattern, scale float64) *Perturbed {
+	return &Perturbed{PatternBase: MakePatternBase(), pattern: p, scale: scale, noise: Perlin, octaves: 1}
+}
+
+// SetNoiseFunction Replace the noise function used for the perturbation
+func (p *Perturbed) SetNoiseFunction(noise NoiseFunction) {
+	p.noise = noise
+}
+
+// SetOctaves Set the number of noise octaves summed for the perturbation
+func (p *Perturbed) SetOctaves(octaves int) {
+	p.octaves = octaves
+}
+
+// ColorAt Return the color of the inner pattern at the jittered point
+func (p Perturbed) ColorAt(point primitives.PV) RGB {
+	patternPoint := p.PatternPoint(point)
+	// Offset the samples so each axis gets an independent jitter
+	jitter := primitives.MakeVector(
+		FBM(p.noise, patternPoint, p.octaves, 2, 0.5),
+		FBM(p.noise, patternPoint.Add(primitives.MakeVector(31.416, 47.853, 12.793)), p.octaves, 2, 0.5),
+		FBM(p.noise, patternPoint.Add(primitives.MakeVector(-19.871, 7.137, 65.231)), p.octaves, 2, 0.5))
+	return p.pattern.ColorAt(patternPoint.Add(jitter.Scalar(p.scale)))
+}
+
+// Solid Solid when the perturbed pattern is
+func (p Perturbed) Solid() bool {
+	return IsSolid(p.pattern)
+}
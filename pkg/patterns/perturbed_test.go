@@ -0,0 +1,43 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestPerturbedColorAt(t *testing.T) {
+	tables := []struct {
+		scale float64
+		point primitives.PV
+	}{
+		{0, primitives.MakePoint(0.5, 0.3, 0.7)},
+		{0.25, primitives.MakePoint(0.5, 0.3, 0.7)},
+		{1, primitives.MakePoint(-2.2, 4.1, 0.9)},
+	}
+	for _, table := range tables {
+		inner := &patterns.TestPattern{PatternBase: patterns.MakePatternBase()}
+		perturbed := patterns.MakePerturbed(inner, table.scale)
+		result := perturbed.ColorAt(table.point)
+		expected := patterns.MakeRGB(
+			table.point.X+(table.scale*patterns.Perlin(table.point)),
+			table.point.Y+(table.scale*patterns.Perlin(table.point.Add(primitives.MakeVector(31.416, 47.853, 12.793)))),
+			table.point.Z+(table.scale*patterns.Perlin(table.point.Add(primitives.MakeVector(-19.871, 7.137, 65.231)))))
+		if !result.Equals(*expected) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, expected, result)
+		}
+	}
+}
+
+func TestPerturbedStripe(t *testing.T) {
+	stripe := patterns.MakeStripe(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+	perturbed := patterns.MakePerturbed(stripe, 0)
+	for x := -2.0; x < 2; x += 0.25 {
+		point := primitives.MakePoint(x, 0.5, 0.5)
+		expected := stripe.ColorAt(point)
+		if result := perturbed.ColorAt(point); !result.Equals(expected) {
+			t.Errorf("Point: %v, Expected %v, got %v", point, expected, result)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package patterns
+
+import (
+	"sort"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// ColorStop Color at a position along a color ramp
+type ColorStop struct {
+	Position float64
+	Color    RGB
+}
+
+// ColorRamp Map the intensity of a scalar pattern onto a gradient of color stops
+type ColorRamp struct {
+	PatternBase
+	scalar Pattern
+	stops  []ColorStop
+}
+
+// MakeColorRamp Make a color ramp, values outside of the stops take the color of the nearest stop
+func MakeColorRamp(scalar Pattern, stops ...ColorStop) *ColorRamp {
+	sorted := make([]ColorStop, len(stops))
+	copy(sorted, stops)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position < sorted[j].Position })
+	return &ColorRamp{PatternBase: MakePatternBase(), scalar: scalar, stops: sorted}
+}
+
+// Lookup Return the color of the ramp at a value
+func (cr ColorRamp) Lookup(value float64) RGB {
+	if len(cr.stops) == 0 {
+		return *MakeRGB(value, value, value)
+	}
+	if value <= cr.stops[0].Position {
+		return cr.stops[0].Color
+	}
+	for index := 1; index < len(cr.stops); index++ {
+		upper := cr.stops[index]
+		if value <= upper.Position {
+			lower := cr.stops[index-1]
+			fraction := (value - lower.Position) / (upper.Position - lower.Position)
+			return lower.Color.Add(upper.Color.Subtract(lower.Color).Scale(fraction))
+		}
+	}
+	return cr.stops[len(cr.stops)-1].Color
+}
+
+// ColorAt Return color at specific point
+func (cr ColorRamp) ColorAt(point primitives.PV) RGB {
+	patternPoint := cr.PatternPoint(point)
+	return cr.Lookup(cr.scalar.ColorAt(patternPoint).Intensity())
+}
+
+// Solid Solid when the scalar pattern is
+func (cr ColorRamp) Solid() bool {
+	return IsSolid(cr.scalar)
+}
@@ -0,0 +1,53 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestColorRampColorAt(t *testing.T) {
+	stops := []patterns.ColorStop{
+		{Position: 0.5, Color: *patterns.MakeRGB(0, 1, 0)},
+		{Position: 0, Color: *patterns.MakeRGB(1, 0, 0)},
+		{Position: 1, Color: *patterns.MakeRGB(0, 0, 1)},
+	}
+	tables := []struct {
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{primitives.MakePoint(0, 0, 0), patterns.MakeRGB(1, 0, 0)},
+		{primitives.MakePoint(0.25, 0, 0), patterns.MakeRGB(0.5, 0.5, 0)},
+		{primitives.MakePoint(0.5, 0, 0), patterns.MakeRGB(0, 1, 0)},
+		{primitives.MakePoint(0.875, 0, 0), patterns.MakeRGB(0, 0.25, 0.75)},
+	}
+	for _, table := range tables {
+		ramp := patterns.MakeColorRamp(patterns.MakeGradient(patterns.MakeRGB(0, 0, 0), patterns.MakeRGB(1, 1, 1)), stops...)
+		result := ramp.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
+
+func TestColorRampLookup(t *testing.T) {
+	ramp := patterns.MakeColorRamp(patterns.MakeRGB(0, 0, 0),
+		patterns.ColorStop{Position: 0.2, Color: *patterns.MakeRGB(1, 1, 1)},
+		patterns.ColorStop{Position: 0.8, Color: *patterns.MakeRGB(0, 0, 0)})
+	tables := []struct {
+		value  float64
+		result *patterns.RGB
+	}{
+		{-1, patterns.MakeRGB(1, 1, 1)},
+		{0.2, patterns.MakeRGB(1, 1, 1)},
+		{0.5, patterns.MakeRGB(0.5, 0.5, 0.5)},
+		{2, patterns.MakeRGB(0, 0, 0)},
+	}
+	for _, table := range tables {
+		result := ramp.Lookup(table.value)
+		if !result.Equals(*table.result) {
+			t.Errorf("Value: %v, Expected %v, got %v", table.value, table.result, result)
+		}
+	}
+}
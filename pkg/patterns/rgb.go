@@ -51,6 +51,26 @@ func (r RGB) Scale(s float64) RGB {
 	return RGB{PatternBase{transform:primitives.MakeIdentityMatrix(4)}, r.red * s, r.green * s, r.blue * s}
 }
 
+// Red Get the red channel
+func (r RGB) Red() float64 {
+	return r.red
+}
+
+// Green Get the green channel
+func (r RGB) Green() float64 {
+	return r.green
+}
+
+// Blue Get the blue channel
+func (r RGB) Blue() float64 {
+	return r.blue
+}
+
+// Intensity Average of the three channels, used when a pattern acts as a scalar value
+func (r RGB) Intensity() float64 {
+	return (r.red + r.green + r.blue) / 3
+}
+
 // ToImageRGBA Convert to an RGBA image format
 func (r RGB) ToImageRGBA() color.RGBA {
 	return color.RGBA{byte(math.Min(1.0, math.Max(0, r.red)) * 255),
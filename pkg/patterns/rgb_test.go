@@ -1,8 +1,10 @@
 package patterns_test
 
 import(
+	"math"
 	"testing"
 	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
 )
 
 func TestLightEquals(t *testing.T) {
@@ -81,3 +83,27 @@ func TestRGBScale(t * testing.T) {
 		}
 	}
 }
+
+func TestRGBIntensity(t *testing.T) {
+	tables := []struct {
+		c *patterns.RGB
+		intensity float64
+	}{
+		{patterns.MakeRGB(0, 0, 0), 0},
+		{patterns.MakeRGB(1, 1, 1), 1},
+		{patterns.MakeRGB(0.9, 0.6, 0.3), 0.6},
+	}
+	for _, table := range tables {
+		intensity := table.c.Intensity()
+		if math.Abs(intensity - table.intensity) > primitives.EPSILON {
+			t.Errorf("Expected %v, got %v", table.intensity, intensity)
+		}
+	}
+}
+
+func TestRGBChannels(t *testing.T) {
+	c := patterns.MakeRGB(0.9, 0.6, 0.3)
+	if c.Red() != 0.9 || c.Green() != 0.6 || c.Blue() != 0.3 {
+		t.Errorf("Expected channels 0.9, 0.6, 0.3, got %v, %v, %v", c.Red(), c.Green(), c.Blue())
+	}
+}
@@ -0,0 +1,48 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Ring Concentric rings around the y-axis on the XZ plane
+type Ring struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+}
+
+// MakeRing Return a ring pattern alternating between two patterns
+func MakeRing(p1, p2 Pattern) *Ring {
+	return &Ring{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2}
+}
+
+// ColorAt Return color at specific point
+func (r Ring) ColorAt(point primitives.PV) RGB {
+	patternPoint := r.PatternPoint(point)
+	distance := math.Sqrt((patternPoint.X * patternPoint.X) + (patternPoint.Z * patternPoint.Z))
+	if int(math.Floor(distance))%2 == 0 {
+		return r.pattern1.ColorAt(patternPoint)
+	}
+	return r.pattern2.ColorAt(patternPoint)
+}
+
+// RadialGradient Gradient repeating outwards from the y-axis on the XZ plane
+type RadialGradient struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+}
+
+// MakeRadialGradient Return a radial gradient pattern between two patterns
+func MakeRadialGradient(p1, p2 Pattern) *RadialGradient {
+	return &RadialGradient{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2}
+}
+
+// ColorAt Return color at specific point
+func (rg RadialGradient) ColorAt(point primitives.PV) RGB {
+	patternPoint := rg.PatternPoint(point)
+	distance := math.Sqrt((patternPoint.X * patternPoint.X) + (patternPoint.Z * patternPoint.Z))
+	color1 := rg.pattern1.ColorAt(patternPoint)
+	color2 := rg.pattern2.ColorAt(patternPoint)
+	return color1.Add(color2.Subtract(color1).Scale(distance - math.Floor(distance)))
+}
@@ -0,0 +1,90 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestRingColorAt(t *testing.T) {
+	tables := []struct {
+		r         *patterns.Ring
+		transform primitives.Matrix
+		point     primitives.PV
+		result    *patterns.RGB
+	}{
+		{patterns.MakeRing(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(1, 1, 1)},
+
+		{patterns.MakeRing(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(1, 0, 0),
+			patterns.MakeRGB(0, 0, 0)},
+
+		{patterns.MakeRing(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 1),
+			patterns.MakeRGB(0, 0, 0)},
+
+		{patterns.MakeRing(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.708, 0, 0.708),
+			patterns.MakeRGB(0, 0, 0)},
+
+		{patterns.MakeRing(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 7, 2.5),
+			patterns.MakeRGB(1, 1, 1)},
+
+		{patterns.MakeRing(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.Scaling(0.5, 0.5, 0.5),
+			primitives.MakePoint(0.6, 0, 0),
+			patterns.MakeRGB(0, 0, 0)},
+	}
+	for _, table := range tables {
+		table.r.SetTransform(table.transform)
+		result := table.r.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
+
+func TestRadialGradientColorAt(t *testing.T) {
+	tables := []struct {
+		rg        *patterns.RadialGradient
+		transform primitives.Matrix
+		point     primitives.PV
+		result    *patterns.RGB
+	}{
+		{patterns.MakeRadialGradient(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0),
+			patterns.MakeRGB(1, 1, 1)},
+
+		{patterns.MakeRadialGradient(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.25, 3, 0),
+			patterns.MakeRGB(0.75, 0.75, 0.75)},
+
+		{patterns.MakeRadialGradient(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.3, 0, 0.4),
+			patterns.MakeRGB(0.5, 0.5, 0.5)},
+
+		{patterns.MakeRadialGradient(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0)),
+			primitives.Scaling(2, 2, 2),
+			primitives.MakePoint(0, 0, 1.5),
+			patterns.MakeRGB(0.25, 0.25, 0.25)},
+	}
+	for _, table := range tables {
+		table.rg.SetTransform(table.transform)
+		result := table.rg.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
@@ -1,25 +1,38 @@
 package patterns
 
 import (
+	"errors"
 	"math"
 	"github.com/factorion/graytracer/pkg/primitives"
 )
 
-// Stripe Basic stripe pattern based on x-axis value
+// Stripe Basic stripe pattern based on x-axis value, or the value along another direction
 type Stripe struct {
 	PatternBase
 	pattern1, pattern2 Pattern
+	direction primitives.PV
 }
 
 // MakeStripe Make a stripe pattern from two patterns
 func MakeStripe(p1, p2 Pattern) *Stripe {
-	return &Stripe{PatternBase:PatternBase{transform:primitives.MakeIdentityMatrix(4)}, pattern1:p1, pattern2:p2}
+	return &Stripe{PatternBase:PatternBase{transform:primitives.MakeIdentityMatrix(4)}, pattern1:p1, pattern2:p2,
+				   direction:primitives.MakeVector(1, 0, 0)}
+}
+
+// SetDirection Set the axis or direction the stripes alternate along, a zero vector has no direction and is an error
+func (s *Stripe) SetDirection(direction primitives.PV) error {
+	direction.W = 0
+	if direction.Magnitude() < primitives.EPSILON {
+		return errors.New("direction must not be a zero vector")
+	}
+	s.direction = direction.Normalize()
+	return nil
 }
 
 // ColorAt Calculate which stripe and return the color
 func (s Stripe) ColorAt(point primitives.PV) RGB {
 	patternPoint := s.PatternPoint(point)
-	if int(math.Floor(patternPoint.X)) % 2 == 0 {
+	if int(math.Floor(AlongDirection(patternPoint, s.direction))) % 2 == 0 {
 		return s.pattern1.ColorAt(patternPoint)
 	}
 	return s.pattern2.ColorAt(patternPoint)
@@ -66,3 +66,50 @@ func TestStripeColorAt(t *testing.T) {
 		}
 	}
 }
+
+func TestStripeDirection(t *testing.T) {
+	tables := []struct {
+		direction primitives.PV
+		point primitives.PV
+		result *patterns.RGB
+	}{
+		{primitives.MakeVector(0, 1, 0),
+		 primitives.MakePoint(5, 0.5, 0),
+		 patterns.MakeRGB(1, 1, 1)},
+
+		{primitives.MakeVector(0, 1, 0),
+		 primitives.MakePoint(0, 1.5, 0),
+		 patterns.MakeRGB(0, 0, 0)},
+
+		{primitives.MakeVector(0, 0, 2),
+		 primitives.MakePoint(0, 0, -0.5),
+		 patterns.MakeRGB(0, 0, 0)},
+
+		{primitives.MakeVector(1, 1, 0),
+		 primitives.MakePoint(0.5, 0.5, 0),
+		 patterns.MakeRGB(1, 1, 1)},
+
+		{primitives.MakeVector(1, 1, 0),
+		 primitives.MakePoint(1, 1, 0),
+		 patterns.MakeRGB(0, 0, 0)},
+	}
+	for _, table := range tables {
+		s := patterns.MakeStripe(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+		if err := s.SetDirection(table.direction); err != nil {
+			t.Fatal(err)
+		}
+		result := s.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+	// A zero vector is rejected, leaving the direction along the x-axis
+	s := patterns.MakeStripe(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+	if err := s.SetDirection(primitives.MakeVector(0, 0, 0)); err == nil {
+		t.Error("Expected an error for a zero direction")
+	}
+	result, expected := s.ColorAt(primitives.MakePoint(0.25, 5, 5)), s.ColorAt(primitives.MakePoint(0.25, 0, 0))
+	if !result.Equals(expected) {
+		t.Errorf("Expected the direction to stay along the x-axis, got %v and %v", result, expected)
+	}
+}
@@ -0,0 +1,49 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Wood Concentric rings around the y-axis with noisy grain
+type Wood struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+	rings, grain       float64
+	octaves            int
+}
+
+// MakeWood Make a wood pattern with rings shading from the first pattern to the second
+func MakeWood(p1, p2 Pattern) *Wood {
+	return &Wood{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2, rings: 4, grain: 0.1, octaves: 3}
+}
+
+// SetRings Set the number of rings per unit of distance from the y-axis
+func (w *Wood) SetRings(rings float64) {
+	w.rings = rings
+}
+
+// SetGrain Set the amount and number of octaves of noise distorting the rings
+func (w *Wood) SetGrain(grain float64, octaves int) {
+	w.grain = grain
+	w.octaves = octaves
+}
+
+// ColorAt Return color at specific point
+func (w Wood) ColorAt(point primitives.PV) RGB {
+	patternPoint := w.PatternPoint(point)
+	radius := math.Sqrt((patternPoint.X * patternPoint.X) + (patternPoint.Z * patternPoint.Z))
+	// Stretch the noise along the y-axis so the grain runs with the trunk
+	grainPoint := primitives.MakePoint(patternPoint.X*4, patternPoint.Y*0.5, patternPoint.Z*4)
+	ring := (radius + (w.grain * FBM(Perlin, grainPoint, w.octaves, 2, 0.5))) * w.rings
+	blend := ring - math.Floor(ring)
+	color1 := w.pattern1.ColorAt(patternPoint)
+	color2 := w.pattern2.ColorAt(patternPoint)
+	return color1.Add(color2.Subtract(color1).Scale(blend))
+}
+
+// Solid Sampled at points in object-space since it varies along all three axes
+func (w Wood) Solid() bool {
+	return true
+}
@@ -0,0 +1,31 @@
+package patterns_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestWoodColorAt(t *testing.T) {
+	tables := []struct {
+		grain  float64
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{0, primitives.MakePoint(0, 0, 0), patterns.MakeRGB(1, 1, 1)},
+		{0, primitives.MakePoint(0.125, 0, 0), patterns.MakeRGB(0.5, 0.5, 0.5)},
+		{0, primitives.MakePoint(0, 5, 0.125), patterns.MakeRGB(0.5, 0.5, 0.5)},
+		{0, primitives.MakePoint(0.25, 0, 0), patterns.MakeRGB(1, 1, 1)},
+		{0.1, primitives.MakePoint(0.5, 0.3, 0.7),
+			patterns.MakeRGB(0.45266119851494935, 0.45266119851494935, 0.45266119851494935)},
+	}
+	for _, table := range tables {
+		wood := patterns.MakeWood(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0))
+		wood.SetGrain(table.grain, 3)
+		result := wood.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
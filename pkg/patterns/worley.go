@@ -0,0 +1,114 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// DistanceMetric Metric used to measure distance to the Worley feature points
+type DistanceMetric int
+
+const (
+	EUCLIDEAN DistanceMetric = iota
+	MANHATTAN
+	CHEBYSHEV
+)
+
+// CellFeature Which feature point distances produce the Worley value
+type CellFeature int
+
+const (
+	F1 CellFeature = iota
+	F2
+	F2_MINUS_F1
+)
+
+// Worley Cellular pattern based on the distance to randomly placed feature points
+type Worley struct {
+	PatternBase
+	pattern1, pattern2 Pattern
+	metric             DistanceMetric
+	feature            CellFeature
+}
+
+// MakeWorley Make a cellular pattern shading from the first pattern at the feature points to the second
+func MakeWorley(p1, p2 Pattern, metric DistanceMetric, feature CellFeature) *Worley {
+	return &Worley{PatternBase: MakePatternBase(), pattern1: p1, pattern2: p2, metric: metric, feature: feature}
+}
+
+// hashCell Hash integer cell coordinates and a seed into a float in [0, 1)
+func hashCell(x, y, z, seed int64) float64 {
+	h := uint64(x)*0x9E3779B97F4A7C15 ^ uint64(y)*0xC2B2AE3D27D4EB4F ^ uint64(z)*0x165667B19E3779F9 ^
+		uint64(seed)*0x27D4EB2F165667C5
+	h ^= h >> 33
+	h *= 0xFF51AFD7ED558CCD
+	h ^= h >> 33
+	h *= 0xC4CEB9FE1A85EC53
+	h ^= h >> 33
+	return float64(h>>11) / float64(1<<53)
+}
+
+// FeaturePoint Return the feature point inside of an integer cell
+func FeaturePoint(x, y, z int64) primitives.PV {
+	return primitives.MakePoint(float64(x)+hashCell(x, y, z, 1), float64(y)+hashCell(x, y, z, 2),
+		float64(z)+hashCell(x, y, z, 3))
+}
+
+// distance Measure the distance between two points with a metric
+func (metric DistanceMetric) distance(p1, p2 primitives.PV) float64 {
+	dx := math.Abs(p1.X - p2.X)
+	dy := math.Abs(p1.Y - p2.Y)
+	dz := math.Abs(p1.Z - p2.Z)
+	switch metric {
+	case MANHATTAN:
+		return dx + dy + dz
+	case CHEBYSHEV:
+		return math.Max(dx, math.Max(dy, dz))
+	}
+	return math.Sqrt((dx * dx) + (dy * dy) + (dz * dz))
+}
+
+// CellDistances Return the distances to the closest and second closest feature points
+func CellDistances(point primitives.PV, metric DistanceMetric) (float64, float64) {
+	cx := int64(math.Floor(point.X))
+	cy := int64(math.Floor(point.Y))
+	cz := int64(math.Floor(point.Z))
+	f1, f2 := math.Inf(1), math.Inf(1)
+	// Feature points are inside their cell so only the neighboring cells need checking
+	for x := cx - 1; x <= cx+1; x++ {
+		for y := cy - 1; y <= cy+1; y++ {
+			for z := cz - 1; z <= cz+1; z++ {
+				d := metric.distance(point, FeaturePoint(x, y, z))
+				if d < f1 {
+					f1, f2 = d, f1
+				} else if d < f2 {
+					f2 = d
+				}
+			}
+		}
+	}
+	return f1, f2
+}
+
+// ColorAt Return color at specific point
+func (w Worley) ColorAt(point primitives.PV) RGB {
+	patternPoint := w.PatternPoint(point)
+	f1, f2 := CellDistances(patternPoint, w.metric)
+	value := f1
+	switch w.feature {
+	case F2:
+		value = f2
+	case F2_MINUS_F1:
+		value = f2 - f1
+	}
+	blend := math.Min(1, value)
+	color1 := w.pattern1.ColorAt(patternPoint)
+	color2 := w.pattern2.ColorAt(patternPoint)
+	return color1.Add(color2.Subtract(color1).Scale(blend))
+}
+
+// Solid Sampled at points in object-space since it varies along all three axes
+func (w Worley) Solid() bool {
+	return true
+}
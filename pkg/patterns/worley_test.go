@@ -0,0 +1,78 @@
+package patterns_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+func TestFeaturePoint(t *testing.T) {
+	tables := []struct {
+		x, y, z int64
+	}{
+		{0, 0, 0},
+		{-1, 2, 3},
+		{100, -50, 7},
+	}
+	for _, table := range tables {
+		point := patterns.FeaturePoint(table.x, table.y, table.z)
+		if math.Floor(point.X) != float64(table.x) || math.Floor(point.Y) != float64(table.y) ||
+			math.Floor(point.Z) != float64(table.z) {
+			t.Errorf("Feature point %v outside of cell %v, %v, %v", point, table.x, table.y, table.z)
+		}
+		if !point.Equals(patterns.FeaturePoint(table.x, table.y, table.z)) {
+			t.Errorf("Feature point for cell %v, %v, %v is not deterministic", table.x, table.y, table.z)
+		}
+	}
+}
+
+func TestCellDistances(t *testing.T) {
+	tables := []struct {
+		point  primitives.PV
+		metric patterns.DistanceMetric
+		f1, f2 float64
+	}{
+		{primitives.MakePoint(0.5, 0.3, 0.7), patterns.EUCLIDEAN, 0.9181726292030714, 0.9457109008339919},
+		{primitives.MakePoint(0.5, 0.3, 0.7), patterns.MANHATTAN, 1.4074521136504323, 1.4508799971750337},
+		{primitives.MakePoint(0.5, 0.3, 0.7), patterns.CHEBYSHEV, 0.6944280500106697, 0.7213912744485773},
+		{patterns.FeaturePoint(0, 0, 0), patterns.EUCLIDEAN, 0, 1.0102959284644326},
+	}
+	for _, table := range tables {
+		f1, f2 := patterns.CellDistances(table.point, table.metric)
+		if math.Abs(f1-table.f1) > primitives.EPSILON || math.Abs(f2-table.f2) > primitives.EPSILON {
+			t.Errorf("Point: %v, Expected %v and %v, got %v and %v", table.point, table.f1, table.f2, f1, f2)
+		}
+	}
+}
+
+func TestWorleyColorAt(t *testing.T) {
+	tables := []struct {
+		w      *patterns.Worley
+		point  primitives.PV
+		result *patterns.RGB
+	}{
+		{patterns.MakeWorley(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), patterns.EUCLIDEAN, patterns.F1),
+			patterns.FeaturePoint(0, 0, 0),
+			patterns.MakeRGB(1, 1, 1)},
+
+		{patterns.MakeWorley(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), patterns.EUCLIDEAN, patterns.F1),
+			primitives.MakePoint(0.5, 0.3, 0.7),
+			patterns.MakeRGB(0.08182737079692863, 0.08182737079692863, 0.08182737079692863)},
+
+		{patterns.MakeWorley(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), patterns.EUCLIDEAN, patterns.F2),
+			primitives.MakePoint(0.5, 0.3, 0.7),
+			patterns.MakeRGB(0.05428909916600812, 0.05428909916600812, 0.05428909916600812)},
+
+		{patterns.MakeWorley(patterns.MakeRGB(1, 1, 1), patterns.MakeRGB(0, 0, 0), patterns.EUCLIDEAN, patterns.F2_MINUS_F1),
+			primitives.MakePoint(0.5, 0.3, 0.7),
+			patterns.MakeRGB(0.9724617283690795, 0.9724617283690795, 0.9724617283690795)},
+	}
+	for _, table := range tables {
+		result := table.w.ColorAt(table.point)
+		if !result.Equals(*table.result) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.result, result)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Levels of subdivision below the whole patch kept as bounding boxes for intersecting
+const bezierDepth = 4
+
+// Newton iterations used to converge on the surface from the middle of a sub-patch
+const bezierIterations = 12
+
+// Most times the parameters of a patch are halved while tessellating it
+const bezierMaxSplits = 10
+
+// Slack allowed around the parameters of a sub-patch when accepting a root found from it
+const bezierSlack = 1e-6
+
+// BezierPatch Bicubic Bézier surface over 16 control points, stored row by row with U running along a row
+// and V from row to row
+type BezierPatch struct {
+	ShapeBase
+	points [16]primitives.PV
+	// Bounds of the control points of the sub-patches as a complete quadtree, children of i are 4i+1 to 4i+4
+	nodes []Bounds
+}
+
+// MakeBezierPatch Make a patch from its control points with an identity matrix for transform
+func MakeBezierPatch(points [16]primitives.PV) *BezierPatch {
+	bp := &BezierPatch{ShapeBase: MakeShapeBase(), points: points}
+	size := 0
+	for level, count := 0, 1; level <= bezierDepth; level, count = level+1, count*4 {
+		size += count
+	}
+	bp.nodes = make([]Bounds, size)
+	bp.buildNode(0, 0, 0, 1, 0)
+	return bp
+}
+
+// Points Get the control points of the patch
+func (bp *BezierPatch) Points() [16]primitives.PV {
+	return bp.points
+}
+
+// buildNode Fill in the bounds of a sub-patch and its children
+func (bp *BezierPatch) buildNode(node int, u0, v0, size float64, level int) {
+	var rows [16]primitives.PV
+	for row := 0; row < 4; row++ {
+		curve := subCurve([4]primitives.PV{bp.points[row*4], bp.points[(row*4)+1], bp.points[(row*4)+2],
+			bp.points[(row*4)+3]}, u0, u0+size)
+		copy(rows[row*4:], curve[:])
+	}
+	bounds := Bounds{Min: primitives.MakePoint(math.Inf(1), math.Inf(1), math.Inf(1)),
+		Max: primitives.MakePoint(math.Inf(-1), math.Inf(-1), math.Inf(-1))}
+	for column := 0; column < 4; column++ {
+		curve := subCurve([4]primitives.PV{rows[column], rows[4+column], rows[8+column], rows[12+column]},
+			v0, v0+size)
+		for _, point := range curve {
+			bounds.AddBounds(&Bounds{Min: point, Max: point})
+		}
+	}
+	// Pad flat sub-patches so rays along them still enter the box
+	bounds.Min = bounds.Min.Subtract(primitives.MakeVector(bezierSlack, bezierSlack, bezierSlack))
+	bounds.Max = bounds.Max.Add(primitives.MakeVector(bezierSlack, bezierSlack, bezierSlack))
+	bp.nodes[node] = bounds
+	if level == bezierDepth {
+		return
+	}
+	half := size / 2
+	for child := 0; child < 4; child++ {
+		bp.buildNode((node*4)+child+1, u0+(float64(child%2)*half), v0+(float64(child/2)*half), half, level+1)
+	}
+}
+
+// subCurve Control points of the part of a cubic curve between the parameters t0 and t1
+func subCurve(points [4]primitives.PV, t0, t1 float64) [4]primitives.PV {
+	// Keep the part before t1, then the part of that after t0
+	left, _ := splitCurve(points, t1)
+	if t1 <= 0 {
+		return left
+	}
+	_, right := splitCurve(left, t0/t1)
+	return right
+}
+
+// splitCurve Split a cubic curve at the parameter t with de Casteljau's algorithm
+func splitCurve(p [4]primitives.PV, t float64) ([4]primitives.PV, [4]primitives.PV) {
+	lerp := func(a, b primitives.PV) primitives.PV {
+		return a.Add(b.Subtract(a).Scalar(t))
+	}
+	p01, p12, p23 := lerp(p[0], p[1]), lerp(p[1], p[2]), lerp(p[2], p[3])
+	p012, p123 := lerp(p01, p12), lerp(p12, p23)
+	middle := lerp(p012, p123)
+	return [4]primitives.PV{p[0], p01, p012, middle}, [4]primitives.PV{middle, p123, p23, p[3]}
+}
+
+// bernstein Cubic Bernstein polynomials and their derivatives at t
+func bernstein(t float64) ([4]float64, [4]float64) {
+	s := 1 - t
+	return [4]float64{s * s * s, 3 * t * s * s, 3 * t * t * s, t * t * t},
+		[4]float64{-3 * s * s, (3 * s * s) - (6 * t * s), (6 * t * s) - (3 * t * t), 3 * t * t}
+}
+
+// Evaluate Get the object-space point on the patch and its partial derivatives along U and V
+func (bp *BezierPatch) Evaluate(u, v float64) (primitives.PV, primitives.PV, primitives.PV) {
+	bu, du := bernstein(u)
+	bv, dv := bernstein(v)
+	point := primitives.MakePoint(0, 0, 0)
+	tangentU := primitives.MakeVector(0, 0, 0)
+	tangentV := primitives.MakeVector(0, 0, 0)
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			control := bp.points[(row*4)+column]
+			control.W = 0
+			point = point.Add(control.Scalar(bu[column] * bv[row]))
+			tangentU = tangentU.Add(control.Scalar(du[column] * bv[row]))
+			tangentV = tangentV.Add(control.Scalar(bu[column] * dv[row]))
+		}
+	}
+	return point, tangentU, tangentV
+}
+
+// GetBounds Return an axis aligned bounding box for the patch
+func (bp *BezierPatch) GetBounds() *Bounds {
+	return bp.nodes[0].Transform(bp.transform)
+}
+
+// Intersect Check if a ray intersects, descending into the sub-patches the ray passes through and
+// converging on the surface with Newton iteration
+func (bp *BezierPatch) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	// convert ray to object space
+	oray := r.Transform(bp.Inverse())
+	// Describe the ray as the line where two planes meet
+	d := oray.Direction
+	var normal1 primitives.PV
+	if math.Abs(d.X) > math.Abs(d.Y) && math.Abs(d.X) > math.Abs(d.Z) {
+		normal1 = primitives.MakeVector(d.Y, -d.X, 0)
+	} else {
+		normal1 = primitives.MakeVector(0, d.Z, -d.Y)
+	}
+	normal2 := normal1.CrossProduct(d)
+	origin := oray.Origin
+	origin.W = 0
+	offset1, offset2 := -normal1.DotProduct(origin), -normal2.DotProduct(origin)
+	var visit func(node int, u0, v0, size float64, level int)
+	visit = func(node int, u0, v0, size float64, level int) {
+		if _, _, ok := bp.nodes[node].Clip(oray); !ok {
+			return
+		}
+		if level < bezierDepth {
+			half := size / 2
+			for child := 0; child < 4; child++ {
+				visit((node*4)+child+1, u0+(float64(child%2)*half), v0+(float64(child/2)*half), half, level+1)
+			}
+			return
+		}
+		u, v := u0+(size/2), v0+(size/2)
+		for iteration := 0; iteration < bezierIterations; iteration++ {
+			point, tangentU, tangentV := bp.Evaluate(u, v)
+			f1, f2 := normal1.DotProduct(point)+offset1, normal2.DotProduct(point)+offset2
+			j11, j12 := normal1.DotProduct(tangentU), normal1.DotProduct(tangentV)
+			j21, j22 := normal2.DotProduct(tangentU), normal2.DotProduct(tangentV)
+			det := (j11 * j22) - (j12 * j21)
+			if det == 0 {
+				return
+			}
+			stepU, stepV := ((j22*f1)-(j12*f2))/det, ((j11*f2)-(j21*f1))/det
+			u, v = u-stepU, v-stepV
+			if math.Abs(stepU)+math.Abs(stepV) < primitives.EPSILON*primitives.EPSILON {
+				break
+			}
+		}
+		if math.IsNaN(u) || math.IsNaN(v) ||
+			u < u0-bezierSlack || u > u0+size+bezierSlack || v < v0-bezierSlack || v > v0+size+bezierSlack {
+			return
+		}
+		point, _, _ := bp.Evaluate(u, v)
+		if math.Abs(normal1.DotProduct(point)+offset1) > primitives.EPSILON*normal1.Magnitude() ||
+			math.Abs(normal2.DotProduct(point)+offset2) > primitives.EPSILON*normal2.Magnitude() {
+			return
+		}
+		distance := point.Subtract(origin).DotProduct(d) / d.DotProduct(d)
+		// Roots on the edge between sub-patches are found from both sides
+		for _, hit := range hits {
+			if math.Abs(hit.Distance-distance) < primitives.EPSILON {
+				return
+			}
+		}
+		hits = append(hits, Intersection{Distance: distance, Obj: bp,
+			U: math.Min(math.Max(u, 0), 1), V: math.Min(math.Max(v, 0), 1)})
+	}
+	visit(0, 0, 0, 1, 0)
+	return hits
+}
+
+// surfaceNormal Object-space normal at the parameters, stepping towards the middle where an edge collapses to a point
+func (bp *BezierPatch) surfaceNormal(u, v float64) primitives.PV {
+	for step := 0; step < 8; step++ {
+		_, tangentU, tangentV := bp.Evaluate(u, v)
+		normal := tangentU.CrossProduct(tangentV)
+		if normal.Magnitude() > primitives.EPSILON {
+			return normal.Normalize()
+		}
+		u += (0.5 - u) * 1e-3
+		v += (0.5 - v) * 1e-3
+	}
+	return primitives.MakeVector(0, 1, 0)
+}
+
+// Normal Calculate the normal from the partial derivatives at the U and V of the intersection
+func (bp *BezierPatch) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	worldNormal := bp.ObjectToWorldPV(bp.surfaceNormal(u, v))
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the patch parameters of the point on the surface closest to a point
+func (bp *BezierPatch) UVMapping(point primitives.PV) primitives.PV {
+	objectPoint := bp.WorldToObjectPV(point)
+	objectPoint.W = 0
+	// Start from the closest of a coarse grid of samples, then refine with Gauss-Newton steps
+	bestU, bestV, best := 0.0, 0.0, math.Inf(1)
+	for row := 0; row <= 8; row++ {
+		for column := 0; column <= 8; column++ {
+			u, v := float64(column)/8, float64(row)/8
+			sample, _, _ := bp.Evaluate(u, v)
+			difference := objectPoint.Subtract(sample)
+			if distance := difference.DotProduct(difference); distance < best {
+				bestU, bestV, best = u, v, distance
+			}
+		}
+	}
+	u, v := bestU, bestV
+	for iteration := 0; iteration < bezierIterations; iteration++ {
+		sample, tangentU, tangentV := bp.Evaluate(u, v)
+		difference := objectPoint.Subtract(sample)
+		a, b, c := tangentU.DotProduct(tangentU), tangentU.DotProduct(tangentV), tangentV.DotProduct(tangentV)
+		det := (a * c) - (b * b)
+		if det < primitives.EPSILON {
+			break
+		}
+		ru, rv := tangentU.DotProduct(difference), tangentV.DotProduct(difference)
+		u = math.Min(math.Max(u+(((c*ru)-(b*rv))/det), 0), 1)
+		v = math.Min(math.Max(v+(((a*rv)-(b*ru))/det), 0), 1)
+	}
+	return primitives.MakePoint(u, v, 0)
+}
+
+// Tessellate Approximate the patch with a mesh of triangles within about the tolerance of the surface, halving the
+// parameters along U and V separately until each part is flat enough so the triangles are denser where the patch
+// curves. The splits are shared across the whole patch so neighbouring triangles meet without cracks, the mesh
+// uses the transform and material of the patch
+func (bp *BezierPatch) Tessellate(tolerance float64) *Mesh {
+	var rows, columns [4][4]primitives.PV
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			rows[row][column] = bp.points[(row*4)+column]
+			columns[column][row] = bp.points[(row*4)+column]
+		}
+	}
+	// Each direction takes half of the tolerance
+	us := flatSplits(rows, tolerance/2)
+	vs := flatSplits(columns, tolerance/2)
+	segmentsU, segmentsV := len(us)-1, len(vs)-1
+	buffer := &VertexBuffer{}
+	for row := 0; row <= segmentsV; row++ {
+		for column := 0; column <= segmentsU; column++ {
+			u, v := us[column], vs[row]
+			point, _, _ := bp.Evaluate(u, v)
+			point.W = 1
+			buffer.Vertices = append(buffer.Vertices, point)
+			buffer.Normals = append(buffer.Normals, bp.surfaceNormal(u, v))
+			buffer.UVs = append(buffer.UVs, primitives.MakePoint(u, v, 0))
+		}
+	}
+	faces := []MeshFace{}
+	stride := int32(segmentsU + 1)
+	for row := int32(0); row < int32(segmentsV); row++ {
+		for column := int32(0); column < int32(segmentsU); column++ {
+			corner := (row * stride) + column
+			first := [3]int32{corner, corner + 1, corner + stride + 1}
+			second := [3]int32{corner, corner + stride + 1, corner + stride}
+			faces = append(faces, MeshFace{Vertices: first, Normals: first, UVs: first},
+				MeshFace{Vertices: second, Normals: second, UVs: second})
+		}
+	}
+	mesh := MakeMesh(buffer, faces)
+	mesh.SetTransform(bp.transform)
+	mesh.SetMaterial(bp.material)
+	return mesh
+}
+
+// flatSplits Parameters splitting the curves into parts no further than the tolerance from straight lines
+// between their ends, halving a part while any of the curves is not flat enough over it
+func flatSplits(curves [4][4]primitives.PV, tolerance float64) []float64 {
+	splits := []float64{0}
+	var split func(t0, t1 float64, level int)
+	split = func(t0, t1 float64, level int) {
+		if level < bezierMaxSplits && !(curveFlatness(curves, t0, t1) <= tolerance) {
+			middle := (t0 + t1) / 2
+			split(t0, middle, level+1)
+			split(middle, t1, level+1)
+			return
+		}
+		splits = append(splits, t1)
+	}
+	split(0, 1, 0)
+	return splits
+}
+
+// curveFlatness Largest distance of the inner control points of the curves between t0 and t1 from where a straight
+// line between their ends would put them, which bounds how far the curves stray from those lines
+func curveFlatness(curves [4][4]primitives.PV, t0, t1 float64) float64 {
+	flatness := 0.0
+	for _, curve := range curves {
+		part := subCurve(curve, t0, t1)
+		chord := part[3].Subtract(part[0])
+		flatness = math.Max(flatness, part[1].Subtract(part[0].Add(chord.Scalar(1.0/3))).Magnitude())
+		flatness = math.Max(flatness, part[2].Subtract(part[0].Add(chord.Scalar(2.0/3))).Magnitude())
+	}
+	return flatness
+}
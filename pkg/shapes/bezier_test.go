@@ -0,0 +1,227 @@
+package shapes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// flatPatch Patch covering the unit square in the XY plane so that U and V match X and Y
+func flatPatch() *shapes.BezierPatch {
+	var points [16]primitives.PV
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			points[(row*4)+column] = primitives.MakePoint(float64(column)/3, float64(row)/3, 0)
+		}
+	}
+	return shapes.MakeBezierPatch(points)
+}
+
+// bumpyPatch Patch over the unit square in the XZ plane with the inner control points raised and lowered
+func bumpyPatch() *shapes.BezierPatch {
+	heights := [16]float64{0, 0.2, -0.1, 0, 0.3, 1, 0.8, -0.2, 0, 1.2, -0.6, 0.1, 0, 0.1, 0.2, 0}
+	var points [16]primitives.PV
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			points[(row*4)+column] = primitives.MakePoint(float64(column)/3, heights[(row*4)+column], float64(row)/3)
+		}
+	}
+	return shapes.MakeBezierPatch(points)
+}
+
+func TestBezierPatchGetBounds(t *testing.T) {
+	tables := []struct {
+		patch     *shapes.BezierPatch
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{flatPatch(), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(1, 1, 0)},
+
+		{flatPatch(), primitives.Translation(0, 0, 2),
+			primitives.MakePoint(0, 0, 2), primitives.MakePoint(1, 1, 2)},
+
+		{bumpyPatch(), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, -0.6, 0), primitives.MakePoint(1, 1.2, 1)},
+	}
+	for _, table := range tables {
+		table.patch.SetTransform(table.transform)
+		bounds := table.patch.GetBounds()
+		// The bounds are padded slightly so rays along flat patches still enter them
+		if math.Abs(bounds.Min.X-table.min.X) > 1e-5 || math.Abs(bounds.Min.Y-table.min.Y) > 1e-5 ||
+			math.Abs(bounds.Min.Z-table.min.Z) > 1e-5 {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if math.Abs(bounds.Max.X-table.max.X) > 1e-5 || math.Abs(bounds.Max.Y-table.max.Y) > 1e-5 ||
+			math.Abs(bounds.Max.Z-table.max.Z) > 1e-5 {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+}
+
+func TestBezierPatchIntersection(t *testing.T) {
+	tables := []struct {
+		patch     *shapes.BezierPatch
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+		u, v      float64
+	}{
+		{flatPatch(),
+			primitives.Ray{Origin: primitives.MakePoint(0.25, 0.75, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{5}, 0.25, 0.75},
+
+		{flatPatch(),
+			primitives.Ray{Origin: primitives.MakePoint(0.5, 0.5, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{5}, 0.5, 0.5},
+
+		{flatPatch(),
+			primitives.Ray{Origin: primitives.MakePoint(-2, 0.1, -2), Direction: primitives.MakeVector(1, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{2}, 0, 0.1},
+
+		{flatPatch(),
+			primitives.Ray{Origin: primitives.MakePoint(1.5, 0.5, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}, 0, 0},
+
+		{flatPatch(),
+			primitives.Ray{Origin: primitives.MakePoint(1.5, 0.5, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.Scaling(2, 2, 1), []float64{5}, 0.75, 0.25},
+	}
+	for _, table := range tables {
+		table.patch.SetTransform(table.transform)
+		hits := table.patch.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+			continue
+		}
+		if len(hits) > 0 && (math.Abs(hits[0].U-table.u) > 1e-6 || math.Abs(hits[0].V-table.v) > 1e-6) {
+			t.Errorf("Expected hit at %v, %v, got %v, %v", table.u, table.v, hits[0].U, hits[0].V)
+		}
+	}
+}
+
+func TestBezierPatchCurvedIntersection(t *testing.T) {
+	patch := bumpyPatch()
+	// Rays aimed at points on the surface must hit at those points
+	for u := 0.05; u < 1; u += 0.15 {
+		for v := 0.05; v < 1; v += 0.15 {
+			point, _, _ := patch.Evaluate(u, v)
+			for _, direction := range []primitives.PV{primitives.MakeVector(0, -1, 0),
+				primitives.MakeVector(0.3, -1, 0.2), primitives.MakeVector(-0.5, -0.4, 0.1)} {
+				ray := primitives.Ray{Origin: point.Subtract(direction.Scalar(5)), Direction: direction}
+				found := false
+				for _, hit := range patch.Intersect(ray) {
+					found = found || (math.Abs(hit.Distance-5) < 1e-6 &&
+						math.Abs(hit.U-u) < 1e-6 && math.Abs(hit.V-v) < 1e-6)
+				}
+				if !found {
+					t.Errorf("Ray %v, expected a hit at %v, %v", ray, u, v)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkBezierPatchIntersection(b *testing.B) {
+	patch := bumpyPatch()
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.4, 5, 0.6), Direction: primitives.MakeVector(0.1, -1, 0)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		patch.Intersect(ray)
+	}
+}
+
+func TestBezierPatchNormal(t *testing.T) {
+	// Patch with its first row collapsed to a point, like the top of the teapot lid
+	var cone [16]primitives.PV
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			cone[(row*4)+column] = primitives.MakePoint(float64(column*row)/9, -float64(row)/3, float64(row)/3)
+		}
+	}
+	tables := []struct {
+		patch     *shapes.BezierPatch
+		transform primitives.Matrix
+		u, v      float64
+		normal    primitives.PV
+	}{
+		{flatPatch(), primitives.MakeIdentityMatrix(4), 0.3, 0.6, primitives.MakeVector(0, 0, 1)},
+		{flatPatch(), primitives.RotationY(math.Pi / 2), 0.3, 0.6, primitives.MakeVector(1, 0, 0)},
+		{shapes.MakeBezierPatch(cone), primitives.MakeIdentityMatrix(4), 0.5, 0,
+			primitives.MakeVector(0, -1/math.Sqrt(2), -1/math.Sqrt(2))},
+	}
+	for _, table := range tables {
+		table.patch.SetTransform(table.transform)
+		normal := table.patch.Normal(primitives.MakePoint(0, 0, 0), table.u, table.v)
+		if math.Abs(normal.X-table.normal.X) > 1e-5 || math.Abs(normal.Y-table.normal.Y) > 1e-5 ||
+			math.Abs(normal.Z-table.normal.Z) > 1e-5 {
+			t.Errorf("UV %v, %v, Expected %v, got %v", table.u, table.v, table.normal, normal)
+		}
+	}
+}
+
+func TestBezierPatchUVMapping(t *testing.T) {
+	patch := bumpyPatch()
+	patch.SetTransform(primitives.Translation(3, 0, 0))
+	for _, uv := range [][2]float64{{0, 0}, {0.5, 0.5}, {0.2, 0.9}, {0.77, 0.13}, {1, 0.4}} {
+		point, _, _ := patch.Evaluate(uv[0], uv[1])
+		result := patch.UVMapping(point.Add(primitives.MakeVector(3, 0, 0)))
+		if math.Abs(result.X-uv[0]) > 1e-6 || math.Abs(result.Y-uv[1]) > 1e-6 {
+			t.Errorf("Expected %v, got %v", uv, result)
+		}
+	}
+}
+
+func TestBezierPatchTessellate(t *testing.T) {
+	if mesh := flatPatch().Tessellate(0.01); mesh.Len() != 2 {
+		t.Errorf("Expected a flat patch to need 2 triangles, got %v", mesh.Len())
+	}
+	patch := bumpyPatch()
+	patch.SetTransform(primitives.Translation(0, 0, 1))
+	tolerance := 0.005
+	mesh := patch.Tessellate(tolerance)
+	if !mesh.Transform().Equals(patch.Transform()) {
+		t.Errorf("Expected the mesh to use the transform of the patch")
+	}
+	// Sample away from the splits, which fall on fractions with powers of two below them, so no ray runs
+	// exactly along an edge shared by two triangles
+	for z := 1.05; z < 2; z += 0.1 {
+		for x := 0.06; x < 1; x += 0.1 {
+			ray := primitives.Ray{Origin: primitives.MakePoint(x, 5, z), Direction: primitives.MakeVector(0, -1, 0)}
+			expected, ok := patch.Intersect(ray).Hit()
+			if !ok {
+				t.Fatalf("Ray %v, expected to hit the patch", ray)
+			}
+			hit, ok := mesh.Intersect(ray).Hit()
+			if !ok || math.Abs(hit.Distance-expected.Distance) > tolerance {
+				t.Errorf("Ray %v, expected a hit within %v of %v, got %v", ray, tolerance, expected, hit)
+			}
+		}
+	}
+}
+
+func TestBezierPatchTessellateAdaptive(t *testing.T) {
+	// Flat along V and bending up only towards the end of U
+	heights := [4]float64{0, 0, 0, 1}
+	var points [16]primitives.PV
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			points[(row*4)+column] = primitives.MakePoint(float64(column)/3, heights[column], float64(row)/3)
+		}
+	}
+	mesh := shapes.MakeBezierPatch(points).Tessellate(0.01)
+	us := []float64{}
+	for _, uv := range mesh.Buffer().UVs {
+		if uv.Y == 0 {
+			us = append(us, uv.X)
+		}
+	}
+	if len(us) < 3 || mesh.Len() != (len(us)-1)*2 {
+		t.Fatalf("Expected one row of triangles across several splits of U, got %v triangles for %v", mesh.Len(), us)
+	}
+	if first, last := us[1]-us[0], us[len(us)-1]-us[len(us)-2]; first <= last {
+		t.Errorf("Expected the splits to be closer where the patch bends, got %v", us)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"sort"
 	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
 )
 
 // MinMax Sort an array of floats and return the minimum and maximum values
@@ -87,3 +88,36 @@ func (b* Bounds) Intersect(ray primitives.Ray) bool {
 	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
 	return tmin <= tmax;
 }
+
+// Clip Find the range of distances along the ray within the bounds, rays running along a side count as inside
+func (b *Bounds) Clip(ray primitives.Ray) (float64, float64, bool) {
+	xtmin, xtmax := clipAxis(ray.Origin.X, ray.Direction.X, b.Min.X, b.Max.X)
+	ytmin, ytmax := clipAxis(ray.Origin.Y, ray.Direction.Y, b.Min.Y, b.Max.Y)
+	ztmin, ztmax := clipAxis(ray.Origin.Z, ray.Direction.Z, b.Min.Z, b.Max.Z)
+	tmin := math.Max(math.Max(xtmin, ytmin), ztmin)
+	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
+	return tmin, tmax, tmin <= tmax
+}
+
+// intersectCounted Check if a ray intersects the bounds like Intersect, counting the test into counts
+func (b *Bounds) intersectCounted(ray primitives.Ray, counts *stats.Counts) bool {
+	counts.AddBoundsTests(1)
+	return b.Intersect(ray)
+}
+
+// clipCounted Find the range of distances along the ray within the bounds like Clip, counting the test into counts
+func (b *Bounds) clipCounted(ray primitives.Ray, counts *stats.Counts) (float64, float64, bool) {
+	counts.AddBoundsTests(1)
+	return b.Clip(ray)
+}
+
+// clipAxis Like CheckAxis, but a ray parallel to the axis is either inside or outside for its whole length
+func clipAxis(origin, direction, minimum, maximum float64) (float64, float64) {
+	if direction == 0 {
+		if origin < minimum || origin > maximum {
+			return math.Inf(1), math.Inf(-1)
+		}
+		return math.Inf(-1), math.Inf(1)
+	}
+	return CheckAxis(origin, direction, minimum, maximum)
+}
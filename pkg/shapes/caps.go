@@ -6,7 +6,12 @@ import (
 
 // CheckCap Checks if an intersection happens at the cap
 func CheckCap(r primitives.Ray, t float64) bool {
+	return CheckCapRadius(r, t, 1)
+}
+
+// CheckCapRadius Checks if an intersection happens at a cap of the given radius
+func CheckCapRadius(r primitives.Ray, t, radius float64) bool {
 	x := r.Origin.X + (t * r.Direction.X)
 	z := r.Origin.Z + (t * r.Direction.Z)
-	return ((x * x) + (z * z)) <= 1
+	return ((x * x) + (z * z)) <= (radius * radius)
 }
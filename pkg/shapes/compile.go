@@ -0,0 +1,59 @@
+package shapes
+
+import (
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// compiler Shape that can cache its matrices to world-space, shapes holding others compile them as well while
+// recording the prototypes of instances already compiled
+type compiler interface {
+	compile(parent primitives.Matrix, prototypes map[Shape]bool)
+}
+
+// Compile Cache the combined matrices from object to world-space for the roots and every shape under them, so
+// converting normals and points no longer walks through the parents. A prototype shared by several instances is
+// compiled once. The cache of a shape is dropped when its own transform or parent changes, but changing a group
+// means compiling it again
+func Compile(roots ...Shape) {
+	prototypes := map[Shape]bool{}
+	for _, root := range roots {
+		if shape, ok := root.(compiler); ok {
+			shape.compile(primitives.MakeIdentityMatrix(4), prototypes)
+		}
+	}
+}
+
+// Flatten Move the transforms of groups and CSG down to the shapes under the roots, baking them into the vertices
+// of triangles and meshes so rays reach those without being transformed at every level. Meshes sharing a vertex
+// buffer under the same transform still share it once baked. Instances only take the transform themselves since
+// their prototype may be shared
+func Flatten(roots ...Shape) {
+	baked := map[*VertexBuffer][]bakedBuffer{}
+	for _, root := range roots {
+		flatten(root, primitives.MakeIdentityMatrix(4), baked)
+	}
+}
+
+// flatten Give the shape the combined transform of its parents and itself
+func flatten(shape Shape, parent primitives.Matrix, baked map[*VertexBuffer][]bakedBuffer) {
+	transform := parent.Multiply(shape.Transform())
+	switch s := shape.(type) {
+	case *Group:
+		s.ShapeBase.SetTransform(primitives.MakeIdentityMatrix(4))
+		for _, child := range s.shapes {
+			flatten(child, transform, baked)
+		}
+		s.updateBounds()
+	case *CSG:
+		s.ShapeBase.SetTransform(primitives.MakeIdentityMatrix(4))
+		flatten(s.left, transform, baked)
+		flatten(s.right, transform, baked)
+		s.computeBounds()
+	case *Triangle:
+		s.bake(transform)
+	case *Mesh:
+		s.bake(transform, baked)
+	default:
+		shape.SetTransform(transform)
+	}
+}
@@ -0,0 +1,159 @@
+package shapes_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// nestedScene Groups nested three deep holding one of each kind of shape, along with the shapes to check
+func nestedScene() (*shapes.Group, []shapes.Shape) {
+	outer := shapes.MakeGroup()
+	outer.SetTransform(primitives.Translation(1, 2, 3).Multiply(primitives.RotationY(0.4)))
+	inner := shapes.MakeGroup()
+	inner.SetTransform(primitives.Scaling(2, 1, 0.5).Multiply(primitives.RotationX(-0.3)))
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Translation(0, 0, -1))
+	triangle := shapes.MakeSmoothTriangle(primitives.MakePoint(-2, -1, 1), primitives.MakePoint(2, -1, 1),
+		primitives.MakePoint(0, 2, 1), primitives.MakeVector(-1, 0, -1), primitives.MakeVector(1, 0, -1),
+		primitives.MakeVector(0, 1, -1))
+	mesh := gridMesh(4)
+	mesh.SetTransform(primitives.Translation(-2, 0, 3).Multiply(primitives.RotationX(-math.Pi / 2)))
+	prototype := shapes.MakeGroup()
+	cube := shapes.MakeCube()
+	cube.SetTransform(primitives.Scaling(0.5, 0.5, 0.5))
+	prototype.AddShape(cube)
+	instance := shapes.MakeInstance(prototype)
+	instance.SetTransform(primitives.Translation(0, 3, 0))
+	left := shapes.MakeSphere()
+	right := shapes.MakeCube()
+	right.SetTransform(primitives.Translation(0, -1, 0))
+	csg := shapes.MakeCSG(shapes.DIFFERENCE, left, right)
+	csg.SetTransform(primitives.Translation(0, -3, 0))
+	inner.AddShape(sphere)
+	inner.AddShape(triangle)
+	inner.AddShape(csg)
+	outer.AddShape(inner)
+	outer.AddShape(mesh)
+	outer.AddShape(instance)
+	return outer, []shapes.Shape{sphere, triangle, mesh, cube, left, right}
+}
+
+func TestCompile(t *testing.T) {
+	expected, expectedShapes := nestedScene()
+	compiled, compiledShapes := nestedScene()
+	shapes.Compile(compiled)
+	points := []primitives.PV{primitives.MakePoint(0, 0, 0), primitives.MakePoint(1.5, -2, 0.25),
+		primitives.MakePoint(0.3, 0.6, -0.7)}
+	check := func() {
+		for index := range expectedShapes {
+			for _, point := range points {
+				if result := compiledShapes[index].WorldToObjectPV(point); !result.Equals(
+					expectedShapes[index].WorldToObjectPV(point)) {
+					t.Errorf("Shape %v, expected %v in object-space, got %v", index,
+						expectedShapes[index].WorldToObjectPV(point), result)
+				}
+				if result := compiledShapes[index].ObjectToWorldPV(point); !result.Equals(
+					expectedShapes[index].ObjectToWorldPV(point)) {
+					t.Errorf("Shape %v, expected %v in world-space, got %v", index,
+						expectedShapes[index].ObjectToWorldPV(point), result)
+				}
+				if result := compiledShapes[index].Normal(point, 0.2, 0.3); !result.Equals(
+					expectedShapes[index].Normal(point, 0.2, 0.3)) {
+					t.Errorf("Shape %v, expected normal %v, got %v", index,
+						expectedShapes[index].Normal(point, 0.2, 0.3), result)
+				}
+			}
+		}
+		// Shading every hit must give the same normals and texture coordinates
+		for x := -3.0; x <= 3; x += 0.5 {
+			for y := -4.0; y <= 6; y += 0.5 {
+				ray := primitives.Ray{Origin: primitives.MakePoint(x, y, -10), Direction: primitives.MakeVector(0.05, 0.1, 1)}
+				expectedHits, hits := expected.Intersect(ray), compiled.Intersect(ray)
+				if len(hits) != len(expectedHits) {
+					t.Errorf("Ray %v, expected %v, got %v", ray, expectedHits, hits)
+					continue
+				}
+				for index, hit := range hits {
+					want := expectedHits[index]
+					point := ray.Position(hit.Distance)
+					if result := hit.Obj.Normal(point, hit.U, hit.V); !result.Equals(want.Obj.Normal(point, want.U, want.V)) {
+						t.Errorf("Ray %v, expected normal %v, got %v", ray, want.Obj.Normal(point, want.U, want.V), result)
+					}
+					if result := hit.Obj.UVMapping(point); !result.Equals(want.Obj.UVMapping(point)) {
+						t.Errorf("Ray %v, expected UV %v, got %v", ray, want.Obj.UVMapping(point), result)
+					}
+				}
+			}
+		}
+	}
+	check()
+	// Moving a compiled shape must not keep its old matrices around
+	for _, transform := range []primitives.Matrix{primitives.Translation(0, 1, 0), primitives.Scaling(1, 3, 1)} {
+		expectedShapes[0].SetTransform(transform)
+		compiledShapes[0].SetTransform(transform)
+		check()
+	}
+	// Neither does moving a group once compiled again
+	expected.SetTransform(primitives.RotationZ(1))
+	compiled.SetTransform(primitives.RotationZ(1))
+	shapes.Compile(compiled)
+	check()
+}
+
+func TestFlatten(t *testing.T) {
+	expected, _ := nestedScene()
+	flattened, _ := nestedScene()
+	shapes.Flatten(flattened)
+	if !flattened.Transform().Equals(primitives.MakeIdentityMatrix(4)) {
+		t.Errorf("Expected the group to have an identity matrix, got %v", flattened.Transform())
+	}
+	for x := -3.0; x <= 3; x += 0.5 {
+		for y := -4.0; y <= 6; y += 0.5 {
+			ray := primitives.Ray{Origin: primitives.MakePoint(x, y, -10), Direction: primitives.MakeVector(0.05, 0.1, 1)}
+			expectedHits := expected.Intersect(ray)
+			hits := flattened.Intersect(ray)
+			sort.Sort(expectedHits)
+			sort.Sort(hits)
+			if len(hits) != len(expectedHits) {
+				t.Errorf("Ray %v, expected %v, got %v", ray, expectedHits, hits)
+				continue
+			}
+			for index, hit := range hits {
+				want := expectedHits[index]
+				if math.Abs(hit.Distance-want.Distance) > 1e-6 {
+					t.Errorf("Ray %v, expected distance %v, got %v", ray, want.Distance, hit.Distance)
+					continue
+				}
+				point := ray.Position(hit.Distance)
+				// Flat faces of a mesh may face the other way once baked, which shading accounts for
+				normal, wantNormal := hit.Obj.Normal(point, hit.U, hit.V), want.Obj.Normal(point, want.U, want.V)
+				if math.Abs(math.Abs(normal.DotProduct(wantNormal))-1) > 1e-6 {
+					t.Errorf("Ray %v, expected normal %v, got %v", ray, wantNormal, normal)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkNestedNormal(b *testing.B) {
+	_, leaves := nestedScene()
+	point := primitives.MakePoint(1, 1, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		leaves[0].Normal(point, 0, 0)
+	}
+}
+
+func BenchmarkCompiledNormal(b *testing.B) {
+	root, leaves := nestedScene()
+	shapes.Compile(root)
+	point := primitives.MakePoint(1, 1, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		leaves[0].Normal(point, 0, 0)
+	}
+}
@@ -6,37 +6,63 @@ import (
 	"github.com/factorion/graytracer/pkg/primitives"
 )
 
-// Cone Represents a cone
+// Cone Represents a cone, or a frustum when neither radius is zero
 type Cone struct {
 	ShapeBase
-	closed bool
+	closed                    bool
+	minimum, maximum          float64
+	bottomRadius, topRadius   float64
+	radiusOffset, radiusSlope float64
 }
 
 // MakeCone Make a regular cone with an identity matrix for transform
 func MakeCone(closed bool) *Cone {
-	return &Cone{MakeShapeBase(), closed}
+	return MakeFrustum(-1, 0, 1, 0, closed)
+}
+
+// MakeFrustum Make a cone running from the minimum to the maximum height, with the
+// bottom radius at the minimum and the top radius at the maximum
+func MakeFrustum(minimum, maximum, bottomRadius, topRadius float64, closed bool) *Cone {
+	// Radius varies linearly with height, radius = offset + slope * y. A frustum with no height is only its caps,
+	// so its side keeps the bottom radius rather than dividing by zero
+	slope := 0.0
+	if maximum != minimum {
+		slope = (topRadius - bottomRadius) / (maximum - minimum)
+	}
+	return &Cone{ShapeBase: MakeShapeBase(), closed: closed, minimum: minimum, maximum: maximum,
+		bottomRadius: bottomRadius, topRadius: topRadius,
+		radiusOffset: bottomRadius - (slope * minimum), radiusSlope: slope}
 }
 
 // GetBounds Return an axis aligned bounding box for the sphere
 func (cone *Cone) GetBounds() *Bounds {
-	bounds := Bounds{Min: primitives.MakePoint(-1, -1, -1), Max: primitives.MakePoint(1, 0, 1)}
+	radius := math.Max(cone.bottomRadius, cone.topRadius)
+	bounds := Bounds{Min: primitives.MakePoint(-radius, cone.minimum, -radius),
+		Max: primitives.MakePoint(radius, cone.maximum, radius)}
 	return bounds.Transform(cone.transform)
 }
 
 // Intersect Check if a ray intersects
 func (cone *Cone) Intersect(r primitives.Ray) Intersections {
-	hits := Intersections{}
+	return cone.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of a ray to the end of hits, reusing its space
+func (cone *Cone) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
 	// convert ray to object space
 	oray := r.Transform(cone.Inverse())
-	a := (oray.Direction.X * oray.Direction.X) - (oray.Direction.Y * oray.Direction.Y) +
+	// Radius of the cone at the height of the ray origin
+	originRadius := cone.radiusOffset + (cone.radiusSlope * oray.Origin.Y)
+	slopeY := cone.radiusSlope * oray.Direction.Y
+	a := (oray.Direction.X * oray.Direction.X) - (slopeY * slopeY) +
 		(oray.Direction.Z * oray.Direction.Z)
-	b := (2.0 * oray.Origin.X * oray.Direction.X) - (2.0 * oray.Origin.Y * oray.Direction.Y) +
+	b := (2.0 * oray.Origin.X * oray.Direction.X) - (2.0 * originRadius * slopeY) +
 		(2.0 * oray.Origin.Z * oray.Direction.Z)
-	c := (oray.Origin.X * oray.Origin.X) - (oray.Origin.Y * oray.Origin.Y) + (oray.Origin.Z * oray.Origin.Z)
+	c := (oray.Origin.X * oray.Origin.X) - (originRadius * originRadius) + (oray.Origin.Z * oray.Origin.Z)
 	if (math.Abs(a) < primitives.EPSILON) && (math.Abs(b) > primitives.EPSILON) {
 		t0 := -c / (2.0 * b)
 		y0 := oray.Origin.Y + (t0 * oray.Direction.Y)
-		if (-1 < y0) && (y0 < 0) {
+		if (cone.minimum < y0) && (y0 < cone.maximum) {
 			hits = append(hits, Intersection{Distance: t0, Obj: cone})
 		}
 	} else if math.Abs(a) > primitives.EPSILON {
@@ -53,12 +79,12 @@ func (cone *Cone) Intersect(r primitives.Ray) Intersections {
 
 		// Verify hits are within height of cone
 		y0 := oray.Origin.Y + (t0 * oray.Direction.Y)
-		if (-1 < y0) && (y0 < 0) {
+		if (cone.minimum < y0) && (y0 < cone.maximum) {
 			hits = append(hits, Intersection{Distance: t0, Obj: cone})
 		}
 
 		y1 := oray.Origin.Y + (t1 * oray.Direction.Y)
-		if (-1 < y1) && (y1 < 0) {
+		if (cone.minimum < y1) && (y1 < cone.maximum) {
 			hits = append(hits, Intersection{Distance: t1, Obj: cone})
 		}
 	}
@@ -68,10 +94,19 @@ func (cone *Cone) Intersect(r primitives.Ray) Intersections {
 		return hits
 	}
 
-	// Check bottom and top caps
-	t := (-1 - oray.Origin.Y) / oray.Direction.Y
-	if CheckCap(oray, t) {
-		hits = append(hits, Intersection{Distance: t, Obj: cone})
+	// Check bottom and top caps, a cap with no radius is the tip of the cone
+	if cone.bottomRadius > primitives.EPSILON {
+		t := (cone.minimum - oray.Origin.Y) / oray.Direction.Y
+		if CheckCapRadius(oray, t, cone.bottomRadius) {
+			hits = append(hits, Intersection{Distance: t, Obj: cone})
+		}
+	}
+
+	if cone.topRadius > primitives.EPSILON {
+		t := (cone.maximum - oray.Origin.Y) / oray.Direction.Y
+		if CheckCapRadius(oray, t, cone.topRadius) {
+			hits = append(hits, Intersection{Distance: t, Obj: cone})
+		}
 	}
 
 	return hits
@@ -82,19 +117,25 @@ func (cone *Cone) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
 	var objectNormal primitives.PV
 	objectPoint := cone.WorldToObjectPV(worldPoint)
 	distance := (objectPoint.X * objectPoint.X) + (objectPoint.Z * objectPoint.Z)
-	if (distance < 1) && (objectPoint.Y <= (-1.0 + primitives.EPSILON)) {
+	if (distance < (cone.topRadius * cone.topRadius)) && (objectPoint.Y >= (cone.maximum - primitives.EPSILON)) {
+		objectNormal = primitives.MakeVector(0, 1, 0)
+	} else if (distance < (cone.bottomRadius * cone.bottomRadius)) &&
+		(objectPoint.Y <= (cone.minimum + primitives.EPSILON)) {
 		objectNormal = primitives.MakeVector(0, -1, 0)
 	} else {
-		objectNormal = primitives.MakeVector(objectPoint.X, math.Sqrt(distance), objectPoint.Z)
+		// Gradient of x^2 + z^2 - radius(y)^2
+		radius := cone.radiusOffset + (cone.radiusSlope * objectPoint.Y)
+		objectNormal = primitives.MakeVector(objectPoint.X, -cone.radiusSlope*radius, objectPoint.Z)
 	}
 	worldNormal := cone.ObjectToWorldPV(objectNormal)
 	worldNormal.W = 0.0
 	return worldNormal.Normalize()
 }
 
-// UVMapping Return the 2D coordinates of an intersection point
+// UVMapping Return the 2D coordinates of an intersection point, V runs from the minimum to the maximum
 func (cone *Cone) UVMapping(point primitives.PV) primitives.PV {
 	objectPoint := cone.WorldToObjectPV(point)
 	d := primitives.MakePoint(0, 0, 0).Subtract(objectPoint)
-	return primitives.MakePoint(0.5+math.Atan2(d.X, d.Z)/(2*math.Pi), objectPoint.Y+1, 0)
+	return primitives.MakePoint(0.5+math.Atan2(d.X, d.Z)/(2*math.Pi),
+		heightFraction(objectPoint.Y, cone.minimum, cone.maximum), 0)
 }
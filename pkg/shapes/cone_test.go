@@ -20,6 +20,14 @@ func TestConeGetBounds(t *testing.T) {
 		{shapes.MakeCone(false),
 			primitives.Scaling(2, 2, 2),
 			primitives.MakePoint(-2, -2, -2), primitives.MakePoint(2, 0, 2)},
+
+		{shapes.MakeFrustum(0, 2, 1, 0.5, true),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1, 0, -1), primitives.MakePoint(1, 2, 1)},
+
+		{shapes.MakeFrustum(-1, 1, 0.5, 2, true),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-2, -1, -2), primitives.MakePoint(2, 1, 2)},
 	}
 	for _, table := range tables {
 		table.cone.SetTransform(table.transform)
@@ -56,6 +64,23 @@ func TestConeIntersection(t *testing.T) {
 		{shapes.MakeCone(true),
 			primitives.Ray{Origin: primitives.MakePoint(0, -1, -0.25), Direction: primitives.MakeVector(0, 1, 0)},
 			primitives.Translation(0, 1, 0), []float64{1.75, 1}},
+
+		// Frustum intersections
+		{shapes.MakeFrustum(0, 2, 1, 0.5, false),
+			primitives.Ray{Origin: primitives.MakePoint(0, 1, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{4.25, 5.75}},
+
+		{shapes.MakeFrustum(0, 2, 1, 0.5, false),
+			primitives.Ray{Origin: primitives.MakePoint(0, 3, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{shapes.MakeFrustum(0, 2, 1, 0.5, true),
+			primitives.Ray{Origin: primitives.MakePoint(0, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{5, 3}},
+
+		{shapes.MakeFrustum(0, 2, 1, 0.5, true),
+			primitives.Ray{Origin: primitives.MakePoint(0.9, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{4.6, 5}},
 	}
 	for _, table := range tables {
 		table.s.SetTransform(table.transform)
@@ -89,6 +114,24 @@ func TestConeNormal(t *testing.T) {
 		{shapes.MakeCone(false), primitives.Translation(0, 1, 0),
 			primitives.MakePoint(0.5, 0.5, 0),
 			primitives.MakeVector(0.7071067811865475, 0.7071067811865475, 0)},
+
+		// Frustum normals
+		{shapes.MakeFrustum(0, 2, 1, 0.5, true), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.2, 2, 0),
+			primitives.MakeVector(0, 1, 0)},
+
+		{shapes.MakeFrustum(0, 2, 1, 0.5, true), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.5, 0, 0),
+			primitives.MakeVector(0, -1, 0)},
+
+		{shapes.MakeFrustum(0, 2, 1, 0.5, false), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.75, 1, 0),
+			primitives.MakeVector(0.9701425001453319, 0.24253562503633297, 0)},
+
+		// A frustum with no height is only its caps
+		{shapes.MakeFrustum(1, 1, 1, 0.5, true), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.2, 1, 0),
+			primitives.MakeVector(0, 1, 0)},
 	}
 	for _, table := range tables {
 		table.c.SetTransform(table.transform)
@@ -108,3 +151,42 @@ func BenchmarkConeNormal(b *testing.B) {
 		cone.Normal(point, 0.0, 0.0)
 	}
 }
+
+func TestConeUVMapping(t *testing.T) {
+	tables := []struct {
+		c         *shapes.Cone
+		point, uv primitives.PV
+	}{
+		{shapes.MakeCone(false), primitives.MakePoint(0, -0.5, -0.5), primitives.MakePoint(0.5, 0.5, 0)},
+		{shapes.MakeFrustum(0, 2, 1, 0.5, false), primitives.MakePoint(-0.75, 1, 0), primitives.MakePoint(0.75, 0.5, 0)},
+
+		{shapes.MakeFrustum(1, 1, 1, 0.5, true), primitives.MakePoint(0.5, 1, 0), primitives.MakePoint(0.25, 0, 0)},
+	}
+	for _, table := range tables {
+		uv := table.c.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
+
+func TestFlatFrustum(t *testing.T) {
+	cone := shapes.MakeFrustum(1, 1, 1, 0.5, true)
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.2, 3, 0), Direction: primitives.MakeVector(0, -1, 0)}
+	xs := cone.Intersect(ray)
+	if len(xs) == 0 {
+		t.Fatal("Expected the caps to be hit")
+	}
+	for _, hit := range xs {
+		if hit.Distance != 2 {
+			t.Errorf("Expected the caps at a distance of 2, got %v", hit.Distance)
+		}
+	}
+	if xs := cone.Intersect(primitives.Ray{Origin: primitives.MakePoint(-3, 1.5, 0), Direction: primitives.MakeVector(1, 0, 0)}); len(xs) != 0 {
+		t.Errorf("Expected a ray above the caps to miss, got %v", xs)
+	}
+	bounds := cone.GetBounds()
+	if bounds.Min.Y != 1 || bounds.Max.Y != 1 || bounds.Max.X != 1 {
+		t.Errorf("Expected flat bounds, got %v", bounds)
+	}
+}
@@ -1,9 +1,11 @@
 package shapes
 
 import (
+	"math"
 	"sort"
 
 	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
 )
 
 type Operation int
@@ -22,9 +24,75 @@ type CSG struct {
 	bounds      *Bounds
 }
 
-// MakeCSG
+// MakeCSG Make a CSG node from two shapes with an identity matrix for transform, the shapes should be
+// fully set up first as the bounds are computed from them here
 func MakeCSG(op Operation, shape1, shape2 Shape) *CSG {
-	return &CSG{MakeShapeBase(), op, shape1, shape2, nil}
+	csg := &CSG{MakeShapeBase(), op, shape1, shape2, nil}
+	shape1.SetParent(csg)
+	shape2.SetParent(csg)
+	csg.computeBounds()
+	return csg
+}
+
+// Left Get the left operand of the CSG
+func (csg *CSG) Left() Shape {
+	return csg.left
+}
+
+// Right Get the right operand of the CSG
+func (csg *CSG) Right() Shape {
+	return csg.right
+}
+
+// SetTransform Set the transform of the CSG and move its bounds with it
+func (csg *CSG) SetTransform(m primitives.Matrix) {
+	csg.ShapeBase.SetTransform(m)
+	csg.computeBounds()
+}
+
+// compile Cache the matrices to world-space for the CSG and both operands
+func (csg *CSG) compile(parent primitives.Matrix, prototypes map[Shape]bool) {
+	csg.ShapeBase.compile(parent, prototypes)
+	for _, operand := range []Shape{csg.left, csg.right} {
+		if child, ok := operand.(compiler); ok {
+			child.compile(csg.world, prototypes)
+		}
+	}
+}
+
+// computeBounds Combine the bounds of the operands the way the operation combines the shapes,
+// an unbounded operand leaves the CSG unbounded unless the other operand limits it
+func (csg *CSG) computeBounds() {
+	left, right := finiteBounds(csg.left.GetBounds()), finiteBounds(csg.right.GetBounds())
+	var bounds *Bounds
+	switch csg.op {
+	case UNION:
+		if left != nil && right != nil {
+			bounds = &Bounds{Min: left.Min, Max: left.Max}
+			bounds.AddBounds(right)
+		}
+	case INTERSECT:
+		switch {
+		case left == nil:
+			bounds = right
+		case right == nil:
+			bounds = left
+		default:
+			// Shapes that don't overlap leave an empty box at the corner where they come closest
+			min := primitives.MakePoint(math.Max(left.Min.X, right.Min.X), math.Max(left.Min.Y, right.Min.Y),
+				math.Max(left.Min.Z, right.Min.Z))
+			max := primitives.MakePoint(math.Max(math.Min(left.Max.X, right.Max.X), min.X),
+				math.Max(math.Min(left.Max.Y, right.Max.Y), min.Y), math.Max(math.Min(left.Max.Z, right.Max.Z), min.Z))
+			bounds = &Bounds{Min: min, Max: max}
+		}
+	case DIFFERENCE:
+		bounds = left
+	}
+	if bounds == nil {
+		csg.bounds = nil
+		return
+	}
+	csg.bounds = bounds.Transform(csg.transform)
 }
 
 // GetBounds Return an axis aligned bounding box for the CSG
@@ -32,6 +100,29 @@ func (csg *CSG) GetBounds() *Bounds {
 	return csg.bounds
 }
 
+// finiteBounds Treat bounds reaching infinity, like those of a plane, as no bounds at all
+func finiteBounds(bounds *Bounds) *Bounds {
+	if bounds == nil {
+		return nil
+	}
+	for _, value := range []float64{bounds.Min.X, bounds.Min.Y, bounds.Min.Z, bounds.Max.X, bounds.Max.Y, bounds.Max.Z} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil
+		}
+	}
+	return bounds
+}
+
+// Includes Check if a shape is the container or one of the shapes inside it, following the parents of the shape
+func Includes(container, shape Shape) bool {
+	for ; shape != nil; shape = shape.Parent() {
+		if shape == container {
+			return true
+		}
+	}
+	return false
+}
+
 // intersection_allowed Determines whether the intersection is valid or not
 func (csg *CSG) IntersectionAllowed(lhit, inl, inr bool) bool {
 	allowed := false
@@ -48,44 +139,54 @@ func (csg *CSG) IntersectionAllowed(lhit, inl, inr bool) bool {
 
 // Intersect Check if a ray intersects
 func (csg *CSG) Intersect(r primitives.Ray) Intersections {
-	hits := Intersections{}
-	if (csg.bounds == nil) || (csg.bounds.Intersect(r)) {
-		// convert ray to object space
-		oray := r.Transform(csg.Inverse())
-		lhits := csg.left.Intersect(oray)
-		rhits := csg.right.Intersect(oray)
-		sort.Sort(lhits)
-		sort.Sort(rhits)
-		lhit := false
-		inl := false
-		inr := false
-		lindex := 0
-		rindex := 0
-		for lindex < lhits.Len() || rindex < rhits.Len() {
-			var i Intersection
-			if (lindex >= lhits.Len()) || (rindex < rhits.Len() && rhits[rindex].Distance < lhits[lindex].Distance) {
-				i = rhits[rindex]
-				lhit = false
-				rindex++
-			} else {
-				i = lhits[lindex]
-				lhit = true
-				lindex++
-			}
-			if csg.IntersectionAllowed(lhit, inl, inr) {
-				hits = append(hits, i)
-			}
-			if lhit {
-				inl = !inl
-			} else {
-				inr = !inr
-			}
+	return csg.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits on the surface of the combined shape to the end of hits, reusing its space
+func (csg *CSG) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
+	return csg.appendIntersectionsCounted(r, hits, nil)
+}
+
+// appendIntersectionsCounted Add the hits on the surface of the combined shape like AppendIntersections, counting
+// the tests
+func (csg *CSG) appendIntersectionsCounted(r primitives.Ray, hits Intersections, counts *stats.Counts) Intersections {
+	if (csg.bounds != nil) && (!csg.bounds.intersectCounted(r, counts)) {
+		return hits
+	}
+	start := len(hits)
+	// convert ray to object space
+	oray := r.Transform(csg.Inverse())
+	hits = AppendIntersectionsCounted(csg.left, oray, hits, counts)
+	hits = AppendIntersectionsCounted(csg.right, oray, hits, counts)
+	sort.Sort(hits[start:])
+	// Filtering never writes past the hit being read, so it can be done in place
+	return csg.appendFiltered(hits[:start], hits[start:])
+}
+
+// FilterIntersections Keep the sorted hits on the surface of the combined shape
+func (csg *CSG) FilterIntersections(hits Intersections) Intersections {
+	return csg.appendFiltered(Intersections{}, hits)
+}
+
+// appendFiltered Add the sorted hits on the surface of the combined shape to the end of result
+func (csg *CSG) appendFiltered(result, hits Intersections) Intersections {
+	inl := false
+	inr := false
+	for _, i := range hits {
+		lhit := Includes(csg.left, i.Obj)
+		if csg.IntersectionAllowed(lhit, inl, inr) {
+			result = append(result, i)
+		}
+		if lhit {
+			inl = !inl
+		} else {
+			inr = !inr
 		}
 	}
-	return hits
+	return result
 }
 
-// Normal Calculate the normal at a given point on the sphere
+// Normal Calculate the normal at a given point on the CSG
 func (csg *CSG) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
 	// Only exists for Interface, should never be called
 	return primitives.MakeVector(0, 1, 0)
@@ -1,6 +1,7 @@
 package shapes_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/factorion/graytracer/pkg/primitives"
@@ -82,7 +83,7 @@ func TestIntersetction(t *testing.T) {
 		distances   []float64
 		shape_index []int
 	}{
-		/*{
+		{
 			shapes.UNION,
 			[]shapes.Shape{shapes.MakeSphere(), shapes.MakeCube()},
 			[]primitives.Matrix{primitives.MakeIdentityMatrix(4), primitives.MakeIdentityMatrix(4)},
@@ -90,7 +91,7 @@ func TestIntersetction(t *testing.T) {
 			0,
 			[]float64{},
 			[]int{},
-		},*/
+		},
 
 		{
 			shapes.UNION,
@@ -120,3 +121,128 @@ func TestIntersetction(t *testing.T) {
 		}
 	}
 }
+
+func TestCSGGetBounds(t *testing.T) {
+	left := shapes.MakeSphere()
+	right := shapes.MakeSphere()
+	right.SetTransform(primitives.Translation(1, 0, 0))
+	apart := shapes.MakeSphere()
+	apart.SetTransform(primitives.Translation(5, 0, 0))
+	tables := []struct {
+		csg       *shapes.CSG
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{shapes.MakeCSG(shapes.UNION, left, right), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1, -1, -1), primitives.MakePoint(2, 1, 1)},
+
+		{shapes.MakeCSG(shapes.INTERSECT, left, right), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, -1, -1), primitives.MakePoint(1, 1, 1)},
+
+		{shapes.MakeCSG(shapes.DIFFERENCE, left, right), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1, -1, -1), primitives.MakePoint(1, 1, 1)},
+
+		{shapes.MakeCSG(shapes.UNION, left, right), primitives.Translation(0, 3, 0),
+			primitives.MakePoint(-1, 2, -1), primitives.MakePoint(2, 4, 1)},
+
+		{shapes.MakeCSG(shapes.INTERSECT, shapes.MakePlane(), right), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, -1, -1), primitives.MakePoint(2, 1, 1)},
+
+		{shapes.MakeCSG(shapes.INTERSECT, left, apart), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(4, -1, -1), primitives.MakePoint(4, 1, 1)},
+	}
+	for _, table := range tables {
+		table.csg.SetTransform(table.transform)
+		bounds := table.csg.GetBounds()
+		if bounds == nil {
+			t.Errorf("Expected bounds %v to %v, got none", table.min, table.max)
+			continue
+		}
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+	if shapes.MakeCSG(shapes.UNION, shapes.MakePlane(), shapes.MakeSphere()).GetBounds() != nil {
+		t.Errorf("Expected no bounds for a union with a plane")
+	}
+}
+
+func TestCSGParents(t *testing.T) {
+	left := shapes.MakeSphere()
+	right := shapes.MakeCube()
+	right.SetTransform(primitives.Scaling(0.5, 0.5, 0.5))
+	csg := shapes.MakeCSG(shapes.DIFFERENCE, left, right)
+	csg.SetTransform(primitives.Translation(0, 0, 5).Multiply(primitives.Scaling(2, 2, 2)))
+	if left.Parent() != csg || right.Parent() != csg {
+		t.Errorf("Expected the operands to have the CSG as their parent")
+	}
+	if csg.Left() != left || csg.Right() != right {
+		t.Errorf("Expected the operands to be kept in order")
+	}
+	ray := primitives.Ray{Origin: primitives.MakePoint(1.2, 0, 0), Direction: primitives.MakeVector(0, 0, 1)}
+	hits := csg.Intersect(ray)
+	if !shapes.IntersectEquals(hits, []float64{3.4, 6.6}) {
+		t.Fatalf("Expected hit %v, got %v", []float64{3.4, 6.6}, hits)
+	}
+	normal := hits[0].Obj.Normal(ray.Position(hits[0].Distance), 0, 0)
+	if !normal.Equals(primitives.MakeVector(0.6, 0, -0.8)) {
+		t.Errorf("Expected %v, got %v", primitives.MakeVector(0.6, 0, -0.8), normal)
+	}
+}
+
+func TestCSGOperands(t *testing.T) {
+	// A group of two spheres minus a nested CSG carving a slab from the middle
+	group := shapes.MakeGroup()
+	sphere1 := shapes.MakeSphere()
+	sphere1.SetTransform(primitives.Translation(0, 0, -1.25))
+	sphere2 := shapes.MakeSphere()
+	sphere2.SetTransform(primitives.Translation(0, 0, 1.25))
+	group.AddShape(sphere1)
+	group.AddShape(sphere2)
+	slab := shapes.MakeCube()
+	slab.SetTransform(primitives.Scaling(2, 2, 0.5))
+	hole := shapes.MakeTruncatedCylinder(-1, 1, true)
+	hole.SetTransform(primitives.RotationX(math.Pi / 2).Multiply(primitives.Scaling(0.25, 1, 0.25)))
+	nested := shapes.MakeCSG(shapes.DIFFERENCE, slab, hole)
+	csg := shapes.MakeCSG(shapes.DIFFERENCE, group, nested)
+	tables := []struct {
+		r    primitives.Ray
+		hits []float64
+	}{
+		{primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			[]float64{2.75, 4.75, 5.25, 7.25}},
+
+		{primitives.Ray{Origin: primitives.MakePoint(0.5, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			[]float64{5 - (1.25 + math.Sqrt(0.75)), 4.5, 5.5, 5 + 1.25 + math.Sqrt(0.75)}},
+
+		{primitives.Ray{Origin: primitives.MakePoint(0, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			[]float64{}},
+	}
+	for _, table := range tables {
+		hits := csg.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Ray %v, expected hit %v, got %v", table.r, table.hits, hits)
+		}
+	}
+	if !shapes.Includes(group, sphere2) || !shapes.Includes(csg, slab) || shapes.Includes(nested, sphere1) {
+		t.Errorf("Expected shapes to be included by their containers only")
+	}
+	if !shapes.Includes(hole, hole) {
+		t.Errorf("Expected a shape to include itself")
+	}
+}
+
+func BenchmarkCSGIntersection(b *testing.B) {
+	left := shapes.MakeSphere()
+	right := shapes.MakeSphere()
+	right.SetTransform(primitives.Translation(0, 0, 0.5))
+	csg := shapes.MakeCSG(shapes.DIFFERENCE, left, right)
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		csg.Intersect(ray)
+	}
+}
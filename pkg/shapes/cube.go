@@ -35,6 +35,11 @@ func (c *Cube) GetBounds() *Bounds {
 
 // Intersect Check for intersection along one of the six sides of the cube
 func (c *Cube) Intersect(r primitives.Ray) Intersections {
+	return c.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of a ray to the end of hits, reusing its space
+func (c *Cube) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
 	// convert ray to object space
 	oray := r.Transform(c.Inverse())
 	xtmin, xtmax := CheckAxis(oray.Origin.X, oray.Direction.X, -1, 1)
@@ -43,9 +48,9 @@ func (c *Cube) Intersect(r primitives.Ray) Intersections {
 	tmin := math.Max(math.Max(xtmin, ytmin), ztmin)
 	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
 	if tmin > tmax {
-		return Intersections{}
+		return hits
 	}
-	return Intersections{Intersection{Distance: tmin, Obj: c}, Intersection{Distance: tmax, Obj: c}}
+	return append(hits, Intersection{Distance: tmin, Obj: c}, Intersection{Distance: tmax, Obj: c})
 }
 
 // Normal Calculate the normal at a given point on the cube
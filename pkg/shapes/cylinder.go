@@ -9,23 +9,33 @@ import (
 // Cylinder Represents a cylinder
 type Cylinder struct {
 	ShapeBase
-	closed bool
+	closed           bool
+	minimum, maximum float64
 }
 
 // MakeCylinder Make a regular cylinder with an identity matrix for transform
 func MakeCylinder(closed bool) *Cylinder {
-	return &Cylinder{MakeShapeBase(), closed}
+	return MakeTruncatedCylinder(0, 1, closed)
+}
+
+// MakeTruncatedCylinder Make a cylinder running from the minimum to the maximum height
+func MakeTruncatedCylinder(minimum, maximum float64, closed bool) *Cylinder {
+	return &Cylinder{MakeShapeBase(), closed, minimum, maximum}
 }
 
 // GetBounds Return an axis aligned bounding box for the sphere
 func (cyl *Cylinder) GetBounds() *Bounds {
-	bounds := &Bounds{Min: primitives.MakePoint(-1, 0, -1), Max: primitives.MakePoint(1, 1, 1)}
+	bounds := &Bounds{Min: primitives.MakePoint(-1, cyl.minimum, -1), Max: primitives.MakePoint(1, cyl.maximum, 1)}
 	return bounds.Transform(cyl.transform)
 }
 
 // Intersect Check if a ray intersects
 func (cyl *Cylinder) Intersect(r primitives.Ray) Intersections {
-	hits := Intersections{}
+	return cyl.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of a ray to the end of hits, reusing its space
+func (cyl *Cylinder) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
 	// convert ray to object space
 	oray := r.Transform(cyl.Inverse())
 	a := (oray.Direction.X * oray.Direction.X) + (oray.Direction.Z * oray.Direction.Z)
@@ -43,30 +53,30 @@ func (cyl *Cylinder) Intersect(r primitives.Ray) Intersections {
 			t0, t1 = t1, t0
 		}
 
-		// Verify hits are within height of cone
+		// Verify hits are within height of cylinder
 		y0 := oray.Origin.Y + (t0 * oray.Direction.Y)
-		if (0 < y0) && (y0 < 1) {
+		if (cyl.minimum < y0) && (y0 < cyl.maximum) {
 			hits = append(hits, Intersection{Distance: t0, Obj: cyl})
 		}
 
 		y1 := oray.Origin.Y + (t1 * oray.Direction.Y)
-		if (0 < y1) && (y1 < 1) {
+		if (cyl.minimum < y1) && (y1 < cyl.maximum) {
 			hits = append(hits, Intersection{Distance: t1, Obj: cyl})
 		}
 	}
 
 	// Cap checking only matters if cylinder is closed
-	if !cyl.closed || math.Abs(oray.Direction.Y) < 0 {
+	if !cyl.closed || math.Abs(oray.Direction.Y) < primitives.EPSILON {
 		return hits
 	}
 
 	// Check bottom and top caps
-	t := -oray.Origin.Y / oray.Direction.Y
+	t := (cyl.minimum - oray.Origin.Y) / oray.Direction.Y
 	if CheckCap(oray, t) {
 		hits = append(hits, Intersection{Distance: t, Obj: cyl})
 	}
 
-	t = (1.0 / oray.Direction.Y) + t
+	t = (cyl.maximum - oray.Origin.Y) / oray.Direction.Y
 	if CheckCap(oray, t) {
 		hits = append(hits, Intersection{Distance: t, Obj: cyl})
 	}
@@ -79,9 +89,9 @@ func (cyl *Cylinder) Normal(worldPoint primitives.PV, u, v float64) primitives.P
 	var objectNormal primitives.PV
 	objectPoint := cyl.WorldToObjectPV(worldPoint)
 	distance := (objectPoint.X * objectPoint.X) + (objectPoint.Z * objectPoint.Z)
-	if (distance < 1) && (objectPoint.Y >= (1.0 - primitives.EPSILON)) {
+	if (distance < 1) && (objectPoint.Y >= (cyl.maximum - primitives.EPSILON)) {
 		objectNormal = primitives.MakeVector(0, 1, 0)
-	} else if (distance < 1) && (objectPoint.Y <= primitives.EPSILON) {
+	} else if (distance < 1) && (objectPoint.Y <= (cyl.minimum + primitives.EPSILON)) {
 		objectNormal = primitives.MakeVector(0, -1, 0)
 	} else {
 		objectNormal = primitives.MakeVector(objectPoint.X, 0, objectPoint.Z)
@@ -91,9 +101,18 @@ func (cyl *Cylinder) Normal(worldPoint primitives.PV, u, v float64) primitives.P
 	return worldNormal.Normalize()
 }
 
-// UVMapping Return the 2D coordinates of an intersection point
+// UVMapping Return the 2D coordinates of an intersection point, V runs from the minimum to the maximum
 func (cyl *Cylinder) UVMapping(point primitives.PV) primitives.PV {
 	objectPoint := cyl.WorldToObjectPV(point)
 	d := primitives.MakePoint(0, 0, 0).Subtract(objectPoint)
-	return primitives.MakePoint(0.5+math.Atan2(d.X, d.Z)/(2*math.Pi), objectPoint.Y, 0)
+	return primitives.MakePoint(0.5+math.Atan2(d.X, d.Z)/(2*math.Pi),
+		heightFraction(objectPoint.Y, cyl.minimum, cyl.maximum), 0)
+}
+
+// heightFraction Fraction of the way from the minimum to the maximum a height is, 0 when they are the same
+func heightFraction(y, minimum, maximum float64) float64 {
+	if maximum == minimum {
+		return 0
+	}
+	return (y - minimum) / (maximum - minimum)
 }
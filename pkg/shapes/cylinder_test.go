@@ -20,6 +20,10 @@ func TestCylinderGetBounds(t *testing.T) {
 		{shapes.MakeCylinder(false),
 			primitives.Scaling(4, 3, 2),
 			primitives.MakePoint(-4, 0, -2), primitives.MakePoint(4, 3, 2)},
+
+		{shapes.MakeTruncatedCylinder(1, 2, false),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1, 1, -1), primitives.MakePoint(1, 2, 1)},
 	}
 	for _, table := range tables {
 		table.cylinder.SetTransform(table.transform)
@@ -109,6 +113,19 @@ func TestCylinderIntersection(t *testing.T) {
 		{shapes.MakeCylinder(true),
 			primitives.Ray{Origin: primitives.MakePoint(0, -1, -2), Direction: primitives.MakeVector(0, 1, 1)},
 			primitives.Translation(0, 1, 0), []float64{2, 3}},
+
+		// Truncated cylinder intersections
+		{shapes.MakeTruncatedCylinder(1, 2, false),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{shapes.MakeTruncatedCylinder(1, 2, false),
+			primitives.Ray{Origin: primitives.MakePoint(0, 1.5, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 6}},
+
+		{shapes.MakeTruncatedCylinder(1, 2, true),
+			primitives.Ray{Origin: primitives.MakePoint(0, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 3}},
 	}
 	for _, table := range tables {
 		table.s.SetTransform(table.transform)
@@ -176,6 +193,19 @@ func TestCylinderNormal(t *testing.T) {
 		{shapes.MakeCylinder(true), primitives.Scaling(0, 2, 0),
 			primitives.MakePoint(0, 2, 0.5),
 			primitives.MakeVector(0, 1, 0)},
+
+		// Truncated cylinder normals
+		{shapes.MakeTruncatedCylinder(1, 2, true), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.5, 2, 0),
+			primitives.MakeVector(0, 1, 0)},
+
+		{shapes.MakeTruncatedCylinder(1, 2, true), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.5, 1, 0),
+			primitives.MakeVector(0, -1, 0)},
+
+		{shapes.MakeTruncatedCylinder(1, 2, true), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(1, 1.5, 0),
+			primitives.MakeVector(1, 0, 0)},
 	}
 	for _, table := range tables {
 		table.c.SetTransform(table.transform)
@@ -195,3 +225,21 @@ func BenchmarkCylinderNormal(b *testing.B) {
 		cylinder.Normal(point, 0.0, 0.0)
 	}
 }
+
+func TestCylinderUVMapping(t *testing.T) {
+	tables := []struct {
+		c         *shapes.Cylinder
+		point, uv primitives.PV
+	}{
+		{shapes.MakeCylinder(false), primitives.MakePoint(0, 0.25, -1), primitives.MakePoint(0.5, 0.25, 0)},
+		{shapes.MakeTruncatedCylinder(1, 3, false), primitives.MakePoint(0, 2, -1), primitives.MakePoint(0.5, 0.5, 0)},
+		{shapes.MakeTruncatedCylinder(1, 3, false), primitives.MakePoint(1, 3, 0), primitives.MakePoint(0.25, 1, 0)},
+		{shapes.MakeTruncatedCylinder(2, 2, true), primitives.MakePoint(1, 2, 0), primitives.MakePoint(0.25, 0, 0)},
+	}
+	for _, table := range tables {
+		uv := table.c.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
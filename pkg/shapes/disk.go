@@ -0,0 +1,76 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Disk Flat unit disk along the XZ axis, with an optional hole to make an annulus
+type Disk struct {
+	ShapeBase
+	inner float64
+}
+
+// MakeDisk Make a solid unit disk with an identity matrix for transform
+func MakeDisk() *Disk {
+	return &Disk{MakeShapeBase(), 0}
+}
+
+// MakeAnnulus Make a unit disk with a hole of the inner radius in the middle, which must be at least 0 and less
+// than 1
+func MakeAnnulus(inner float64) (*Disk, error) {
+	if !(inner >= 0 && inner < 1) {
+		return nil, errors.New("annulus inner radius must be at least 0 and less than 1")
+	}
+	return &Disk{MakeShapeBase(), inner}, nil
+}
+
+// GetBounds Return an axis aligned bounding box for the disk
+func (d *Disk) GetBounds() *Bounds {
+	bounds := &Bounds{Min: primitives.MakePoint(-1, -primitives.EPSILON, -1),
+		Max: primitives.MakePoint(1, primitives.EPSILON, 1)}
+	return bounds.Transform(d.transform)
+}
+
+// Intersect Check if a ray intersects
+func (d *Disk) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	// convert ray to object space
+	objectRay := r.Transform(d.Inverse())
+	if math.Abs(objectRay.Direction.Y) < primitives.EPSILON {
+		return hits
+	}
+	t := -objectRay.Origin.Y / objectRay.Direction.Y
+	x := objectRay.Origin.X + (t * objectRay.Direction.X)
+	z := objectRay.Origin.Z + (t * objectRay.Direction.Z)
+	distance := (x * x) + (z * z)
+	if (distance <= 1) && (distance >= (d.inner * d.inner)) {
+		hits = append(hits, Intersection{Distance: t, Obj: d})
+	}
+	return hits
+}
+
+// Normal Calculate the normal at a given point on the disk
+func (d *Disk) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	objectNormal := primitives.MakeVector(0, 1, 0)
+	worldNormal := d.ObjectToWorldPV(objectNormal)
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the 2D coordinates of an intersected point, U around the disk and V outwards from the inner edge
+func (d *Disk) UVMapping(point primitives.PV) primitives.PV {
+	objectPoint := d.WorldToObjectPV(point)
+	radius := math.Sqrt((objectPoint.X * objectPoint.X) + (objectPoint.Z * objectPoint.Z))
+	u := 0.5 + math.Atan2(objectPoint.X, objectPoint.Z)/(2*math.Pi)
+	return primitives.MakePoint(u, (radius-d.inner)/(1-d.inner), 0)
+}
+
+// SamplePoint Map U and V to a world-space point, spread evenly over the area of the disk
+func (d *Disk) SamplePoint(u, v float64) primitives.PV {
+	radius := math.Sqrt((d.inner * d.inner) + (u * (1 - (d.inner * d.inner))))
+	angle := 2 * math.Pi * v
+	return d.objectToWorldPoint(primitives.MakePoint(radius*math.Sin(angle), 0, radius*math.Cos(angle)))
+}
@@ -0,0 +1,167 @@
+package shapes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// mustAnnulus Make an annulus that must not be an error
+func mustAnnulus(inner float64) *shapes.Disk {
+	disk, err := shapes.MakeAnnulus(inner)
+	if err != nil {
+		panic(err)
+	}
+	return disk
+}
+
+func TestDiskGetBounds(t *testing.T) {
+	tables := []struct {
+		disk      *shapes.Disk
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{shapes.MakeDisk(),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1, -primitives.EPSILON, -1), primitives.MakePoint(1, primitives.EPSILON, 1)},
+
+		{mustAnnulus(0.5),
+			primitives.Scaling(2, 1, 3),
+			primitives.MakePoint(-2, -primitives.EPSILON, -3), primitives.MakePoint(2, primitives.EPSILON, 3)},
+	}
+	for _, table := range tables {
+		table.disk.SetTransform(table.transform)
+		bounds := table.disk.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+}
+
+func TestDiskIntersection(t *testing.T) {
+	tables := []struct {
+		d         *shapes.Disk
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{shapes.MakeDisk(),
+			primitives.Ray{Origin: primitives.MakePoint(0, 1, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{shapes.MakeDisk(),
+			primitives.Ray{Origin: primitives.MakePoint(0.5, 1, 0.5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{shapes.MakeDisk(),
+			primitives.Ray{Origin: primitives.MakePoint(1, 1, 1), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{shapes.MakeDisk(),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{mustAnnulus(0.5),
+			primitives.Ray{Origin: primitives.MakePoint(0.25, 1, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{mustAnnulus(0.5),
+			primitives.Ray{Origin: primitives.MakePoint(0.75, 1, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{shapes.MakeDisk(),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.RotationX(math.Pi / 2), []float64{5}},
+	}
+	for _, table := range tables {
+		table.d.SetTransform(table.transform)
+		hits := table.d.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+	}
+}
+
+func BenchmarkDiskIntersection(b *testing.B) {
+	disk := mustAnnulus(0.5)
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.75, 1, 0), Direction: primitives.MakeVector(0, -1, 0)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		disk.Intersect(ray)
+	}
+}
+
+func TestDiskNormal(t *testing.T) {
+	tables := []struct {
+		d             *shapes.Disk
+		transform     primitives.Matrix
+		point, normal primitives.PV
+	}{
+		{shapes.MakeDisk(), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.5, 0, 0), primitives.MakeVector(0, 1, 0)},
+
+		{shapes.MakeDisk(), primitives.RotationX(math.Pi / 2),
+			primitives.MakePoint(0, 0.5, 0), primitives.MakeVector(0, 0, 1)},
+	}
+	for _, table := range tables {
+		table.d.SetTransform(table.transform)
+		normal := table.d.Normal(table.point, 0.0, 0.0)
+		if !normal.Equals(table.normal) {
+			t.Errorf("Expected %v, got %v", table.normal, normal)
+		}
+	}
+}
+
+func TestDiskUVMapping(t *testing.T) {
+	tables := []struct {
+		d         *shapes.Disk
+		point, uv primitives.PV
+	}{
+		{shapes.MakeDisk(), primitives.MakePoint(0, 0, 1), primitives.MakePoint(0.5, 1, 0)},
+		{shapes.MakeDisk(), primitives.MakePoint(0, 0, 0), primitives.MakePoint(0.5, 0, 0)},
+		{mustAnnulus(0.5), primitives.MakePoint(0.75, 0, 0), primitives.MakePoint(0.75, 0.5, 0)},
+	}
+	for _, table := range tables {
+		uv := table.d.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
+
+func TestDiskSamplePoint(t *testing.T) {
+	tables := []struct {
+		d         *shapes.Disk
+		transform primitives.Matrix
+		u, v      float64
+		point     primitives.PV
+	}{
+		{shapes.MakeDisk(), primitives.MakeIdentityMatrix(4), 0, 0, primitives.MakePoint(0, 0, 0)},
+		{shapes.MakeDisk(), primitives.MakeIdentityMatrix(4), 1, 0.25, primitives.MakePoint(1, 0, 0)},
+		{mustAnnulus(0.5), primitives.MakeIdentityMatrix(4), 0, 0, primitives.MakePoint(0, 0, 0.5)},
+		{shapes.MakeDisk(), primitives.Translation(0, 2, 0), 1, 0.25, primitives.MakePoint(1, 2, 0)},
+	}
+	for _, table := range tables {
+		table.d.SetTransform(table.transform)
+		point := table.d.SamplePoint(table.u, table.v)
+		if !point.Equals(table.point) {
+			t.Errorf("U: %v, V: %v, Expected %v, got %v", table.u, table.v, table.point, point)
+		}
+	}
+}
+
+func TestAnnulusErrors(t *testing.T) {
+	for _, inner := range []float64{1, 2, -0.5, math.NaN()} {
+		if disk, err := shapes.MakeAnnulus(inner); err == nil {
+			t.Errorf("Inner radius %v, expected an error, got %v", inner, disk)
+		}
+	}
+	if _, err := shapes.MakeAnnulus(0); err != nil {
+		t.Errorf("Expected an annulus without a hole to be made, got %v", err)
+	}
+}
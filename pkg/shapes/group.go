@@ -1,9 +1,15 @@
 package shapes
 
 import (
+	"runtime"
+
 	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
 )
 
+// groupParallelSize Fewest shapes added at once for which their bounds are found in parallel
+const groupParallelSize = 256
+
 // Group Represents a group of other shapes
 type Group struct {
 	ShapeBase
@@ -21,10 +27,48 @@ func (g *Group) GetBounds() *Bounds {
 	return g.bounds
 }
 
+// Shapes Get the shapes in the group
+func (g *Group) Shapes() []Shape {
+	return g.shapes
+}
+
 // AddShape Add a shape to the group and set its parent
 func (g *Group) AddShape(shape Shape) {
 	g.shapes = append(g.shapes, shape)
 	shape.SetParent(g)
+	g.addBounds(shape)
+}
+
+// AddShapes Add shapes to the group and set their parents, finding the bounds of large numbers of shapes in parallel
+func (g *Group) AddShapes(shapes ...Shape) {
+	bounds := make([]*Bounds, len(shapes))
+	workers := 1
+	if len(shapes) >= groupParallelSize {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	parallelFor(len(shapes), workers, func(start, end int) {
+		for index := start; index < end; index++ {
+			shapes[index].SetParent(g)
+			if shapeBounds := shapes[index].GetBounds(); shapeBounds != nil {
+				bounds[index] = shapeBounds.Transform(g.transform)
+			}
+		}
+	})
+	g.shapes = append(g.shapes, shapes...)
+	for _, shapeBounds := range bounds {
+		if shapeBounds == nil {
+			continue
+		}
+		if g.bounds == nil {
+			g.bounds = shapeBounds
+		} else {
+			g.bounds.AddBounds(shapeBounds)
+		}
+	}
+}
+
+// addBounds Grow the bounds of the group to hold a shape
+func (g *Group) addBounds(shape Shape) {
 	bounds := shape.GetBounds()
 	if bounds != nil {
 		if g.bounds == nil {
@@ -35,19 +79,67 @@ func (g *Group) AddShape(shape Shape) {
 	}
 }
 
+// updateBounds Recalculate the bounds from the shapes in the group after their transforms changed
+func (g *Group) updateBounds() {
+	g.bounds = nil
+	for _, shape := range g.shapes {
+		g.addBounds(shape)
+	}
+}
+
+// compile Cache the matrices to world-space for the group and the shapes in it
+func (g *Group) compile(parent primitives.Matrix, prototypes map[Shape]bool) {
+	g.ShapeBase.compile(parent, prototypes)
+	for _, shape := range g.shapes {
+		if child, ok := shape.(compiler); ok {
+			child.compile(g.world, prototypes)
+		}
+	}
+}
+
 // Intersect Check if a ray intersects
 func (g *Group) Intersect(r primitives.Ray) Intersections {
-	hits := Intersections{}
-	if (g.bounds == nil) || (g.bounds.Intersect(r)) {
+	return g.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of the shapes in the group to the end of hits, reusing its space
+func (g *Group) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
+	return g.appendIntersectionsCounted(r, hits, nil)
+}
+
+// appendIntersectionsCounted Add the hits of the shapes in the group like AppendIntersections, counting the tests
+func (g *Group) appendIntersectionsCounted(r primitives.Ray, hits Intersections, counts *stats.Counts) Intersections {
+	if (g.bounds == nil) || (g.bounds.intersectCounted(r, counts)) {
 		// convert ray to object space
 		oray := r.Transform(g.inverse)
 		for _, shape := range g.shapes {
-			hits = append(hits, shape.Intersect(oray)...)
+			hits = AppendIntersectionsCounted(shape, oray, hits, counts)
 		}
 	}
 	return hits
 }
 
+// IntersectAny Visit the hits of the shapes in the group, stopping once visit returns false
+func (g *Group) IntersectAny(r primitives.Ray, maxDistance float64, visit func(Intersection) bool) bool {
+	return g.intersectAnyCounted(r, maxDistance, visit, nil)
+}
+
+// intersectAnyCounted Visit the hits of the shapes in the group like IntersectAny, counting the tests
+func (g *Group) intersectAnyCounted(r primitives.Ray, maxDistance float64, visit func(Intersection) bool,
+	counts *stats.Counts) bool {
+	if (g.bounds != nil) && (!g.bounds.intersectCounted(r, counts)) {
+		return true
+	}
+	// convert ray to object space
+	oray := r.Transform(g.inverse)
+	for _, shape := range g.shapes {
+		if !IntersectAnyCounted(shape, oray, maxDistance, visit, counts) {
+			return false
+		}
+	}
+	return true
+}
+
 // Normal Calculate the normal at a given point on the sphere
 func (g *Group) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
 	// Only exists for Interface, should never be called
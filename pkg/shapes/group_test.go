@@ -46,3 +46,40 @@ func TestGroupIntersection(t *testing.T) {
 		}
 	}
 }
+
+func TestGroupAddShapes(t *testing.T) {
+	single := shapes.MakeGroup()
+	single.SetTransform(primitives.Translation(1, 0, 0))
+	many := shapes.MakeGroup()
+	many.SetTransform(primitives.Translation(1, 0, 0))
+	spheres := []shapes.Shape{}
+	for index := 0; index < 1000; index++ {
+		sphere := shapes.MakeSphere()
+		sphere.SetTransform(primitives.Translation(float64(index % 10) * 3, float64(index / 10) * 3, 0))
+		single.AddShape(sphere)
+		copied := shapes.MakeSphere()
+		copied.SetTransform(sphere.Transform())
+		spheres = append(spheres, copied)
+	}
+	many.AddShapes(spheres...)
+	if !many.GetBounds().Min.Equals(single.GetBounds().Min) || !many.GetBounds().Max.Equals(single.GetBounds().Max) {
+		t.Errorf("Expected bounds %v, got %v", single.GetBounds(), many.GetBounds())
+	}
+	for _, sphere := range spheres {
+		if sphere.Parent() != many {
+			t.Errorf("Expected the group to be the parent of %v", sphere)
+		}
+	}
+	for y := 0.5; y < 300; y += 7 {
+		ray := primitives.Ray{Origin:primitives.MakePoint(-5, y, 0), Direction:primitives.MakeVector(1, 0, 0)}
+		hits, expected := many.Intersect(ray), single.Intersect(ray)
+		sort.Sort(expected)
+		distances := []float64{}
+		for _, hit := range expected {
+			distances = append(distances, hit.Distance)
+		}
+		if !shapes.IntersectEquals(hits, distances) {
+			t.Errorf("Ray %v, expected %v, got %v", ray, distances, hits)
+		}
+	}
+}
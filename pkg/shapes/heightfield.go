@@ -0,0 +1,235 @@
+package shapes
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// HeightField Terrain over the unit square in the XZ plane, the heights are sampled on a regular grid
+// with each cell split into two triangles
+type HeightField struct {
+	ShapeBase
+	width, depth int
+	heights      []float64
+	normals      []primitives.PV
+	bounds       Bounds
+}
+
+// checkHeightFieldSize Check a grid has at least two samples along each side, so every cell has corners
+func checkHeightFieldSize(width, depth int) error {
+	if width < 2 || depth < 2 {
+		return fmt.Errorf("height field is %dx%d, both sizes must be at least 2", width, depth)
+	}
+	return nil
+}
+
+// MakeHeightField Make a height field from width by depth samples stored row by row along x,
+// with an identity matrix for transform. Both sizes must be at least 2, with a height for every sample
+func MakeHeightField(width, depth int, heights []float64) (*HeightField, error) {
+	if err := checkHeightFieldSize(width, depth); err != nil {
+		return nil, err
+	}
+	if len(heights) != width*depth {
+		return nil, fmt.Errorf("height field is %dx%d, expected %d heights, got %d", width, depth, width*depth,
+			len(heights))
+	}
+	hf := &HeightField{ShapeBase: MakeShapeBase(), width: width, depth: depth, heights: heights,
+		normals: make([]primitives.PV, width*depth)}
+	minimum, maximum := math.Inf(1), math.Inf(-1)
+	for _, height := range heights {
+		minimum = math.Min(minimum, height)
+		maximum = math.Max(maximum, height)
+	}
+	hf.bounds = Bounds{Min: primitives.MakePoint(0, minimum, 0), Max: primitives.MakePoint(1, maximum, 1)}
+	// Vertex normals from the slope between neighbouring samples
+	for z := 0; z < depth; z++ {
+		for x := 0; x < width; x++ {
+			x0, x1 := maxInt(x-1, 0), minInt(x+1, width-1)
+			z0, z1 := maxInt(z-1, 0), minInt(z+1, depth-1)
+			dx := (hf.height(x1, z) - hf.height(x0, z)) * float64(width-1) / float64(x1-x0)
+			dz := (hf.height(x, z1) - hf.height(x, z0)) * float64(depth-1) / float64(z1-z0)
+			hf.normals[(z*width)+x] = primitives.MakeVector(-dx, 1, -dz).Normalize()
+		}
+	}
+	return hf, nil
+}
+
+// MakeFunctionHeightField Make a height field sampling the function on a width by depth grid over the unit square
+func MakeFunctionHeightField(width, depth int, height func(x, z float64) float64) (*HeightField, error) {
+	if err := checkHeightFieldSize(width, depth); err != nil {
+		return nil, err
+	}
+	heights := make([]float64, width*depth)
+	for z := 0; z < depth; z++ {
+		for x := 0; x < width; x++ {
+			heights[(z*width)+x] = height(float64(x)/float64(width-1), float64(z)/float64(depth-1))
+		}
+	}
+	return MakeHeightField(width, depth, heights)
+}
+
+// MakeImageHeightField Make a height field from the brightness of an image between 0 and 1, the top row
+// of the image is at z=1 so the field lines up with an image texture of the same picture. The image must be at least
+// 2 pixels wide and tall
+func MakeImageHeightField(img image.Image) (*HeightField, error) {
+	bounds := img.Bounds()
+	width, depth := bounds.Dx(), bounds.Dy()
+	heights := make([]float64, width*depth)
+	for y := 0; y < depth; y++ {
+		for x := 0; x < width; x++ {
+			gray := color.Gray16Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray16)
+			heights[((depth-1-y)*width)+x] = float64(gray.Y) / 0xffff
+		}
+	}
+	return MakeHeightField(width, depth, heights)
+}
+
+// LoadHeightField Decode a grayscale PNG or JPEG file into a height field
+func LoadHeightField(filename string) (*HeightField, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return MakeImageHeightField(img)
+}
+
+// height Get the height of a sample on the grid
+func (hf *HeightField) height(x, z int) float64 {
+	return hf.heights[(z*hf.width)+x]
+}
+
+// vertex Get the object-space point of a sample on the grid
+func (hf *HeightField) vertex(x, z int) primitives.PV {
+	return primitives.MakePoint(float64(x)/float64(hf.width-1), hf.height(x, z), float64(z)/float64(hf.depth-1))
+}
+
+// GetBounds Return an axis aligned bounding box for the height field
+func (hf *HeightField) GetBounds() *Bounds {
+	return hf.bounds.Transform(hf.transform)
+}
+
+// Intersect Check if a ray intersects, walking the cells of the grid along the ray
+func (hf *HeightField) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	// convert ray to object space
+	oray := r.Transform(hf.Inverse())
+	tmin, tmax, ok := hf.bounds.Clip(oray)
+	if !ok {
+		return hits
+	}
+	// Walk the grid in cell units
+	cellsX, cellsZ := hf.width-1, hf.depth-1
+	start := oray.Position(tmin)
+	x, stepX, nextX, deltaX := gridAxis(start.X*float64(cellsX), oray.Direction.X*float64(cellsX), tmin, cellsX)
+	z, stepZ, nextZ, deltaZ := gridAxis(start.Z*float64(cellsZ), oray.Direction.Z*float64(cellsZ), tmin, cellsZ)
+	enter := tmin
+	for x >= 0 && x < cellsX && z >= 0 && z < cellsZ && enter <= tmax {
+		exit := math.Min(math.Min(nextX, nextZ), tmax)
+		// Skip cells where the ray passes entirely above or below the terrain
+		y0, y1 := oray.Origin.Y+(oray.Direction.Y*enter), oray.Origin.Y+(oray.Direction.Y*exit)
+		low := math.Min(math.Min(hf.height(x, z), hf.height(x+1, z)), math.Min(hf.height(x, z+1), hf.height(x+1, z+1)))
+		high := math.Max(math.Max(hf.height(x, z), hf.height(x+1, z)), math.Max(hf.height(x, z+1), hf.height(x+1, z+1)))
+		if math.Min(y0, y1) <= high+primitives.EPSILON && math.Max(y0, y1) >= low-primitives.EPSILON {
+			corner, opposite := hf.vertex(x, z), hf.vertex(x+1, z+1)
+			for _, third := range []primitives.PV{hf.vertex(x+1, z), hf.vertex(x, z+1)} {
+				distance, _, _, ok := intersectTriangle(corner, third, opposite, oray)
+				if !ok || hf.seen(hits, distance) {
+					continue
+				}
+				hits = append(hits, Intersection{Distance: distance, Obj: hf})
+			}
+		}
+		if exit >= tmax {
+			break
+		}
+		if nextX < nextZ {
+			x += stepX
+			enter, nextX = nextX, nextX+deltaX
+		} else {
+			z += stepZ
+			enter, nextZ = nextZ, nextZ+deltaZ
+		}
+	}
+	return hits
+}
+
+// seen Check if a hit on an edge shared by triangles was already found from the other side
+func (hf *HeightField) seen(hits Intersections, distance float64) bool {
+	for index := maxInt(len(hits)-2, 0); index < len(hits); index++ {
+		if math.Abs(hits[index].Distance-distance) < primitives.EPSILON {
+			return true
+		}
+	}
+	return false
+}
+
+// gridAxis Set up walking one axis of the grid, returning the starting cell, the step direction,
+// the distance to the next cell boundary and the distance between boundaries
+func gridAxis(position, direction, start float64, cells int) (int, int, float64, float64) {
+	cell := minInt(maxInt(int(math.Floor(position)), 0), cells-1)
+	switch {
+	case direction > 0:
+		return cell, 1, start + ((float64(cell+1) - position) / direction), 1 / direction
+	case direction < 0:
+		return cell, -1, start + ((float64(cell) - position) / direction), -1 / direction
+	default:
+		return cell, 0, math.Inf(1), math.Inf(1)
+	}
+}
+
+// cell Find the cell under an object-space point and the position within it
+func (hf *HeightField) cell(point primitives.PV) (int, int, float64, float64) {
+	gx, gz := point.X*float64(hf.width-1), point.Z*float64(hf.depth-1)
+	x := minInt(maxInt(int(math.Floor(gx)), 0), hf.width-2)
+	z := minInt(maxInt(int(math.Floor(gz)), 0), hf.depth-2)
+	return x, z, gx - float64(x), gz - float64(z)
+}
+
+// Normal Calculate the normal at a given point, interpolating the vertex normals across the triangle
+func (hf *HeightField) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	x, z, fx, fz := hf.cell(hf.WorldToObjectPV(worldPoint))
+	n00, n11 := hf.normals[(z*hf.width)+x], hf.normals[((z+1)*hf.width)+x+1]
+	var normal primitives.PV
+	if fx >= fz {
+		n10 := hf.normals[(z*hf.width)+x+1]
+		normal = n00.Scalar(1 - fx).Add(n10.Scalar(fx - fz)).Add(n11.Scalar(fz))
+	} else {
+		n01 := hf.normals[((z+1)*hf.width)+x]
+		normal = n00.Scalar(1 - fz).Add(n01.Scalar(fz - fx)).Add(n11.Scalar(fx))
+	}
+	worldNormal := hf.ObjectToWorldPV(normal)
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the 2D coordinates of an intersection point, the grid covers U and V from 0 to 1
+func (hf *HeightField) UVMapping(point primitives.PV) primitives.PV {
+	objectPoint := hf.WorldToObjectPV(point)
+	return primitives.MakePoint(objectPoint.X, objectPoint.Z, 0)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
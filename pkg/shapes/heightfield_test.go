@@ -0,0 +1,265 @@
+package shapes_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// mustHeightField Get a height field that was made without an error
+func mustHeightField(field *shapes.HeightField, err error) *shapes.HeightField {
+	if err != nil {
+		panic(err)
+	}
+	return field
+}
+
+// hillField Smooth bump in the middle of the unit square
+func hillField(size int) *shapes.HeightField {
+	return mustHeightField(shapes.MakeFunctionHeightField(size, size, func(x, z float64) float64 {
+		return 0.5 * math.Sin(math.Pi*x) * math.Sin(math.Pi*z)
+	}))
+}
+
+func TestHeightFieldGetBounds(t *testing.T) {
+	tables := []struct {
+		field     *shapes.HeightField
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{mustHeightField(shapes.MakeHeightField(2, 2, []float64{0, 1, 0.5, -1})), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, -1, 0), primitives.MakePoint(1, 1, 1)},
+
+		{hillField(33), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(1, 0.5, 1)},
+
+		{hillField(33), primitives.Scaling(10, 2, 10),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(10, 1, 10)},
+	}
+	for _, table := range tables {
+		table.field.SetTransform(table.transform)
+		bounds := table.field.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+}
+
+func TestHeightFieldIntersection(t *testing.T) {
+	ramp := mustHeightField(shapes.MakeFunctionHeightField(5, 3, func(x, z float64) float64 { return x }))
+	tables := []struct {
+		field     *shapes.HeightField
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{ramp,
+			primitives.Ray{Origin: primitives.MakePoint(0.3, 5, 0.6), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{4.7}},
+
+		{ramp,
+			primitives.Ray{Origin: primitives.MakePoint(0, 0.5, 0.3), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{0.5}},
+
+		{ramp,
+			primitives.Ray{Origin: primitives.MakePoint(-0.5, 5, 5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.Scaling(10, 1, 10), []float64{}},
+
+		{ramp,
+			primitives.Ray{Origin: primitives.MakePoint(5, 5, 5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.Scaling(10, 1, 10), []float64{4.5}},
+
+		{ramp,
+			primitives.Ray{Origin: primitives.MakePoint(1.5, 0.5, 0.5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{hillField(17),
+			primitives.Ray{Origin: primitives.MakePoint(-1, 0.25, 0.5), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1 + (1.0 / 6), 1 + (5.0 / 6)}},
+	}
+	for index, table := range tables {
+		table.field.SetTransform(table.transform)
+		hits := table.field.Intersect(table.r)
+		// The hill is made of flat triangles so only approximates the function
+		if index == len(tables)-1 {
+			if len(hits) != 2 || math.Abs(hits[0].Distance-table.hits[0]) > 0.01 ||
+				math.Abs(hits[1].Distance-table.hits[1]) > 0.01 {
+				t.Errorf("Expected hit near %v, got %v", table.hits, hits)
+			}
+			continue
+		}
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+	}
+}
+
+func TestHeightFieldTraversal(t *testing.T) {
+	field := hillField(16)
+	// Walking the grid must find the same hits as testing every triangle of the field
+	triangles := []*shapes.Triangle{}
+	point := func(x, z int) primitives.PV {
+		fx, fz := float64(x)/15, float64(z)/15
+		return primitives.MakePoint(fx, 0.5*math.Sin(math.Pi*fx)*math.Sin(math.Pi*fz), fz)
+	}
+	for z := 0; z < 15; z++ {
+		for x := 0; x < 15; x++ {
+			triangles = append(triangles, shapes.MakeTriangle(point(x, z), point(x+1, z), point(x+1, z+1)),
+				shapes.MakeTriangle(point(x, z), point(x, z+1), point(x+1, z+1)))
+		}
+	}
+	for angle := 0.05; angle < 2*math.Pi; angle += 0.3 {
+		for height := 0.03; height < 0.6; height += 0.07 {
+			direction := primitives.MakeVector(math.Cos(angle), -0.1, math.Sin(angle))
+			ray := primitives.Ray{Origin: primitives.MakePoint(0.5, height, 0.5).Subtract(direction.Scalar(2)),
+				Direction: direction}
+			expected := []float64{}
+			for _, triangle := range triangles {
+				for _, hit := range triangle.Intersect(ray) {
+					duplicate := false
+					for _, distance := range expected {
+						duplicate = duplicate || math.Abs(distance-hit.Distance) < primitives.EPSILON
+					}
+					if !duplicate {
+						expected = append(expected, hit.Distance)
+					}
+				}
+			}
+			hits := field.Intersect(ray)
+			if !shapes.IntersectEquals(hits, expected) {
+				t.Errorf("Ray %v, expected %v, got %v", ray, expected, hits)
+			}
+		}
+	}
+}
+
+func BenchmarkHeightFieldIntersection(b *testing.B) {
+	field := hillField(513)
+	ray := primitives.Ray{Origin: primitives.MakePoint(-0.5, 0.6, 0.3), Direction: primitives.MakeVector(1, -0.2, 0.4)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		field.Intersect(ray)
+	}
+}
+
+func TestHeightFieldNormal(t *testing.T) {
+	ramp := mustHeightField(shapes.MakeFunctionHeightField(5, 5, func(x, z float64) float64 { return x }))
+	peak := mustHeightField(shapes.MakeHeightField(3, 3, []float64{0, 0, 0, 0, 1, 0, 0, 0, 0}))
+	tables := []struct {
+		field     *shapes.HeightField
+		transform primitives.Matrix
+		point     primitives.PV
+		normal    primitives.PV
+	}{
+		{ramp, primitives.MakeIdentityMatrix(4), primitives.MakePoint(0.3, 0.3, 0.7),
+			primitives.MakeVector(-math.Sqrt(2)/2, math.Sqrt(2)/2, 0)},
+
+		{ramp, primitives.Scaling(2, 1, 1), primitives.MakePoint(0.6, 0.3, 0.7),
+			primitives.MakeVector(-1/math.Sqrt(5), 2/math.Sqrt(5), 0)},
+
+		{peak, primitives.MakeIdentityMatrix(4), primitives.MakePoint(0.5, 1, 0.5),
+			primitives.MakeVector(0, 1, 0)},
+
+		{peak, primitives.MakeIdentityMatrix(4), primitives.MakePoint(0, 0, 0.5),
+			primitives.MakeVector(-2/math.Sqrt(5), 1/math.Sqrt(5), 0)},
+
+		{peak, primitives.MakeIdentityMatrix(4), primitives.MakePoint(0.25, 0.5, 0.5),
+			primitives.MakeVector(-2/math.Sqrt(5), 1/math.Sqrt(5), 0).Add(primitives.MakeVector(0, 1, 0)).Scalar(0.5)},
+	}
+	for _, table := range tables {
+		table.field.SetTransform(table.transform)
+		normal := table.field.Normal(table.point, 0, 0)
+		expected := table.normal.Normalize()
+		if !normal.Equals(expected) {
+			t.Errorf("Point %v, Expected %v, got %v", table.point, expected, normal)
+		}
+	}
+}
+
+func TestHeightFieldUVMapping(t *testing.T) {
+	field := hillField(9)
+	field.SetTransform(primitives.Translation(-5, 0, -5).Multiply(primitives.Scaling(10, 1, 10)))
+	tables := []struct {
+		point, uv primitives.PV
+	}{
+		{primitives.MakePoint(-5, 0, -5), primitives.MakePoint(0, 0, 0)},
+		{primitives.MakePoint(0, 0.5, 0), primitives.MakePoint(0.5, 0.5, 0)},
+		{primitives.MakePoint(5, 0, -2.5), primitives.MakePoint(1, 0.25, 0)},
+	}
+	for _, table := range tables {
+		uv := field.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
+
+func TestImageHeightField(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.Gray{255})
+	img.Set(2, 1, color.Gray{51})
+	field := mustHeightField(shapes.MakeImageHeightField(img))
+	bounds := field.GetBounds()
+	if !bounds.Min.Equals(primitives.MakePoint(0, 0, 0)) || !bounds.Max.Equals(primitives.MakePoint(1, 1, 1)) {
+		t.Errorf("Expected bounds from black to white, got %v", bounds)
+	}
+	tables := []struct {
+		x, z, height float64
+	}{
+		{0, 1, 1},
+		{1, 0, 0.2},
+		{0.5, 0, 0},
+	}
+	for _, table := range tables {
+		ray := primitives.Ray{Origin: primitives.MakePoint(table.x, 5, table.z), Direction: primitives.MakeVector(0, -1, 0)}
+		if hits := field.Intersect(ray); !shapes.IntersectEquals(hits, []float64{5 - table.height}) {
+			t.Errorf("Expected height %v at %v, %v, got %v", table.height, table.x, table.z, hits)
+		}
+	}
+	if _, err := shapes.LoadHeightField("missing.png"); err == nil {
+		t.Error("Expected an error loading a missing image")
+	}
+}
+
+func TestHeightFieldSizes(t *testing.T) {
+	flat := func(x, z float64) float64 { return 0 }
+	tables := []struct {
+		name string
+		err  error
+	}{
+		{"One sample wide", func() error { _, err := shapes.MakeHeightField(1, 3, []float64{0, 0, 0}); return err }()},
+		{"Too few heights", func() error { _, err := shapes.MakeHeightField(2, 2, []float64{0, 0, 0}); return err }()},
+		{"Too many heights", func() error { _, err := shapes.MakeHeightField(2, 2, make([]float64, 5)); return err }()},
+		{"Negative size", func() error { _, err := shapes.MakeFunctionHeightField(-1, 2, flat); return err }()},
+		{"One sample deep", func() error { _, err := shapes.MakeFunctionHeightField(4, 1, flat); return err }()},
+		{"One pixel tall image", func() error {
+			_, err := shapes.MakeImageHeightField(image.NewGray(image.Rect(0, 0, 4, 1)))
+			return err
+		}()},
+	}
+	for _, table := range tables {
+		if table.err == nil {
+			t.Errorf("%v, expected an error", table.name)
+		}
+	}
+	filename := filepath.Join(t.TempDir(), "line.png")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewGray(image.Rect(0, 0, 1, 5)))
+	f.Close()
+	if field, err := shapes.LoadHeightField(filename); err == nil || field != nil {
+		t.Errorf("Expected an error loading a one pixel wide image, got %v", field)
+	}
+}
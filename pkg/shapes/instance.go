@@ -0,0 +1,190 @@
+package shapes
+
+import (
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+// Instance Places a shared prototype shape with its own transform and optional material,
+// the prototype should not be added to a group or the world itself
+type Instance struct {
+	ShapeBase
+	prototype Shape
+	override  bool
+}
+
+// MakeInstance Make an instance of the prototype with an identity matrix for transform
+func MakeInstance(prototype Shape) *Instance {
+	return &Instance{MakeShapeBase(), prototype, false}
+}
+
+// Prototype Get the shape shared by the instance
+func (in *Instance) Prototype() Shape {
+	return in.prototype
+}
+
+// SetMaterial Set a material overriding the materials of the prototype
+func (in *Instance) SetMaterial(mat patterns.Material) {
+	in.ShapeBase.SetMaterial(mat)
+	in.override = true
+}
+
+// ClearMaterial Remove the material override, using the materials of the prototype again
+func (in *Instance) ClearMaterial() {
+	in.override = false
+}
+
+// GetBounds Return an axis aligned bounding box for the instance
+func (in *Instance) GetBounds() *Bounds {
+	bounds := in.prototype.GetBounds()
+	if bounds == nil {
+		return nil
+	}
+	return bounds.Transform(in.transform)
+}
+
+// compile Cache the matrices to world-space for the instance, the prototype is compiled on its own
+// since hits on it are converted through the instance separately, and only once when shared
+func (in *Instance) compile(parent primitives.Matrix, prototypes map[Shape]bool) {
+	in.ShapeBase.compile(parent, prototypes)
+	if prototypes[in.prototype] {
+		return
+	}
+	prototypes[in.prototype] = true
+	if prototype, ok := in.prototype.(compiler); ok {
+		prototype.compile(primitives.MakeIdentityMatrix(4), prototypes)
+	}
+}
+
+// Intersect Check if a ray intersects
+func (in *Instance) Intersect(r primitives.Ray) Intersections {
+	return in.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of the prototype seen through the instance to the end of hits, reusing its space
+func (in *Instance) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
+	return in.appendIntersectionsCounted(r, hits, nil)
+}
+
+// appendIntersectionsCounted Add the hits of the prototype like AppendIntersections, counting the tests
+func (in *Instance) appendIntersectionsCounted(r primitives.Ray, hits Intersections, counts *stats.Counts) Intersections {
+	start := len(hits)
+	// convert ray to object space
+	hits = AppendIntersectionsCounted(in.prototype, r.Transform(in.inverse), hits, counts)
+	for index := start; index < len(hits); index++ {
+		hits[index].Obj = InstanceHit{in, hits[index].Obj}
+	}
+	return hits
+}
+
+// IntersectAny Visit the hits of the prototype seen through the instance, stopping once visit returns false
+func (in *Instance) IntersectAny(r primitives.Ray, maxDistance float64, visit func(Intersection) bool) bool {
+	return in.intersectAnyCounted(r, maxDistance, visit, nil)
+}
+
+// intersectAnyCounted Visit the hits of the prototype like IntersectAny, counting the tests
+func (in *Instance) intersectAnyCounted(r primitives.Ray, maxDistance float64, visit func(Intersection) bool,
+	counts *stats.Counts) bool {
+	return IntersectAnyCounted(in.prototype, r.Transform(in.inverse), maxDistance, func(hit Intersection) bool {
+		hit.Obj = InstanceHit{in, hit.Obj}
+		return visit(hit)
+	}, counts)
+}
+
+// Normal Calculate the normal at a given point on the instance
+func (in *Instance) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	// Only exists for Interface, intersections refer to the shapes of the prototype
+	return primitives.MakeVector(0, 1, 0)
+}
+
+// UVMapping Return the 2D coordinates of an intersection point
+func (in *Instance) UVMapping(point primitives.PV) primitives.PV {
+	// Only exists for Interface, intersections refer to the shapes of the prototype
+	return primitives.MakePoint(point.X, point.Y, 0)
+}
+
+// InstanceHit Shape of a prototype as seen through one of its instances
+type InstanceHit struct {
+	instance *Instance
+	shape    Shape
+}
+
+// Instance Get the instance the shape was hit through
+func (ih InstanceHit) Instance() *Instance {
+	return ih.instance
+}
+
+// Shape Get the shape of the prototype that was hit
+func (ih InstanceHit) Shape() Shape {
+	return ih.shape
+}
+
+// Intersect Check if a ray intersects the single shape placed by the instance
+func (ih InstanceHit) Intersect(r primitives.Ray) Intersections {
+	hits := ih.shape.Intersect(r.Transform(ih.instance.inverse))
+	for index := range hits {
+		hits[index].Obj = InstanceHit{ih.instance, hits[index].Obj}
+	}
+	return hits
+}
+
+// Normal Calculate the normal of the prototype shape, placed by the instance
+func (ih InstanceHit) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	normal := ih.shape.Normal(ih.instance.WorldToObjectPV(worldPoint), u, v)
+	worldNormal := ih.instance.ObjectToWorldPV(normal)
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the 2D coordinates of an intersection point on the prototype shape
+func (ih InstanceHit) UVMapping(point primitives.PV) primitives.PV {
+	return ih.shape.UVMapping(ih.instance.WorldToObjectPV(point))
+}
+
+// GetBounds Return an axis aligned bounding box for the shape placed by the instance
+func (ih InstanceHit) GetBounds() *Bounds {
+	bounds := ih.shape.GetBounds()
+	if bounds == nil {
+		return nil
+	}
+	return bounds.Transform(ih.instance.transform)
+}
+
+// SetTransform Does nothing, the prototype is shared between instances
+func (ih InstanceHit) SetTransform(m primitives.Matrix) {}
+
+// Transform Get the matrix from the object-space of the prototype shape to the object-space of the instance,
+// so walking up through the parents matches ObjectToWorldPV
+func (ih InstanceHit) Transform() primitives.Matrix {
+	return ObjectToWorldMatrix(ih.shape)
+}
+
+// SetMaterial Does nothing, the prototype is shared between instances
+func (ih InstanceHit) SetMaterial(mat patterns.Material) {}
+
+// Material Get the material of the instance if it overrides the prototype, otherwise of the shape
+func (ih InstanceHit) Material() patterns.Material {
+	if ih.instance.override {
+		return ih.instance.Material()
+	}
+	return ih.shape.Material()
+}
+
+// SetParent Does nothing, the prototype is shared between instances
+func (ih InstanceHit) SetParent(parent Shape) {}
+
+// Parent Get the instance the shape was hit through
+func (ih InstanceHit) Parent() Shape {
+	return ih.instance
+}
+
+// WorldToObjectPV Convert a Point/Vector from world to the object-space of the prototype shape
+func (ih InstanceHit) WorldToObjectPV(pv primitives.PV) primitives.PV {
+	return ih.shape.WorldToObjectPV(ih.instance.WorldToObjectPV(pv))
+}
+
+// ObjectToWorldPV Convert a Point/Vector from the object-space of the prototype shape to world-space
+func (ih InstanceHit) ObjectToWorldPV(pv primitives.PV) primitives.PV {
+	return ih.instance.ObjectToWorldPV(ih.shape.ObjectToWorldPV(pv))
+}
@@ -0,0 +1,236 @@
+package shapes_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func TestInstanceGetBounds(t *testing.T) {
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Translation(1, 0, 0))
+	tables := []struct {
+		prototype shapes.Shape
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{sphere, primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, -1, -1), primitives.MakePoint(2, 1, 1)},
+
+		{sphere, primitives.Translation(0, 5, 0),
+			primitives.MakePoint(0, 4, -1), primitives.MakePoint(2, 6, 1)},
+
+		{sphere, primitives.Scaling(2, 2, 2),
+			primitives.MakePoint(0, -2, -2), primitives.MakePoint(4, 2, 2)},
+	}
+	for _, table := range tables {
+		instance := shapes.MakeInstance(table.prototype)
+		instance.SetTransform(table.transform)
+		bounds := instance.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+	if shapes.MakeInstance(shapes.MakeGroup()).GetBounds() != nil {
+		t.Errorf("Expected no bounds for an instance of an empty group")
+	}
+}
+
+func TestInstanceIntersection(t *testing.T) {
+	group := shapes.MakeGroup()
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Translation(0, 0, 2))
+	group.AddShape(sphere)
+	tables := []struct {
+		prototype shapes.Shape
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{shapes.MakeSphere(),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 6}},
+
+		{shapes.MakeSphere(),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.Translation(5, 0, 0), []float64{}},
+
+		{shapes.MakeSphere(),
+			primitives.Ray{Origin: primitives.MakePoint(5, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.Translation(5, 0, 0), []float64{4, 6}},
+
+		{group,
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.Scaling(2, 2, 2), []float64{7, 11}},
+
+		{gridMesh(4),
+			primitives.Ray{Origin: primitives.MakePoint(12.3, 5, 1.6), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.Translation(10, 0, 0), []float64{5}},
+	}
+	for _, table := range tables {
+		instance := shapes.MakeInstance(table.prototype)
+		instance.SetTransform(table.transform)
+		hits := instance.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+		for _, hit := range hits {
+			if hit.Obj.Parent() != instance {
+				t.Errorf("Expected hit %v to belong to the instance", hit.Obj)
+			}
+		}
+	}
+}
+
+func BenchmarkInstanceIntersection(b *testing.B) {
+	instance := shapes.MakeInstance(shapes.MakeSphere())
+	instance.SetTransform(primitives.Translation(5, 0, 0))
+	ray := primitives.Ray{Origin: primitives.MakePoint(5, 0, -5), Direction: primitives.MakeVector(0, 0, 1)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		instance.Intersect(ray)
+	}
+}
+
+func TestInstanceNormal(t *testing.T) {
+	// Each instance must shade exactly like the same geometry built without instancing
+	prototype := shapes.MakeGroup()
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Translation(5, 0, 0))
+	prototype.SetTransform(primitives.Scaling(1, 2, 3))
+	prototype.AddShape(sphere)
+	equivalent := func(transform primitives.Matrix) shapes.Shape {
+		group := shapes.MakeGroup()
+		group.SetTransform(transform.Multiply(primitives.Scaling(1, 2, 3)))
+		copied := shapes.MakeSphere()
+		copied.SetTransform(primitives.Translation(5, 0, 0))
+		group.AddShape(copied)
+		return group
+	}
+	tables := []struct {
+		transform primitives.Matrix
+		r         primitives.Ray
+	}{
+		{primitives.Translation(0, 1, 0),
+			primitives.Ray{Origin: primitives.MakePoint(5.5, 1.5, -10), Direction: primitives.MakeVector(0, 0, 1)}},
+
+		{primitives.RotationY(math.Pi / 2),
+			primitives.Ray{Origin: primitives.MakePoint(1, 1, -20), Direction: primitives.MakeVector(0, 0, 1)}},
+
+		{primitives.Scaling(1, 0.5, 1).Multiply(primitives.RotationZ(math.Pi / 5)),
+			primitives.Ray{Origin: primitives.MakePoint(-5, 1.47, 0.5), Direction: primitives.MakeVector(1, 0, 0)}},
+	}
+	for index, table := range tables {
+		instance := shapes.MakeInstance(prototype)
+		instance.SetTransform(table.transform)
+		inner := shapes.MakeInstance(prototype)
+		inner.SetTransform(table.transform)
+		outer := shapes.MakeGroup()
+		outer.SetTransform(primitives.Translation(0, 0, 1))
+		outer.AddShape(inner)
+		expectedOuter := shapes.MakeGroup()
+		expectedOuter.SetTransform(primitives.Translation(0, 0, 1))
+		expectedOuter.AddShape(equivalent(table.transform))
+		for _, shapePair := range [][2]shapes.Shape{{instance, equivalent(table.transform)}, {outer, expectedOuter}} {
+			expectedHits := shapePair[1].Intersect(table.r)
+			sort.Sort(expectedHits)
+			expectedHit, expectedOk := expectedHits.Hit()
+			hits := shapePair[0].Intersect(table.r)
+			sort.Sort(hits)
+			hit, ok := hits.Hit()
+			if !ok || !expectedOk {
+				t.Errorf("Table %v expected a hit", index)
+				continue
+			}
+			expected := expectedHit.Obj.Normal(table.r.Position(expectedHit.Distance), expectedHit.U, expectedHit.V)
+			normal := hit.Obj.Normal(table.r.Position(hit.Distance), hit.U, hit.V)
+			if !normal.Equals(expected) {
+				t.Errorf("Table %v expected %v, got %v", index, expected, normal)
+			}
+		}
+	}
+}
+
+func TestInstanceObjectToWorldMatrix(t *testing.T) {
+	prototype := shapes.MakeGroup()
+	prototype.SetTransform(primitives.Scaling(1, 2, 3))
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Translation(5, 0, 0))
+	prototype.AddShape(sphere)
+	outer := shapes.MakeGroup()
+	outer.SetTransform(primitives.Translation(0, 0, 1))
+	for _, transform := range []primitives.Matrix{primitives.Translation(0, 1, 0), primitives.RotationY(math.Pi / 2)} {
+		instance := shapes.MakeInstance(prototype)
+		instance.SetTransform(transform)
+		outer.AddShape(instance)
+	}
+	shapes.Compile(outer)
+	tables := []struct {
+		r      primitives.Ray
+		result primitives.PV
+	}{
+		{primitives.Ray{Origin: primitives.MakePoint(5.5, 1.5, -10), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakePoint(6, 3, 4)},
+		{primitives.Ray{Origin: primitives.MakePoint(1, 1, -20), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakePoint(3, 2, -5)},
+	}
+	for index, table := range tables {
+		hits := outer.Intersect(table.r)
+		sort.Sort(hits)
+		hit, ok := hits.Hit()
+		if !ok {
+			t.Errorf("Table %v expected a hit", index)
+			continue
+		}
+		point := primitives.MakePoint(1, 1, 1).Transform(shapes.ObjectToWorldMatrix(hit.Obj))
+		if !point.Equals(table.result) {
+			t.Errorf("Table %v expected %v, got %v", index, table.result, point)
+		}
+	}
+}
+
+func TestInstanceMaterial(t *testing.T) {
+	prototype := shapes.MakeSphere()
+	prototypeMaterial := patterns.MakeDefaultMaterial()
+	prototypeMaterial.Ambient = 0.5
+	prototype.SetMaterial(prototypeMaterial)
+	overrideMaterial := patterns.MakeDefaultMaterial()
+	overrideMaterial.Ambient = 0.25
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)}
+
+	plain := shapes.MakeInstance(prototype)
+	if material := plain.Intersect(ray)[0].Obj.Material(); material.Ambient != 0.5 {
+		t.Errorf("Expected prototype material ambient 0.5, got %v", material.Ambient)
+	}
+	override := shapes.MakeInstance(prototype)
+	override.SetMaterial(overrideMaterial)
+	if material := override.Intersect(ray)[0].Obj.Material(); material.Ambient != 0.25 {
+		t.Errorf("Expected override material ambient 0.25, got %v", material.Ambient)
+	}
+	override.ClearMaterial()
+	if material := override.Intersect(ray)[0].Obj.Material(); material.Ambient != 0.5 {
+		t.Errorf("Expected prototype material ambient 0.5 after clearing, got %v", material.Ambient)
+	}
+	if prototype.Material().Ambient != 0.5 {
+		t.Errorf("Expected prototype material to be unchanged, got %v", prototype.Material().Ambient)
+	}
+}
+
+func TestInstanceUVMapping(t *testing.T) {
+	instance := shapes.MakeInstance(shapes.MakeSphere())
+	instance.SetTransform(primitives.Translation(0, 0, 10))
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 0, 1)}
+	hit, _ := instance.Intersect(ray).Hit()
+	expected := shapes.MakeSphere().UVMapping(primitives.MakePoint(0, 0, -1))
+	uv := hit.Obj.UVMapping(ray.Position(hit.Distance))
+	if !uv.Equals(expected) {
+		t.Errorf("Expected %v, got %v", expected, uv)
+	}
+}
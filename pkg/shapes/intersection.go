@@ -5,6 +5,7 @@ import (
 	"sort"
 
 	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
 )
 
 // Intersection Structure to hold intersection information
@@ -48,3 +49,79 @@ func (i Intersections) Hit() (Intersection, bool) {
 	}
 	return Intersection{}, false
 }
+
+// Closest Get the closest hit in front of the ray from intersections in any order
+func (i Intersections) Closest() (Intersection, bool) {
+	closest, found := Intersection{}, false
+	for _, v := range i {
+		if v.Distance >= 0 && (!found || v.Distance < closest.Distance) {
+			closest, found = v, true
+		}
+	}
+	return closest, found
+}
+
+// IntersectionAppender Shape that adds its hits to the end of a caller's buffer instead of allocating its own
+type IntersectionAppender interface {
+	AppendIntersections(r primitives.Ray, hits Intersections) Intersections
+}
+
+// AppendIntersections Add the hits of a shape to the end of hits, reusing its space when the shape supports it
+func AppendIntersections(shape Shape, r primitives.Ray, hits Intersections) Intersections {
+	return AppendIntersectionsCounted(shape, r, hits, nil)
+}
+
+// countedAppender Shape holding others that passes the counts of a ray on to them
+type countedAppender interface {
+	appendIntersectionsCounted(r primitives.Ray, hits Intersections, counts *stats.Counts) Intersections
+}
+
+// AppendIntersectionsCounted Add the hits of a shape to the end of hits like AppendIntersections, counting the
+// bounds and intersection tests made for the ray into counts when they are given
+func AppendIntersectionsCounted(shape Shape, r primitives.Ray, hits Intersections, counts *stats.Counts) Intersections {
+	countTests(shape, counts, 1)
+	if counter, ok := shape.(countedAppender); ok {
+		return counter.appendIntersectionsCounted(r, hits, counts)
+	}
+	if appender, ok := shape.(IntersectionAppender); ok {
+		return appender.AppendIntersections(r, hits)
+	}
+	return append(hits, shape.Intersect(r)...)
+}
+
+// Occluder Shape that answers any-hit queries without collecting and sorting every intersection
+type Occluder interface {
+	// IntersectAny Visit hits further than 0 and closer than maxDistance in any order, returning false
+	// as soon as visit does to stop the search
+	IntersectAny(r primitives.Ray, maxDistance float64, visit func(Intersection) bool) bool
+}
+
+// IntersectAny Visit the hits of a shape further than 0 and closer than maxDistance in any order, stopping
+// once visit returns false, shapes that aren't Occluders have all their hits collected first
+func IntersectAny(shape Shape, r primitives.Ray, maxDistance float64, visit func(Intersection) bool) bool {
+	return IntersectAnyCounted(shape, r, maxDistance, visit, nil)
+}
+
+// countedOccluder Occluder holding other shapes that passes the counts of a ray on to them
+type countedOccluder interface {
+	intersectAnyCounted(r primitives.Ray, maxDistance float64, visit func(Intersection) bool, counts *stats.Counts) bool
+}
+
+// IntersectAnyCounted Visit the hits of a shape like IntersectAny, counting the bounds and intersection tests made
+// for the ray into counts when they are given
+func IntersectAnyCounted(shape Shape, r primitives.Ray, maxDistance float64, visit func(Intersection) bool,
+	counts *stats.Counts) bool {
+	countTests(shape, counts, 1)
+	if counter, ok := shape.(countedOccluder); ok {
+		return counter.intersectAnyCounted(r, maxDistance, visit, counts)
+	}
+	if occluder, ok := shape.(Occluder); ok {
+		return occluder.IntersectAny(r, maxDistance, visit)
+	}
+	for _, hit := range shape.Intersect(r) {
+		if hit.Distance > 0 && hit.Distance < maxDistance && !visit(hit) {
+			return false
+		}
+	}
+	return true
+}
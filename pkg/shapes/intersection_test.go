@@ -1,7 +1,9 @@
 package shapes_test
 
 import (
+	"math"
 	"testing"
+	"github.com/factorion/graytracer/pkg/primitives"
 	"github.com/factorion/graytracer/pkg/shapes"
 )
 
@@ -31,3 +33,125 @@ func TestIntersectEquals(t *testing.T) {
 		}
 	}
 }
+
+func TestIntersectAny(t *testing.T) {
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Translation(0, 0, 5))
+	for _, z := range []float64{-3, 0, 3} {
+		sphere := shapes.MakeSphere()
+		sphere.SetTransform(primitives.Translation(0, 0, z))
+		group.AddShape(sphere)
+	}
+	instance := shapes.MakeInstance(group)
+	instance.SetTransform(primitives.Translation(0, 0, 1))
+	mesh := gridMesh(8)
+	mesh.SetTransform(primitives.Translation(-4, 0, -4).Multiply(primitives.RotationX(-math.Pi / 2)))
+	csg := shapes.MakeCSG(shapes.DIFFERENCE, shapes.MakeSphere(), shapes.MakeCube())
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.5, 0.5, 0), Direction: primitives.MakeVector(0, 0, 1)}
+	tables := []struct {
+		shape       shapes.Shape
+		maxDistance float64
+	}{
+		{group, 100},
+		{group, 5},
+		{instance, 6},
+		{mesh, 100},
+		{mesh, 3},
+		{csg, 100},
+	}
+	for index, table := range tables {
+		// Every hit in range must be visited, in any order
+		expected := []float64{}
+		for _, hit := range table.shape.Intersect(ray) {
+			if hit.Distance > 0 && hit.Distance < table.maxDistance {
+				expected = append(expected, hit.Distance)
+			}
+		}
+		visited := shapes.Intersections{}
+		if !shapes.IntersectAny(table.shape, ray, table.maxDistance, func(hit shapes.Intersection) bool {
+			visited = append(visited, hit)
+			return true
+		}) {
+			t.Errorf("Table %v, expected the search to finish", index)
+		}
+		if !shapes.IntersectEquals(visited, expected) {
+			t.Errorf("Table %v, expected %v, got %v", index, expected, visited)
+		}
+		// Stopping on the first hit must stop the whole search
+		if len(expected) > 0 {
+			count := 0
+			if shapes.IntersectAny(table.shape, ray, table.maxDistance, func(hit shapes.Intersection) bool {
+				count++
+				return false
+			}) || count != 1 {
+				t.Errorf("Table %v, expected the search to stop after one hit, got %v", index, count)
+			}
+		}
+	}
+}
+
+func TestAppendIntersections(t *testing.T) {
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Translation(0, 0, 5))
+	for _, z := range []float64{-3, 0, 3} {
+		sphere := shapes.MakeSphere()
+		sphere.SetTransform(primitives.Translation(0, 0, z))
+		group.AddShape(sphere)
+	}
+	instance := shapes.MakeInstance(group)
+	instance.SetTransform(primitives.Translation(0, 0, 1))
+	mesh := gridMesh(8)
+	mesh.SetTransform(primitives.Translation(-4, 0, -4).Multiply(primitives.RotationX(-math.Pi / 2)))
+	csg := shapes.MakeCSG(shapes.DIFFERENCE, shapes.MakeSphere(), shapes.MakeCube())
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.5, 0.5, 0), Direction: primitives.MakeVector(0, 0, 1)}
+	shapeList := []shapes.Shape{shapes.MakeSphere(), shapes.MakePlane(), shapes.MakeCube(), shapes.MakeCylinder(false),
+		shapes.MakeCone(false), group, instance, mesh, csg, shapes.MakeTorus(1, 0.25)}
+	for index, shape := range shapeList {
+		// Hits already in the buffer are kept ahead of the new ones
+		previous := shapes.Intersection{Distance: -7, Obj: shape}
+		buffer := make(shapes.Intersections, 1, 32)
+		buffer[0] = previous
+		hits := shapes.AppendIntersections(shape, ray, buffer)
+		if hits[0] != previous {
+			t.Errorf("Table %v, expected the first hit to stay %v, got %v", index, previous, hits[0])
+		}
+		expected := shape.Intersect(ray)
+		if len(hits) != len(expected)+1 {
+			t.Errorf("Table %v, expected %v, got %v", index, expected, hits[1:])
+			continue
+		}
+		for hit := range expected {
+			if hits[hit+1] != expected[hit] {
+				t.Errorf("Table %v, expected hit %v, got %v", index, expected[hit], hits[hit+1])
+			}
+		}
+		if &hits[0] != &buffer[0] {
+			t.Errorf("Table %v, expected the buffer to be reused", index)
+		}
+	}
+}
+
+func TestIntersectionsClosest(t *testing.T) {
+	sphere := shapes.MakeSphere()
+	tables := []struct {
+		distances []float64
+		closest   float64
+		hit       bool
+	}{
+		{[]float64{5, -1, 2, 7}, 2, true},
+		{[]float64{-3, -1}, 0, false},
+		{[]float64{}, 0, false},
+		{[]float64{0, 4}, 0, true},
+	}
+	for _, table := range tables {
+		hits := shapes.Intersections{}
+		for _, distance := range table.distances {
+			hits = append(hits, shapes.Intersection{Distance: distance, Obj: sphere})
+		}
+		closest, hit := hits.Closest()
+		if hit != table.hit || closest.Distance != table.closest {
+			t.Errorf("Hits %v, expected %v %v, got %v %v", table.distances, table.closest, table.hit,
+				closest.Distance, hit)
+		}
+	}
+}
@@ -0,0 +1,541 @@
+package shapes
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/factorion/graytracer/pkg/patterns"
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+// meshLeafSize Maximum number of triangles kept in a leaf of the mesh hierarchy
+const meshLeafSize = 4
+
+// meshParallelSize Fewest faces in a part of the hierarchy worth handing to another worker
+const meshParallelSize = 4096
+
+// VertexBuffer Vertex positions, normals and texture coordinates that can be shared between meshes
+type VertexBuffer struct {
+	Vertices, Normals, UVs []primitives.PV
+}
+
+// MeshFace Indices into a vertex buffer for the three corners of a triangle,
+// a negative normal or UV index means the face does not have them
+type MeshFace struct {
+	Vertices, Normals, UVs [3]int32
+}
+
+// meshNode Node of the flattened bounding volume hierarchy, the left child immediately
+// follows its parent and leaves reference a range of the face order
+type meshNode struct {
+	min, max     primitives.PV
+	start, count int32
+	right        int32
+}
+
+// Mesh Triangle mesh made of indexed faces into a vertex buffer
+type Mesh struct {
+	ShapeBase
+	buffer *VertexBuffer
+	faces  []MeshFace
+	order  []int32
+	nodes  []meshNode
+	// How long building the hierarchy took
+	buildTime time.Duration
+}
+
+// MakeMesh Make a mesh from faces indexing into the buffer, building its acceleration structure
+func MakeMesh(buffer *VertexBuffer, faces []MeshFace) *Mesh {
+	mesh := &Mesh{ShapeBase: MakeShapeBase(), buffer: buffer, faces: faces}
+	mesh.build()
+	return mesh
+}
+
+// MakeMeshWithWorkers Make a mesh like MakeMesh, building its acceleration structure with at most the given
+// number of goroutines. The structure is the same for any number of workers
+func MakeMeshWithWorkers(buffer *VertexBuffer, faces []MeshFace, workers int) *Mesh {
+	mesh := &Mesh{ShapeBase: MakeShapeBase(), buffer: buffer, faces: faces}
+	mesh.buildWith(workers)
+	return mesh
+}
+
+// Buffer Get the vertex buffer used by the mesh
+func (m *Mesh) Buffer() *VertexBuffer {
+	return m.buffer
+}
+
+// Len Get the number of triangles in the mesh, a nil mesh has none
+func (m *Mesh) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.faces)
+}
+
+// Face Get the triangle at the given index, in the order the faces were given
+func (m *Mesh) Face(index int) MeshTriangle {
+	return MeshTriangle{m, int32(index)}
+}
+
+// build Construct the bounding volume hierarchy over the faces with a worker for each CPU
+func (m *Mesh) build() {
+	m.buildWith(runtime.GOMAXPROCS(0))
+}
+
+// buildWith Construct the bounding volume hierarchy over the faces, splitting the work between workers.
+// Every subtree is written to a place known from its number of faces, so any number of workers builds the same hierarchy
+func (m *Mesh) buildWith(workers int) {
+	start := time.Now()
+	builder := &meshBuilder{mesh: m, centroids: make([]primitives.PV, len(m.faces))}
+	if workers > 1 {
+		builder.workers = make(chan struct{}, workers-1)
+	}
+	m.order = make([]int32, len(m.faces))
+	parallelFor(len(m.faces), workers, func(start, end int) {
+		for index := start; index < end; index++ {
+			face := m.faces[index]
+			m.order[index] = int32(index)
+			p1 := m.buffer.Vertices[face.Vertices[0]]
+			p2 := m.buffer.Vertices[face.Vertices[1]]
+			p3 := m.buffer.Vertices[face.Vertices[2]]
+			builder.centroids[index] = primitives.MakePoint((p1.X+p2.X+p3.X)/3, (p1.Y+p2.Y+p3.Y)/3, (p1.Z+p2.Z+p3.Z)/3)
+		}
+	})
+	m.nodes = make([]meshNode, meshNodeCount(int32(len(m.faces))))
+	if len(m.faces) > 0 {
+		builder.buildNode(0, 0, int32(len(m.faces)))
+	}
+	m.buildTime = time.Since(start)
+}
+
+// BuildTime Get how long building the hierarchy of the mesh took, which is zero when it was loaded from a cache
+func (m *Mesh) BuildTime() time.Duration {
+	return m.buildTime
+}
+
+// meshNodeCount Number of nodes in the hierarchy over a number of faces, which always splits them in half
+func meshNodeCount(faces int32) int {
+	if faces == 0 {
+		return 0
+	}
+	if faces <= meshLeafSize {
+		return 1
+	}
+	return 1 + meshNodeCount(faces/2) + meshNodeCount(faces-(faces/2))
+}
+
+// meshBuilder Shared state for building the hierarchy of a mesh
+type meshBuilder struct {
+	mesh      *Mesh
+	centroids []primitives.PV
+	// Tokens for the goroutines that may run alongside the one that started the build
+	workers chan struct{}
+}
+
+// both Run two independent parts of the build, at the same time when there is a spare worker and the part is large
+func (b *meshBuilder) both(large bool, first, second func()) {
+	if large {
+		select {
+		case b.workers <- struct{}{}:
+			done := make(chan struct{})
+			go func() {
+				first()
+				<-b.workers
+				close(done)
+			}()
+			second()
+			<-done
+			return
+		default:
+		}
+	}
+	first()
+	second()
+}
+
+// bounds Find the bounds of the faces and of their centroids, halving large ranges between workers
+func (b *meshBuilder) bounds(faces []int32) (min, max, centerMin, centerMax primitives.PV) {
+	if len(faces) >= 2*meshParallelSize {
+		var lower, upper [4]primitives.PV
+		half := len(faces) / 2
+		b.both(true, func() {
+			lower[0], lower[1], lower[2], lower[3] = b.bounds(faces[:half])
+		}, func() {
+			upper[0], upper[1], upper[2], upper[3] = b.bounds(faces[half:])
+		})
+		return minPV(lower[0], upper[0]), maxPV(lower[1], upper[1]), minPV(lower[2], upper[2]), maxPV(lower[3], upper[3])
+	}
+	min = primitives.MakePoint(math.Inf(1), math.Inf(1), math.Inf(1))
+	max = primitives.MakePoint(math.Inf(-1), math.Inf(-1), math.Inf(-1))
+	centerMin, centerMax = min, max
+	for _, face := range faces {
+		for _, vertex := range b.mesh.faces[face].Vertices {
+			point := b.mesh.buffer.Vertices[vertex]
+			min, max = minPV(min, point), maxPV(max, point)
+		}
+		centerMin, centerMax = minPV(centerMin, b.centroids[face]), maxPV(centerMax, b.centroids[face])
+	}
+	return min, max, centerMin, centerMax
+}
+
+// sortFaces Stable sort of the faces by one coordinate of their centroids, large ranges are sorted in halves
+// by separate workers and then merged, which keeps the order any stable sort would give
+func (b *meshBuilder) sortFaces(faces []int32, key func(int32) float64) {
+	if len(faces) < 2*meshParallelSize {
+		sort.SliceStable(faces, func(i, j int) bool { return key(faces[i]) < key(faces[j]) })
+		return
+	}
+	half := len(faces) / 2
+	b.both(true, func() { b.sortFaces(faces[:half], key) }, func() { b.sortFaces(faces[half:], key) })
+	merged := make([]int32, 0, len(faces))
+	left, right := faces[:half], faces[half:]
+	for len(left) > 0 && len(right) > 0 {
+		// Equal keys take the face from the left half first to stay stable
+		if key(right[0]) < key(left[0]) {
+			merged, right = append(merged, right[0]), right[1:]
+		} else {
+			merged, left = append(merged, left[0]), left[1:]
+		}
+	}
+	merged = append(append(merged, left...), right...)
+	copy(faces, merged)
+}
+
+// buildNode Fill in the node at index covering the faces from start to end, splitting at the median centroid
+// of the longest axis. The left child follows the node and the right child follows the whole left subtree
+func (b *meshBuilder) buildNode(index int, start, end int32) {
+	m := b.mesh
+	faces := m.order[start:end]
+	min, max, centerMin, centerMax := b.bounds(faces)
+	node := meshNode{min: min, max: max}
+	if end-start <= meshLeafSize {
+		node.start = start
+		node.count = end - start
+		m.nodes[index] = node
+		return
+	}
+	extent := centerMax.Subtract(centerMin)
+	key := func(face int32) float64 { return b.centroids[face].X }
+	if (extent.Y > extent.X) && (extent.Y >= extent.Z) {
+		key = func(face int32) float64 { return b.centroids[face].Y }
+	} else if (extent.Z > extent.X) && (extent.Z > extent.Y) {
+		key = func(face int32) float64 { return b.centroids[face].Z }
+	}
+	b.sortFaces(faces, key)
+	middle := start + ((end - start) / 2)
+	right := index + 1 + meshNodeCount(middle-start)
+	node.right = int32(right)
+	m.nodes[index] = node
+	b.both(end-start >= meshParallelSize, func() { b.buildNode(index+1, start, middle) },
+		func() { b.buildNode(right, middle, end) })
+}
+
+// minPV Point with the smallest of each coordinate
+func minPV(a, b primitives.PV) primitives.PV {
+	return primitives.MakePoint(math.Min(a.X, b.X), math.Min(a.Y, b.Y), math.Min(a.Z, b.Z))
+}
+
+// maxPV Point with the largest of each coordinate
+func maxPV(a, b primitives.PV) primitives.PV {
+	return primitives.MakePoint(math.Max(a.X, b.X), math.Max(a.Y, b.Y), math.Max(a.Z, b.Z))
+}
+
+// bakedBuffer Copy of a vertex buffer moved by a transform while flattening
+type bakedBuffer struct {
+	transform primitives.Matrix
+	buffer    *VertexBuffer
+}
+
+// bake Move the vertices and normals of the mesh by the transform and rebuild its hierarchy, leaving the mesh
+// with an identity matrix. The buffer may be shared with other meshes so it is copied, but only once for each
+// transform, meshes sharing a buffer under the same transform keep sharing the copy
+func (m *Mesh) bake(transform primitives.Matrix, baked map[*VertexBuffer][]bakedBuffer) {
+	identity := primitives.MakeIdentityMatrix(4)
+	if transform.Equals(identity) {
+		m.ShapeBase.SetTransform(identity)
+		return
+	}
+	original := m.buffer
+	m.buffer = nil
+	for _, copied := range baked[original] {
+		if copied.transform.Equals(transform) {
+			m.buffer = copied.buffer
+			break
+		}
+	}
+	if m.buffer == nil {
+		m.buffer = bakeBuffer(original, transform)
+		baked[original] = append(baked[original], bakedBuffer{transform, m.buffer})
+	}
+	m.build()
+	m.ShapeBase.SetTransform(identity)
+}
+
+// bakeBuffer Copy of the buffer with its vertices and normals moved by the transform
+func bakeBuffer(original *VertexBuffer, transform primitives.Matrix) *VertexBuffer {
+	inverse, _ := transform.Inverse()
+	normalMatrix := inverse.Transpose()
+	buffer := &VertexBuffer{Vertices: make([]primitives.PV, len(original.Vertices)),
+		Normals: make([]primitives.PV, len(original.Normals)), UVs: original.UVs}
+	for index, vertex := range original.Vertices {
+		buffer.Vertices[index] = vertex.Transform(transform)
+	}
+	// Normals are left unnormalized so interpolating them matches interpolating before the transform
+	for index, normal := range original.Normals {
+		normal = normal.Transform(normalMatrix)
+		normal.W = 0
+		buffer.Normals[index] = normal
+	}
+	return buffer
+}
+
+// GetBounds Return an axis aligned bounding box for the mesh
+func (m *Mesh) GetBounds() *Bounds {
+	if len(m.nodes) == 0 {
+		return nil
+	}
+	bounds := &Bounds{Min: m.nodes[0].min, Max: m.nodes[0].max}
+	return bounds.Transform(m.transform)
+}
+
+// Intersect Check if a ray intersects
+func (m *Mesh) Intersect(r primitives.Ray) Intersections {
+	return m.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the faces hit by the ray to the end of hits, reusing its space
+func (m *Mesh) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
+	return m.appendIntersectionsCounted(r, hits, nil)
+}
+
+// appendIntersectionsCounted Add the faces hit by the ray like AppendIntersections, counting the tests
+func (m *Mesh) appendIntersectionsCounted(r primitives.Ray, hits Intersections, counts *stats.Counts) Intersections {
+	if len(m.nodes) == 0 {
+		return hits
+	}
+	// convert ray to object space
+	oray := r.Transform(m.inverse)
+	stack := make([]int32, 1, 64)
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := &m.nodes[index]
+		bounds := Bounds{Min: node.min, Max: node.max}
+		if !bounds.intersectCounted(oray, counts) {
+			continue
+		}
+		if node.count > 0 {
+			for _, face := range m.order[node.start : node.start+node.count] {
+				if distance, u, v, hit := m.intersectFace(face, oray, counts); hit {
+					hits = append(hits, Intersection{Distance: distance, Obj: MeshTriangle{m, face}, U: u, V: v})
+				}
+			}
+			continue
+		}
+		stack = append(stack, node.right, index+1)
+	}
+	return hits
+}
+
+// IntersectAny Visit the faces hit by the ray, skipping nodes out of range and stopping once visit returns false
+func (m *Mesh) IntersectAny(r primitives.Ray, maxDistance float64, visit func(Intersection) bool) bool {
+	return m.intersectAnyCounted(r, maxDistance, visit, nil)
+}
+
+// intersectAnyCounted Visit the faces hit by the ray like IntersectAny, counting the tests
+func (m *Mesh) intersectAnyCounted(r primitives.Ray, maxDistance float64, visit func(Intersection) bool,
+	counts *stats.Counts) bool {
+	if len(m.nodes) == 0 {
+		return true
+	}
+	// convert ray to object space
+	oray := r.Transform(m.inverse)
+	stack := make([]int32, 1, 64)
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := &m.nodes[index]
+		bounds := Bounds{Min: node.min, Max: node.max}
+		if tmin, tmax, ok := bounds.clipCounted(oray, counts); !ok || tmax <= 0 || tmin >= maxDistance {
+			continue
+		}
+		if node.count > 0 {
+			for _, face := range m.order[node.start : node.start+node.count] {
+				distance, u, v, hit := m.intersectFace(face, oray, counts)
+				if hit && distance > 0 && distance < maxDistance &&
+					!visit(Intersection{Distance: distance, Obj: MeshTriangle{m, face}, U: u, V: v}) {
+					return false
+				}
+			}
+			continue
+		}
+		stack = append(stack, node.right, index+1)
+	}
+	return true
+}
+
+// intersectFace Check if an object-space ray intersects a single face, counting the test into counts
+func (m *Mesh) intersectFace(face int32, oray primitives.Ray, counts *stats.Counts) (float64, float64, float64, bool) {
+	counts.AddShapeTests(meshTriangleTests, 1)
+	indices := m.faces[face].Vertices
+	return intersectTriangle(m.buffer.Vertices[indices[0]], m.buffer.Vertices[indices[1]],
+		m.buffer.Vertices[indices[2]], oray)
+}
+
+// intersectTriangle Check if an object-space ray intersects a triangle, returning the distance and
+// the barycentric coordinates of the hit
+func intersectTriangle(point1, point2, point3 primitives.PV, oray primitives.Ray) (float64, float64, float64, bool) {
+	edge1 := point2.Subtract(point1)
+	edge2 := point3.Subtract(point1)
+	dce2 := oray.Direction.CrossProduct(edge2) // Direction crossed with edge 2
+	det := edge1.DotProduct(dce2)
+	if math.Abs(det) < primitives.EPSILON {
+		return 0, 0, 0, false
+	}
+	f := 1.0 / det
+	p1too := oray.Origin.Subtract(point1) // origin to point 1
+	u := f * p1too.DotProduct(dce2)
+	if (u < 0) || (u > 1) {
+		return 0, 0, 0, false
+	}
+	oce1 := p1too.CrossProduct(edge1) // Cross product of origin and edge
+	v := f * oray.Direction.DotProduct(oce1)
+	if (v < 0) || ((u + v) > 1) {
+		return 0, 0, 0, false
+	}
+	return f * edge2.DotProduct(oce1), u, v, true
+}
+
+// Normal Calculate the normal at a given point on the mesh
+func (m *Mesh) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	// Only exists for Interface, intersections refer to the individual triangles
+	return primitives.MakeVector(0, 1, 0)
+}
+
+// UVMapping Return the 2D coordinates of an intersection point
+func (m *Mesh) UVMapping(point primitives.PV) primitives.PV {
+	// Only exists for Interface, intersections refer to the individual triangles
+	return primitives.MakePoint(point.X, point.Y, 0)
+}
+
+// MeshTriangle Handle to a single face of a mesh, sharing the transform, material and parent of the mesh
+type MeshTriangle struct {
+	mesh  *Mesh
+	index int32
+}
+
+// Mesh Get the mesh the triangle belongs to
+func (mt MeshTriangle) Mesh() *Mesh {
+	return mt.mesh
+}
+
+// Points Get the three corners of the triangle
+func (mt MeshTriangle) Points() [3]primitives.PV {
+	indices := mt.mesh.faces[mt.index].Vertices
+	vertices := mt.mesh.buffer.Vertices
+	return [3]primitives.PV{vertices[indices[0]], vertices[indices[1]], vertices[indices[2]]}
+}
+
+// VertexNormals Get the normals at the three corners, and whether the triangle has them
+func (mt MeshTriangle) VertexNormals() ([3]primitives.PV, bool) {
+	indices := mt.mesh.faces[mt.index].Normals
+	if indices[0] < 0 {
+		return [3]primitives.PV{}, false
+	}
+	normals := mt.mesh.buffer.Normals
+	return [3]primitives.PV{normals[indices[0]], normals[indices[1]], normals[indices[2]]}, true
+}
+
+// Intersect Check if a ray intersects the single triangle
+func (mt MeshTriangle) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	if distance, u, v, hit := mt.mesh.intersectFace(mt.index, r.Transform(mt.mesh.inverse), nil); hit {
+		hits = append(hits, Intersection{Distance: distance, Obj: mt, U: u, V: v})
+	}
+	return hits
+}
+
+// Normal Calculate the normal at a given point on the triangle, interpolated when it has vertex normals
+func (mt MeshTriangle) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	var normal primitives.PV
+	if normals, smooth := mt.VertexNormals(); smooth {
+		normal = normals[1].Scalar(u).Add(normals[2].Scalar(v)).Add(normals[0].Scalar(1 - u - v))
+	} else {
+		points := mt.Points()
+		normal = points[2].Subtract(points[0]).CrossProduct(points[1].Subtract(points[0]))
+	}
+	worldNormal := mt.mesh.ObjectToWorldPV(normal)
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the texture coordinates of a point on the triangle, or its X and Y without them
+func (mt MeshTriangle) UVMapping(point primitives.PV) primitives.PV {
+	indices := mt.mesh.faces[mt.index].UVs
+	if indices[0] < 0 {
+		return primitives.MakePoint(point.X, point.Y, 0)
+	}
+	// Barycentric coordinates of the object-space point
+	points := mt.Points()
+	edge1 := points[1].Subtract(points[0])
+	edge2 := points[2].Subtract(points[0])
+	offset := mt.mesh.WorldToObjectPV(point).Subtract(points[0])
+	d11 := edge1.DotProduct(edge1)
+	d12 := edge1.DotProduct(edge2)
+	d22 := edge2.DotProduct(edge2)
+	d1 := offset.DotProduct(edge1)
+	d2 := offset.DotProduct(edge2)
+	denominator := (d11 * d22) - (d12 * d12)
+	u := ((d22 * d1) - (d12 * d2)) / denominator
+	v := ((d11 * d2) - (d12 * d1)) / denominator
+	uvs := mt.mesh.buffer.UVs
+	uv := uvs[indices[0]].Scalar(1 - u - v).Add(uvs[indices[1]].Scalar(u)).Add(uvs[indices[2]].Scalar(v))
+	return primitives.MakePoint(uv.X, uv.Y, 0)
+}
+
+// GetBounds Return an axis aligned bounding box for the triangle
+func (mt MeshTriangle) GetBounds() *Bounds {
+	points := mt.Points()
+	x_min, x_max := MinMax([]float64{points[0].X, points[1].X, points[2].X})
+	y_min, y_max := MinMax([]float64{points[0].Y, points[1].Y, points[2].Y})
+	z_min, z_max := MinMax([]float64{points[0].Z, points[1].Z, points[2].Z})
+	bounds := &Bounds{Min: primitives.MakePoint(x_min, y_min, z_min), Max: primitives.MakePoint(x_max, y_max, z_max)}
+	return bounds.Transform(mt.mesh.transform)
+}
+
+// SetTransform Does nothing, the triangle uses the transform of its mesh
+func (mt MeshTriangle) SetTransform(m primitives.Matrix) {}
+
+// Transform Get the identity matrix, the vertices are already in the object-space of the mesh which is the parent
+func (mt MeshTriangle) Transform() primitives.Matrix {
+	return primitives.MakeIdentityMatrix(4)
+}
+
+// SetMaterial Does nothing, the triangle uses the material of its mesh
+func (mt MeshTriangle) SetMaterial(mat patterns.Material) {}
+
+// Material Get the material of the mesh
+func (mt MeshTriangle) Material() patterns.Material {
+	return mt.mesh.Material()
+}
+
+// SetParent Does nothing, the parent of the triangle is always its mesh
+func (mt MeshTriangle) SetParent(parent Shape) {}
+
+// Parent Get the mesh the triangle belongs to
+func (mt MeshTriangle) Parent() Shape {
+	return mt.mesh
+}
+
+// WorldToObjectPV Convert a Point/Vector from world to the object-space of the mesh
+func (mt MeshTriangle) WorldToObjectPV(pv primitives.PV) primitives.PV {
+	return mt.mesh.WorldToObjectPV(pv)
+}
+
+// ObjectToWorldPV Convert a Point/Vector from the object-space of the mesh to world-space
+func (mt MeshTriangle) ObjectToWorldPV(pv primitives.PV) primitives.PV {
+	return mt.mesh.ObjectToWorldPV(pv)
+}
@@ -0,0 +1,252 @@
+package shapes
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// MeshCacheVersion Version of the binary mesh layout, to be raised whenever the layout or the way the
+// hierarchy is built changes so older caches are rebuilt instead of loaded
+const MeshCacheVersion uint32 = 1
+
+// meshCacheChunk Most values allocated up front for a length read from a cache
+const meshCacheChunk = 1 << 16
+
+// meshCacheMagic Marks the start of meshes written by WriteMeshes
+var meshCacheMagic = [4]byte{'G', 'T', 'M', 'S'}
+
+// ErrStaleMeshCache Returned when the meshes were written by another version or with other build settings
+var ErrStaleMeshCache = errors.New("mesh cache was written by another version")
+
+// meshWriter Little endian writer that keeps the first error so writes can be chained
+type meshWriter struct {
+	w       *bufio.Writer
+	scratch [8]byte
+	err     error
+}
+
+func (mw *meshWriter) write(data []byte) {
+	if mw.err == nil {
+		_, mw.err = mw.w.Write(data)
+	}
+}
+
+func (mw *meshWriter) uint32(value uint32) {
+	binary.LittleEndian.PutUint32(mw.scratch[:4], value)
+	mw.write(mw.scratch[:4])
+}
+
+func (mw *meshWriter) int32(value int32) {
+	mw.uint32(uint32(value))
+}
+
+func (mw *meshWriter) pv(value primitives.PV) {
+	for _, component := range [4]float64{value.X, value.Y, value.Z, value.W} {
+		binary.LittleEndian.PutUint64(mw.scratch[:], math.Float64bits(component))
+		mw.write(mw.scratch[:])
+	}
+}
+
+func (mw *meshWriter) pvs(values []primitives.PV) {
+	mw.uint32(uint32(len(values)))
+	for _, value := range values {
+		mw.pv(value)
+	}
+}
+
+// meshReader Little endian reader that keeps the first error so reads can be chained
+type meshReader struct {
+	r       *bufio.Reader
+	scratch [8]byte
+	err     error
+}
+
+func (mr *meshReader) read(data []byte) {
+	if mr.err == nil {
+		_, mr.err = io.ReadFull(mr.r, data)
+	}
+}
+
+func (mr *meshReader) uint32() uint32 {
+	mr.read(mr.scratch[:4])
+	if mr.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(mr.scratch[:4])
+}
+
+func (mr *meshReader) int32() int32 {
+	return int32(mr.uint32())
+}
+
+// count Read a length, which must be below limit so a corrupt file can't ask for huge allocations
+func (mr *meshReader) count(limit uint32) int {
+	value := mr.uint32()
+	if mr.err == nil && value > limit {
+		mr.err = fmt.Errorf("mesh cache length %d is larger than %d", value, limit)
+		return 0
+	}
+	return int(value)
+}
+
+func (mr *meshReader) pv() primitives.PV {
+	var components [4]float64
+	for index := range components {
+		mr.read(mr.scratch[:])
+		if mr.err != nil {
+			return primitives.PV{}
+		}
+		components[index] = math.Float64frombits(binary.LittleEndian.Uint64(mr.scratch[:]))
+	}
+	return primitives.PV{X: components[0], Y: components[1], Z: components[2], W: components[3]}
+}
+
+func (mr *meshReader) pvs() []primitives.PV {
+	count := mr.count(math.MaxInt32)
+	// Grow while reading rather than trusting the length of a file that may be cut short
+	values := make([]primitives.PV, 0, minInt(count, meshCacheChunk))
+	for index := 0; index < count && mr.err == nil; index++ {
+		values = append(values, mr.pv())
+	}
+	return values
+}
+
+// WriteMeshes Write the vertex buffer and the faces and built hierarchies of meshes using it,
+// so ReadMeshes can load them without parsing or building again
+func WriteMeshes(w io.Writer, buffer *VertexBuffer, meshes []*Mesh) error {
+	mw := &meshWriter{w: bufio.NewWriter(w)}
+	mw.write(meshCacheMagic[:])
+	mw.uint32(MeshCacheVersion)
+	mw.uint32(meshLeafSize)
+	mw.pvs(buffer.Vertices)
+	mw.pvs(buffer.Normals)
+	mw.pvs(buffer.UVs)
+	mw.uint32(uint32(len(meshes)))
+	for _, mesh := range meshes {
+		if mesh.buffer != buffer {
+			return errors.New("meshes must all use the vertex buffer being written")
+		}
+		mw.uint32(uint32(len(mesh.faces)))
+		for _, face := range mesh.faces {
+			for _, indices := range [3][3]int32{face.Vertices, face.Normals, face.UVs} {
+				for _, index := range indices {
+					mw.int32(index)
+				}
+			}
+		}
+		for _, face := range mesh.order {
+			mw.int32(face)
+		}
+		mw.uint32(uint32(len(mesh.nodes)))
+		for _, node := range mesh.nodes {
+			mw.pv(node.min)
+			mw.pv(node.max)
+			mw.int32(node.start)
+			mw.int32(node.count)
+			mw.int32(node.right)
+		}
+	}
+	if mw.err != nil {
+		return mw.err
+	}
+	return mw.w.Flush()
+}
+
+// ReadMeshes Read meshes written by WriteMeshes, returning ErrStaleMeshCache when they were written
+// by another version or leaf size and an error when the data doesn't describe valid meshes
+func ReadMeshes(r io.Reader) (*VertexBuffer, []*Mesh, error) {
+	mr := &meshReader{r: bufio.NewReader(r)}
+	var magic [4]byte
+	mr.read(magic[:])
+	version := mr.uint32()
+	leafSize := mr.uint32()
+	if mr.err != nil {
+		return nil, nil, mr.err
+	}
+	if magic != meshCacheMagic || version != MeshCacheVersion || leafSize != meshLeafSize {
+		return nil, nil, ErrStaleMeshCache
+	}
+	buffer := &VertexBuffer{Vertices: mr.pvs(), Normals: mr.pvs(), UVs: mr.pvs()}
+	meshCount := mr.count(math.MaxInt32)
+	meshes := make([]*Mesh, 0, minInt(meshCount, meshCacheChunk))
+	for len(meshes) < meshCount && mr.err == nil {
+		mesh := &Mesh{ShapeBase: MakeShapeBase(), buffer: buffer}
+		count := mr.count(math.MaxInt32)
+		mesh.faces = make([]MeshFace, 0, minInt(count, meshCacheChunk))
+		for face := 0; face < count && mr.err == nil; face++ {
+			var read MeshFace
+			for _, indices := range []*[3]int32{&read.Vertices, &read.Normals, &read.UVs} {
+				for corner := range indices {
+					indices[corner] = mr.int32()
+				}
+			}
+			mesh.faces = append(mesh.faces, read)
+		}
+		mesh.order = make([]int32, len(mesh.faces))
+		for face := range mesh.order {
+			mesh.order[face] = mr.int32()
+		}
+		mesh.nodes = make([]meshNode, mr.count(uint32(2*len(mesh.faces))))
+		for node := range mesh.nodes {
+			mesh.nodes[node] = meshNode{min: mr.pv(), max: mr.pv(), start: mr.int32(), count: mr.int32(),
+				right: mr.int32()}
+		}
+		if mr.err != nil {
+			return nil, nil, mr.err
+		}
+		if err := mesh.validate(); err != nil {
+			return nil, nil, err
+		}
+		meshes = append(meshes, mesh)
+	}
+	if mr.err != nil {
+		return nil, nil, mr.err
+	}
+	return buffer, meshes, nil
+}
+
+// validate Check that every index of a loaded mesh is in range so a corrupt cache can't panic later
+func (m *Mesh) validate() error {
+	inRange := func(index int32, length int) bool {
+		return index >= 0 && int(index) < length
+	}
+	// Normals and UVs are either missing from all three corners or index the buffer at all three
+	optional := func(indices [3]int32, length int) bool {
+		return (indices == [3]int32{-1, -1, -1}) ||
+			(inRange(indices[0], length) && inRange(indices[1], length) && inRange(indices[2], length))
+	}
+	for index, face := range m.faces {
+		if !inRange(face.Vertices[0], len(m.buffer.Vertices)) || !inRange(face.Vertices[1], len(m.buffer.Vertices)) ||
+			!inRange(face.Vertices[2], len(m.buffer.Vertices)) || !optional(face.Normals, len(m.buffer.Normals)) ||
+			!optional(face.UVs, len(m.buffer.UVs)) {
+			return fmt.Errorf("mesh cache face %d indexes outside the vertex buffer", index)
+		}
+	}
+	seen := make([]bool, len(m.faces))
+	for _, face := range m.order {
+		if !inRange(face, len(m.faces)) || seen[face] {
+			return errors.New("mesh cache face order is not a permutation of the faces")
+		}
+		seen[face] = true
+	}
+	if len(m.faces) > 0 && len(m.nodes) == 0 {
+		return errors.New("mesh cache is missing its hierarchy")
+	}
+	for index, node := range m.nodes {
+		if node.count > 0 {
+			if node.start < 0 || int(node.start)+int(node.count) > len(m.faces) {
+				return fmt.Errorf("mesh cache node %d covers faces outside the mesh", index)
+			}
+		} else if node.count < 0 || index+1 >= len(m.nodes) || node.right <= int32(index) ||
+			int(node.right) >= len(m.nodes) {
+			return fmt.Errorf("mesh cache node %d has children outside the hierarchy", index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package shapes_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func TestMeshCache(t *testing.T) {
+	grid := gridMesh(16)
+	buffer, faces := squareBuffer()
+	square := shapes.MakeMesh(buffer, faces)
+	tables := []struct {
+		buffer *shapes.VertexBuffer
+		meshes []*shapes.Mesh
+	}{
+		{grid.Buffer(), []*shapes.Mesh{grid}},
+		{buffer, []*shapes.Mesh{square, shapes.MakeMesh(buffer, faces[:1])}},
+		{buffer, []*shapes.Mesh{}},
+	}
+	for index, table := range tables {
+		var data bytes.Buffer
+		if err := shapes.WriteMeshes(&data, table.buffer, table.meshes); err != nil {
+			t.Fatalf("Table %v, unexpected error %v", index, err)
+		}
+		buffer, meshes, err := shapes.ReadMeshes(&data)
+		if err != nil {
+			t.Fatalf("Table %v, unexpected error %v", index, err)
+		}
+		if len(buffer.Vertices) != len(table.buffer.Vertices) || len(buffer.Normals) != len(table.buffer.Normals) ||
+			len(buffer.UVs) != len(table.buffer.UVs) || len(meshes) != len(table.meshes) {
+			t.Errorf("Table %v, expected %v meshes on the same buffer, got %v", index, len(table.meshes), len(meshes))
+			continue
+		}
+		for mesh, expected := range table.meshes {
+			if meshes[mesh].Buffer() != buffer || meshes[mesh].Len() != expected.Len() {
+				t.Errorf("Table %v, expected mesh %v with %v faces on the shared buffer", index, mesh, expected.Len())
+				continue
+			}
+			if bounds, expectedBounds := meshes[mesh].GetBounds(), expected.GetBounds(); !bounds.Min.Equals(
+				expectedBounds.Min) || !bounds.Max.Equals(expectedBounds.Max) {
+				t.Errorf("Table %v, expected bounds %v, got %v", index, expectedBounds, bounds)
+			}
+			for face := 0; face < expected.Len(); face++ {
+				normals, smooth := meshes[mesh].Face(face).VertexNormals()
+				expectedNormals, expectedSmooth := expected.Face(face).VertexNormals()
+				if meshes[mesh].Face(face).Points() != expected.Face(face).Points() || normals != expectedNormals ||
+					smooth != expectedSmooth {
+					t.Errorf("Table %v, expected face %v to match", index, face)
+				}
+			}
+			for x := 0.05; x < 16; x += 0.9 {
+				ray := primitives.Ray{Origin: primitives.MakePoint(x, 3, x/2), Direction: primitives.MakeVector(0.1, -1, 0.3)}
+				hits, expectedHits := meshes[mesh].Intersect(ray), expected.Intersect(ray)
+				if len(hits) != len(expectedHits) {
+					t.Errorf("Table %v, ray %v, expected %v, got %v", index, ray, expectedHits, hits)
+					continue
+				}
+				for hit := range hits {
+					if hits[hit].Distance != expectedHits[hit].Distance || hits[hit].U != expectedHits[hit].U ||
+						hits[hit].V != expectedHits[hit].V {
+						t.Errorf("Table %v, ray %v, expected %v, got %v", index, ray, expectedHits[hit], hits[hit])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMeshCacheErrors(t *testing.T) {
+	mesh := gridMesh(4)
+	if err := shapes.WriteMeshes(&bytes.Buffer{}, &shapes.VertexBuffer{}, []*shapes.Mesh{mesh}); err == nil {
+		t.Error("Expected an error writing a mesh with another buffer")
+	}
+	var data bytes.Buffer
+	if err := shapes.WriteMeshes(&data, mesh.Buffer(), []*shapes.Mesh{mesh}); err != nil {
+		t.Fatal(err)
+	}
+	valid := data.Bytes()
+	// The first vertex index of the first face comes after the header, the buffer and the counts
+	firstFace := 12 + 4 + (len(mesh.Buffer().Vertices) * 32) + 4 + 4 + 4 + 4
+	tables := []struct {
+		name   string
+		change func([]byte) []byte
+		stale  bool
+	}{
+		{"Other version", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[4:], shapes.MeshCacheVersion+1)
+			return data
+		}, true},
+		{"Other leaf size", func(data []byte) []byte {
+			data[8]++
+			return data
+		}, true},
+		{"Not a cache", func(data []byte) []byte { return []byte("v 1 2 3\nv 4 5 6\n") }, true},
+		{"Empty", func(data []byte) []byte { return data[:0] }, false},
+		{"Truncated", func(data []byte) []byte { return data[:len(data)-10] }, false},
+		{"Missing vertex", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[firstFace:], 1000)
+			return data
+		}, false},
+		{"Huge length", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[12:], 0xFFFFFFFF)
+			return data
+		}, false},
+	}
+	for _, table := range tables {
+		changed := table.change(append([]byte{}, valid...))
+		_, _, err := shapes.ReadMeshes(bytes.NewReader(changed))
+		if err == nil {
+			t.Errorf("%v, expected an error", table.name)
+			continue
+		}
+		if errors.Is(err, shapes.ErrStaleMeshCache) != table.stale {
+			t.Errorf("%v, expected a stale cache %v, got %v", table.name, table.stale, err)
+		}
+	}
+}
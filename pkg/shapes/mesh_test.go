@@ -0,0 +1,316 @@
+package shapes_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// squareBuffer Unit square in the XY plane made of two triangles with normals and texture coordinates
+func squareBuffer() (*shapes.VertexBuffer, []shapes.MeshFace) {
+	buffer := &shapes.VertexBuffer{
+		Vertices: []primitives.PV{primitives.MakePoint(0, 0, 0), primitives.MakePoint(1, 0, 0),
+			primitives.MakePoint(1, 1, 0), primitives.MakePoint(0, 1, 0)},
+		Normals: []primitives.PV{primitives.MakeVector(0, 0, -1), primitives.MakeVector(-1, 0, -1).Normalize()},
+		UVs: []primitives.PV{primitives.MakePoint(0, 0, 0), primitives.MakePoint(1, 0, 0),
+			primitives.MakePoint(1, 1, 0), primitives.MakePoint(0, 1, 0)}}
+	faces := []shapes.MeshFace{
+		{Vertices: [3]int32{0, 1, 2}, Normals: [3]int32{-1, -1, -1}, UVs: [3]int32{0, 1, 2}},
+		{Vertices: [3]int32{0, 3, 2}, Normals: [3]int32{1, 0, 0}, UVs: [3]int32{-1, -1, -1}}}
+	return buffer, faces
+}
+
+// gridBuffer Flat grid of size by size unit squares in the XZ plane
+func gridBuffer(size int) (*shapes.VertexBuffer, []shapes.MeshFace) {
+	buffer := &shapes.VertexBuffer{}
+	for z := 0; z <= size; z++ {
+		for x := 0; x <= size; x++ {
+			buffer.Vertices = append(buffer.Vertices, primitives.MakePoint(float64(x), 0, float64(z)))
+		}
+	}
+	faces := []shapes.MeshFace{}
+	row := int32(size + 1)
+	for z := int32(0); z < int32(size); z++ {
+		for x := int32(0); x < int32(size); x++ {
+			corner := (z * row) + x
+			faces = append(faces,
+				shapes.MeshFace{Vertices: [3]int32{corner, corner + 1, corner + row + 1},
+					Normals: [3]int32{-1, -1, -1}, UVs: [3]int32{-1, -1, -1}},
+				shapes.MeshFace{Vertices: [3]int32{corner, corner + row + 1, corner + row},
+					Normals: [3]int32{-1, -1, -1}, UVs: [3]int32{-1, -1, -1}})
+		}
+	}
+	return buffer, faces
+}
+
+// gridMesh Mesh of the grid from gridBuffer
+func gridMesh(size int) *shapes.Mesh {
+	return shapes.MakeMesh(gridBuffer(size))
+}
+
+func TestMeshGetBounds(t *testing.T) {
+	buffer, faces := squareBuffer()
+	tables := []struct {
+		mesh      *shapes.Mesh
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{shapes.MakeMesh(buffer, faces), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(1, 1, 0)},
+
+		{shapes.MakeMesh(buffer, faces[1:]), primitives.Translation(0, 0, 2),
+			primitives.MakePoint(0, 0, 2), primitives.MakePoint(1, 1, 2)},
+
+		{gridMesh(8), primitives.Scaling(0.5, 1, 0.5),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(4, 0, 4)},
+	}
+	for _, table := range tables {
+		table.mesh.SetTransform(table.transform)
+		bounds := table.mesh.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+	if shapes.MakeMesh(buffer, []shapes.MeshFace{}).GetBounds() != nil {
+		t.Errorf("Expected no bounds for an empty mesh")
+	}
+}
+
+func TestMeshIntersection(t *testing.T) {
+	buffer, faces := squareBuffer()
+	tables := []struct {
+		mesh      *shapes.Mesh
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{shapes.MakeMesh(buffer, faces),
+			primitives.Ray{Origin: primitives.MakePoint(0.75, 0.25, -2), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{2}},
+
+		{shapes.MakeMesh(buffer, faces),
+			primitives.Ray{Origin: primitives.MakePoint(0.25, 0.75, -2), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{2}},
+
+		{shapes.MakeMesh(buffer, faces),
+			primitives.Ray{Origin: primitives.MakePoint(1.5, 0.5, -2), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{shapes.MakeMesh(buffer, faces),
+			primitives.Ray{Origin: primitives.MakePoint(0.75, 0.25, -2), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.Translation(0, 0, 3), []float64{5}},
+
+		{gridMesh(16),
+			primitives.Ray{Origin: primitives.MakePoint(11.3, 5, 7.6), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{5}},
+
+		{gridMesh(16),
+			primitives.Ray{Origin: primitives.MakePoint(-1.5, 0.5, 3.3), Direction: primitives.MakeVector(1, -0.05, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{10}},
+
+		{gridMesh(16),
+			primitives.Ray{Origin: primitives.MakePoint(17, 1, 3.5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+	}
+	for _, table := range tables {
+		table.mesh.SetTransform(table.transform)
+		hits := table.mesh.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+	}
+}
+
+func TestMeshHierarchy(t *testing.T) {
+	mesh := gridMesh(16)
+	// Every face found through the hierarchy must be the one found by testing each face directly
+	for z := 0.05; z < 16; z += 0.7 {
+		for x := 0.05; x < 16; x += 0.7 {
+			ray := primitives.Ray{Origin: primitives.MakePoint(x, 1, z), Direction: primitives.MakeVector(0, -1, 0)}
+			expected := shapes.Intersections{}
+			for index := 0; index < mesh.Len(); index++ {
+				expected = append(expected, mesh.Face(index).Intersect(ray)...)
+			}
+			hits := mesh.Intersect(ray)
+			if len(hits) != len(expected) {
+				t.Fatalf("Ray %v, expected %v, got %v", ray, expected, hits)
+			}
+			// Rays along a shared edge hit both faces, in whichever order they are visited
+			for _, hit := range hits {
+				found := false
+				for _, other := range expected {
+					found = found || (hit == other)
+				}
+				if !found {
+					t.Errorf("Ray %v, unexpected hit %v, expected one of %v", ray, hit, expected)
+				}
+			}
+		}
+	}
+}
+
+// jitteredMesh Grid of size by size squares with every vertex moved a little, leaving no two centroids in line
+func jitteredMesh(size int) (*shapes.VertexBuffer, []shapes.MeshFace) {
+	buffer, faces := gridBuffer(size)
+	random := rand.New(rand.NewSource(7))
+	for index, vertex := range buffer.Vertices {
+		buffer.Vertices[index] = vertex.Add(primitives.MakeVector(random.Float64()*0.4, random.Float64(), random.Float64()*0.4))
+	}
+	return buffer, faces
+}
+
+func TestMeshBuildWorkers(t *testing.T) {
+	grid, gridFaces := gridBuffer(100)
+	jittered, jitteredFaces := jitteredMesh(100)
+	tables := []struct {
+		buffer *shapes.VertexBuffer
+		faces  []shapes.MeshFace
+	}{
+		{grid, gridFaces},
+		{jittered, jitteredFaces},
+		{jittered, jitteredFaces[:5]},
+	}
+	for index, table := range tables {
+		var expected bytes.Buffer
+		serial := shapes.MakeMeshWithWorkers(table.buffer, table.faces, 1)
+		if err := shapes.WriteMeshes(&expected, table.buffer, []*shapes.Mesh{serial}); err != nil {
+			t.Fatal(err)
+		}
+		// The hierarchy must come out the same however many workers build it
+		for _, workers := range []int{2, 3, 8, 64} {
+			var result bytes.Buffer
+			mesh := shapes.MakeMeshWithWorkers(table.buffer, table.faces, workers)
+			if err := shapes.WriteMeshes(&result, table.buffer, []*shapes.Mesh{mesh}); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(result.Bytes(), expected.Bytes()) {
+				t.Errorf("Table %v, expected %v workers to build the same hierarchy as one", index, workers)
+			}
+		}
+	}
+}
+
+func BenchmarkMeshBuild(b *testing.B) {
+	buffer, faces := jitteredMesh(256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shapes.MakeMesh(buffer, faces)
+	}
+}
+
+func BenchmarkMeshBuildSerial(b *testing.B) {
+	buffer, faces := jitteredMesh(256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shapes.MakeMeshWithWorkers(buffer, faces, 1)
+	}
+}
+
+func BenchmarkMeshIntersection(b *testing.B) {
+	mesh := gridMesh(64)
+	ray := primitives.Ray{Origin: primitives.MakePoint(31.3, 5, 17.6), Direction: primitives.MakeVector(0, -1, 0)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mesh.Intersect(ray)
+	}
+}
+
+func BenchmarkMeshAppendIntersections(b *testing.B) {
+	mesh := gridMesh(64)
+	ray := primitives.Ray{Origin: primitives.MakePoint(31.3, 5, 17.6), Direction: primitives.MakeVector(0, -1, 0)}
+	hits := shapes.Intersections{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hits = mesh.AppendIntersections(ray, hits[:0])
+	}
+}
+
+func TestMeshTriangleNormal(t *testing.T) {
+	buffer, faces := squareBuffer()
+	mesh := shapes.MakeMesh(buffer, faces)
+	tables := []struct {
+		face   int
+		u, v   float64
+		normal primitives.PV
+	}{
+		{0, 0.2, 0.3, primitives.MakeVector(0, 0, -1)},
+		{1, 0, 0, primitives.MakeVector(-0.7071067811865475, 0, -0.7071067811865475)},
+		{1, 0.5, 0.5, primitives.MakeVector(0, 0, -1)},
+		{1, 0, 0.5, primitives.MakeVector(-0.3826834323650898, 0, -0.9238795325112867)},
+	}
+	for _, table := range tables {
+		normal := mesh.Face(table.face).Normal(primitives.MakePoint(0, 0, 0), table.u, table.v)
+		if !normal.Equals(table.normal) {
+			t.Errorf("Face %v, Expected %v, got %v", table.face, table.normal, normal)
+		}
+	}
+}
+
+func TestMeshTriangleUVMapping(t *testing.T) {
+	buffer, faces := squareBuffer()
+	mesh := shapes.MakeMesh(buffer, faces)
+	mesh.SetTransform(primitives.Translation(0, 0, 5))
+	tables := []struct {
+		face      int
+		point, uv primitives.PV
+	}{
+		{0, primitives.MakePoint(0.75, 0.25, 5), primitives.MakePoint(0.75, 0.25, 0)},
+		{0, primitives.MakePoint(1, 1, 5), primitives.MakePoint(1, 1, 0)},
+		{1, primitives.MakePoint(0.25, 0.75, 5), primitives.MakePoint(0.25, 0.75, 0)},
+	}
+	for _, table := range tables {
+		uv := mesh.Face(table.face).UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
+
+func TestMeshTriangleObjectToWorldMatrix(t *testing.T) {
+	buffer, faces := squareBuffer()
+	mesh := shapes.MakeMesh(buffer, faces)
+	mesh.SetTransform(primitives.Translation(0, 0, 5))
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Scaling(2, 2, 2))
+	group.AddShape(mesh)
+	point := primitives.MakePoint(1, 1, 0).Transform(shapes.ObjectToWorldMatrix(mesh.Face(0)))
+	if expected := primitives.MakePoint(2, 2, 10); !point.Equals(expected) {
+		t.Errorf("Expected %v, got %v", expected, point)
+	}
+}
+
+func TestMeshTriangleShared(t *testing.T) {
+	buffer, faces := squareBuffer()
+	mesh1 := shapes.MakeMesh(buffer, faces[:1])
+	mesh2 := shapes.MakeMesh(buffer, faces[1:])
+	if mesh1.Buffer() != mesh2.Buffer() {
+		t.Errorf("Expected meshes to share a vertex buffer")
+	}
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Translation(0, 0, 1))
+	group.AddShape(mesh1)
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.75, 0.25, -2), Direction: primitives.MakeVector(0, 0, 1)}
+	hits := group.Intersect(ray)
+	if !shapes.IntersectEquals(hits, []float64{3}) {
+		t.Fatalf("Expected hit %v, got %v", []float64{3}, hits)
+	}
+	face := hits[0].Obj
+	if face.Parent() != mesh1 {
+		t.Errorf("Expected the parent of the face to be its mesh")
+	}
+	if face.Parent().Parent() != group {
+		t.Errorf("Expected the parent of the mesh to be the group")
+	}
+	if face.Material() != mesh1.Material() {
+		t.Errorf("Expected the face to use the material of the mesh")
+	}
+	if face != mesh1.Face(0) {
+		t.Errorf("Expected hit %v to be the first face of the mesh", face)
+	}
+}
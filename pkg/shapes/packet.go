@@ -0,0 +1,243 @@
+package shapes
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+// PacketSize Most rays traced together in a RayPacket
+const PacketSize = 4
+
+// RayPacket Coherent rays traced together through the same hierarchy, stored as a structure of arrays so
+// every step is a short loop over the rays. Origins are points and directions are vectors
+type RayPacket struct {
+	OriginX, OriginY, OriginZ          [PacketSize]float64
+	DirectionX, DirectionY, DirectionZ [PacketSize]float64
+	// Active Rays still taking part, a packet can hold fewer than PacketSize rays
+	Active [PacketSize]bool
+}
+
+// MakeRayPacket Make a packet from up to PacketSize rays, the rest of the packet is inactive. More rays than fit
+// are an error, MakeRayPackets splits them over several packets
+func MakeRayPacket(rays []primitives.Ray) (RayPacket, error) {
+	packet := RayPacket{}
+	if len(rays) > PacketSize {
+		return packet, fmt.Errorf("%d rays don't fit in a packet of %d", len(rays), PacketSize)
+	}
+	for lane, ray := range rays {
+		packet.OriginX[lane], packet.OriginY[lane], packet.OriginZ[lane] = ray.Origin.X, ray.Origin.Y, ray.Origin.Z
+		packet.DirectionX[lane], packet.DirectionY[lane], packet.DirectionZ[lane] = ray.Direction.X,
+			ray.Direction.Y, ray.Direction.Z
+		packet.Active[lane] = true
+	}
+	return packet, nil
+}
+
+// MakeRayPackets Split any number of rays into packets in order, only the last of which may be partly full
+func MakeRayPackets(rays []primitives.Ray) []RayPacket {
+	packets := make([]RayPacket, 0, (len(rays)+PacketSize-1)/PacketSize)
+	for start := 0; start < len(rays); start += PacketSize {
+		// The slice never holds more than PacketSize rays, so there is no error
+		packet, _ := MakeRayPacket(rays[start:minInt(start+PacketSize, len(rays))])
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+// Ray Get a single ray of the packet
+func (p *RayPacket) Ray(lane int) primitives.Ray {
+	return primitives.Ray{Origin: primitives.MakePoint(p.OriginX[lane], p.OriginY[lane], p.OriginZ[lane]),
+		Direction: primitives.MakeVector(p.DirectionX[lane], p.DirectionY[lane], p.DirectionZ[lane])}
+}
+
+// Any Check whether any ray of the packet is active
+func (p *RayPacket) Any() bool {
+	for _, active := range p.Active {
+		if active {
+			return true
+		}
+	}
+	return false
+}
+
+// count Count the active rays of the packet
+func (p *RayPacket) count() uint64 {
+	count := uint64(0)
+	for _, active := range p.Active {
+		if active {
+			count++
+		}
+	}
+	return count
+}
+
+// Transform Transform every ray of the packet by an affine matrix, giving the same values as primitives.Ray.Transform
+func (p *RayPacket) Transform(m primitives.Matrix) RayPacket {
+	result := RayPacket{Active: p.Active}
+	for lane := 0; lane < PacketSize; lane++ {
+		ox, oy, oz := p.OriginX[lane], p.OriginY[lane], p.OriginZ[lane]
+		dx, dy, dz := p.DirectionX[lane], p.DirectionY[lane], p.DirectionZ[lane]
+		result.OriginX[lane] = (m[0][0] * ox) + (m[0][1] * oy) + (m[0][2] * oz) + m[0][3]
+		result.OriginY[lane] = (m[1][0] * ox) + (m[1][1] * oy) + (m[1][2] * oz) + m[1][3]
+		result.OriginZ[lane] = (m[2][0] * ox) + (m[2][1] * oy) + (m[2][2] * oz) + m[2][3]
+		result.DirectionX[lane] = (m[0][0] * dx) + (m[0][1] * dy) + (m[0][2] * dz)
+		result.DirectionY[lane] = (m[1][0] * dx) + (m[1][1] * dy) + (m[1][2] * dz)
+		result.DirectionZ[lane] = (m[2][0] * dx) + (m[2][1] * dy) + (m[2][2] * dz)
+	}
+	return result
+}
+
+// clip Find the range of distances inside the bounds for every active ray, matching Bounds.Intersect.
+// Returns the rays that enter the bounds, counting a bounds test for each active ray into counts
+func (p *RayPacket) clip(min, max primitives.PV, tmin, tmax *[PacketSize]float64,
+	counts *stats.Counts) [PacketSize]bool {
+	var inside [PacketSize]bool
+	counts.AddBoundsTests(p.count())
+	for lane := 0; lane < PacketSize; lane++ {
+		if !p.Active[lane] {
+			continue
+		}
+		xtmin, xtmax := CheckAxis(p.OriginX[lane], p.DirectionX[lane], min.X, max.X)
+		ytmin, ytmax := CheckAxis(p.OriginY[lane], p.DirectionY[lane], min.Y, max.Y)
+		ztmin, ztmax := CheckAxis(p.OriginZ[lane], p.DirectionZ[lane], min.Z, max.Z)
+		tmin[lane] = math.Max(math.Max(xtmin, ytmin), ztmin)
+		tmax[lane] = math.Min(math.Min(xtmax, ytmax), ztmax)
+		inside[lane] = tmin[lane] <= tmax[lane]
+	}
+	return inside
+}
+
+// PacketHits Closest hit found so far for each ray of a packet
+type PacketHits struct {
+	Hits  [PacketSize]Intersection
+	Found [PacketSize]bool
+}
+
+// Add Keep a hit when it is in front of the ray and closer than the one already found, like Intersections.Closest
+func (h *PacketHits) Add(lane int, hit Intersection) {
+	if hit.Distance >= 0 && (!h.Found[lane] || hit.Distance < h.Hits[lane].Distance) {
+		h.Hits[lane], h.Found[lane] = hit, true
+	}
+}
+
+// beyond Check if every hit inside a range of distances starting at tmin is further away than the closest found,
+// with some room for the rounding between bounds and the faces inside them
+func (h *PacketHits) beyond(lane int, tmin float64) bool {
+	return h.Found[lane] && tmin > h.Hits[lane].Distance+(primitives.EPSILON*(1+math.Abs(h.Hits[lane].Distance)))
+}
+
+// PacketIntersector Shape that can find the closest hits of a whole packet at once
+type PacketIntersector interface {
+	ClosestPacket(packet *RayPacket, hits *PacketHits, scratch *Intersections, counts *stats.Counts)
+}
+
+// ClosestPacket Update hits with the hits of a shape for the active rays of a packet, tracing them one at a time
+// through scratch for shapes that don't trace packets. The tests are counted into counts when it is given
+func ClosestPacket(shape Shape, packet *RayPacket, hits *PacketHits, scratch *Intersections, counts *stats.Counts) {
+	if intersector, ok := shape.(PacketIntersector); ok {
+		countTests(shape, counts, packet.count())
+		intersector.ClosestPacket(packet, hits, scratch, counts)
+		return
+	}
+	for lane := 0; lane < PacketSize; lane++ {
+		if !packet.Active[lane] {
+			continue
+		}
+		*scratch = AppendIntersectionsCounted(shape, packet.Ray(lane), (*scratch)[:0], counts)
+		for _, hit := range *scratch {
+			hits.Add(lane, hit)
+		}
+	}
+}
+
+// ClosestPacket Trace the rays of the packet that enter the bounds of the group through its shapes together
+func (g *Group) ClosestPacket(packet *RayPacket, hits *PacketHits, scratch *Intersections, counts *stats.Counts) {
+	entering := *packet
+	if g.bounds != nil {
+		var tmin, tmax [PacketSize]float64
+		entering.Active = packet.clip(g.bounds.Min, g.bounds.Max, &tmin, &tmax, counts)
+	}
+	if !entering.Any() {
+		return
+	}
+	// convert rays to object space
+	opacket := entering.Transform(g.inverse)
+	for _, shape := range g.shapes {
+		ClosestPacket(shape, &opacket, hits, scratch, counts)
+	}
+}
+
+// ClosestPacket Walk the hierarchy once for the whole packet, testing the faces of each leaf against every ray
+// that reaches it and skipping nodes behind the rays or further than their closest hits
+func (m *Mesh) ClosestPacket(packet *RayPacket, hits *PacketHits, scratch *Intersections, counts *stats.Counts) {
+	if len(m.nodes) == 0 || !packet.Any() {
+		return
+	}
+	// convert rays to object space
+	opacket := packet.Transform(m.inverse)
+	var stackBuffer [64]int32
+	stack := append(stackBuffer[:0], 0)
+	var tmin, tmax [PacketSize]float64
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := &m.nodes[index]
+		inside := opacket.clip(node.min, node.max, &tmin, &tmax, counts)
+		reached := false
+		for lane := 0; lane < PacketSize; lane++ {
+			inside[lane] = inside[lane] && tmax[lane] >= -primitives.EPSILON && !hits.beyond(lane, tmin[lane])
+			reached = reached || inside[lane]
+		}
+		if !reached {
+			continue
+		}
+		if node.count > 0 {
+			for _, face := range m.order[node.start : node.start+node.count] {
+				m.intersectFacePacket(face, &opacket, inside, hits, counts)
+			}
+			continue
+		}
+		stack = append(stack, node.right, index+1)
+	}
+}
+
+// intersectFacePacket Test a face against the rays of an object-space packet, with the same arithmetic as
+// intersectTriangle so each ray finds the same hits it would alone
+func (m *Mesh) intersectFacePacket(face int32, opacket *RayPacket, lanes [PacketSize]bool, hits *PacketHits,
+	counts *stats.Counts) {
+	indices := m.faces[face].Vertices
+	point1 := m.buffer.Vertices[indices[0]]
+	edge1 := m.buffer.Vertices[indices[1]].Subtract(point1)
+	edge2 := m.buffer.Vertices[indices[2]].Subtract(point1)
+	for lane := 0; lane < PacketSize; lane++ {
+		if !lanes[lane] {
+			continue
+		}
+		counts.AddShapeTests(meshTriangleTests, 1)
+		dx, dy, dz := opacket.DirectionX[lane], opacket.DirectionY[lane], opacket.DirectionZ[lane]
+		// Direction crossed with edge 2
+		cx, cy, cz := (dy*edge2.Z)-(dz*edge2.Y), (dz*edge2.X)-(dx*edge2.Z), (dx*edge2.Y)-(dy*edge2.X)
+		det := (edge1.X * cx) + (edge1.Y * cy) + (edge1.Z * cz)
+		if math.Abs(det) < primitives.EPSILON {
+			continue
+		}
+		f := 1.0 / det
+		// origin to point 1
+		px, py, pz := opacket.OriginX[lane]-point1.X, opacket.OriginY[lane]-point1.Y, opacket.OriginZ[lane]-point1.Z
+		u := f * ((px * cx) + (py * cy) + (pz * cz))
+		if (u < 0) || (u > 1) {
+			continue
+		}
+		// Cross product of origin and edge
+		ox, oy, oz := (py*edge1.Z)-(pz*edge1.Y), (pz*edge1.X)-(px*edge1.Z), (px*edge1.Y)-(py*edge1.X)
+		v := f * ((dx * ox) + (dy * oy) + (dz * oz))
+		if (v < 0) || ((u + v) > 1) {
+			continue
+		}
+		hits.Add(lane, Intersection{Distance: f * ((edge2.X * ox) + (edge2.Y * oy) + (edge2.Z * oz)),
+			Obj: MeshTriangle{m, face}, U: u, V: v})
+	}
+}
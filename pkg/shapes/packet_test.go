@@ -0,0 +1,154 @@
+package shapes_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// packetRays Fan of rays from one point looking down at the grids, four to a packet like neighbouring pixels
+func packetRays(count int) []primitives.Ray {
+	rays := []primitives.Ray{}
+	origin := primitives.MakePoint(-3, 8, -4)
+	for index := 0; index < count; index++ {
+		x, z := float64(index%32)/8, float64(index/32)/8
+		rays = append(rays, primitives.Ray{Origin: origin, Direction: primitives.MakeVector(0.3+x, -1, 0.4+z).Normalize()})
+	}
+	return rays
+}
+
+// packetScene Group holding a transformed jittered mesh, a sphere and a plane
+func packetScene() *shapes.Group {
+	buffer, faces := jitteredMesh(32)
+	mesh := shapes.MakeMesh(buffer, faces)
+	mesh.SetTransform(primitives.Scaling(0.25, 0.25, 0.25))
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Translation(3, 1, 3))
+	plane := shapes.MakePlane()
+	plane.SetTransform(primitives.Translation(0, -1, 0))
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Translation(0.5, 0, 0.25).Multiply(primitives.RotationY(0.2)))
+	group.AddShape(mesh)
+	group.AddShape(sphere)
+	inner := shapes.MakeGroup()
+	inner.AddShape(plane)
+	group.AddShape(inner)
+	return group
+}
+
+func TestClosestPacket(t *testing.T) {
+	buffer, faces := jitteredMesh(32)
+	tables := []struct {
+		name  string
+		shape shapes.Shape
+	}{
+		{"Mesh", shapes.MakeMesh(buffer, faces)},
+		{"Scene", packetScene()},
+		{"Sphere", shapes.MakeSphere()},
+	}
+	rays := packetRays(1024)
+	for _, table := range tables {
+		// The last packet is only partly full
+		for start := 0; start < len(rays); start += shapes.PacketSize - 1 {
+			end := start + shapes.PacketSize
+			if end > len(rays) {
+				end = len(rays)
+			}
+			packet, err := shapes.MakeRayPacket(rays[start:end])
+			if err != nil {
+				t.Fatal(err)
+			}
+			hits := shapes.PacketHits{}
+			scratch := shapes.Intersections{}
+			shapes.ClosestPacket(table.shape, &packet, &hits, &scratch, nil)
+			for lane := 0; lane < shapes.PacketSize; lane++ {
+				if start+lane >= len(rays) {
+					if packet.Active[lane] || hits.Found[lane] {
+						t.Errorf("%v, expected lane %v of the last packet to be empty", table.name, lane)
+					}
+					continue
+				}
+				expected, found := shapes.AppendIntersections(table.shape, rays[start+lane], nil).Closest()
+				if hits.Found[lane] != found || (found && hits.Hits[lane] != expected) {
+					t.Errorf("%v, ray %v, expected %v, got %v", table.name, rays[start+lane], expected, hits.Hits[lane])
+				}
+			}
+		}
+	}
+}
+
+func TestRayPacketTransform(t *testing.T) {
+	transform := primitives.Translation(1, -2, 3).Multiply(primitives.RotationX(0.7)).Multiply(
+		primitives.Scaling(2, 0.5, 1))
+	rays := packetRays(3)
+	packet, _ := shapes.MakeRayPacket(rays)
+	transformed := packet.Transform(transform)
+	for lane, ray := range rays {
+		expected := ray.Transform(transform)
+		if result := transformed.Ray(lane); result.Origin != expected.Origin || result.Direction != expected.Direction {
+			t.Errorf("Lane %v, expected %v, got %v", lane, expected, result)
+		}
+	}
+	if !transformed.Active[2] || transformed.Active[3] {
+		t.Errorf("Expected three active rays, got %v", transformed.Active)
+	}
+}
+
+func TestMakeRayPackets(t *testing.T) {
+	rays := packetRays(10)
+	if _, err := shapes.MakeRayPacket(rays[:shapes.PacketSize+1]); err == nil {
+		t.Errorf("Expected an error for %v rays", shapes.PacketSize+1)
+	}
+	packets := shapes.MakeRayPackets(rays)
+	if len(packets) != 3 {
+		t.Fatalf("Expected 3 packets, got %v", len(packets))
+	}
+	for index, ray := range rays {
+		packet := packets[index/shapes.PacketSize]
+		lane := index % shapes.PacketSize
+		if result := packet.Ray(lane); !packet.Active[lane] || result.Origin != ray.Origin ||
+			result.Direction != ray.Direction {
+			t.Errorf("Ray %v, expected %v, got %v", index, ray, result)
+		}
+	}
+	if last := packets[2]; last.Active[2] || last.Active[3] {
+		t.Errorf("Expected two active rays in the last packet, got %v", last.Active)
+	}
+	if last := packets[2]; !last.Any() {
+		t.Error("Expected the last packet to have active rays")
+	}
+	if empty, _ := shapes.MakeRayPacket(nil); empty.Any() {
+		t.Error("Expected an empty packet to have no active rays")
+	}
+	if packets := shapes.MakeRayPackets(nil); len(packets) != 0 {
+		t.Errorf("Expected no packets without rays, got %v", len(packets))
+	}
+}
+
+func BenchmarkMeshClosestPacket(b *testing.B) {
+	buffer, faces := jitteredMesh(64)
+	mesh := shapes.MakeMesh(buffer, faces)
+	rays := packetRays(1024)[520:524]
+	packet, _ := shapes.MakeRayPacket(rays)
+	scratch := shapes.Intersections{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hits := shapes.PacketHits{}
+		shapes.ClosestPacket(mesh, &packet, &hits, &scratch, nil)
+	}
+}
+
+func BenchmarkMeshClosestRays(b *testing.B) {
+	buffer, faces := jitteredMesh(64)
+	mesh := shapes.MakeMesh(buffer, faces)
+	rays := packetRays(1024)[520:524]
+	hits := shapes.Intersections{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, ray := range rays {
+			hits = mesh.AppendIntersections(ray, hits[:0])
+			hits.Closest()
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package shapes
+
+import (
+	"sync"
+)
+
+// parallelFor Split the indices up to count into a contiguous range for each worker and run them concurrently
+func parallelFor(count, workers int, body func(start, end int)) {
+	if workers > count {
+		workers = count
+	}
+	if workers <= 1 {
+		body(0, count)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func(start, end int) {
+			defer wg.Done()
+			body(start, end)
+		}(count*worker/workers, count*(worker+1)/workers)
+	}
+	wg.Wait()
+}
@@ -6,18 +6,30 @@ import (
 	"github.com/factorion/graytracer/pkg/primitives"
 )
 
-// Plane Plane along the XZ axis
+// Plane Plane along the XZ axis, infinite unless given extents
 type Plane struct {
 	ShapeBase
+	bounded                bool
+	minX, maxX, minZ, maxZ float64
 }
 
 // MakePlane Make a default plane
 func MakePlane() *Plane {
-	return &Plane{MakeShapeBase()}
+	return &Plane{ShapeBase: MakeShapeBase()}
+}
+
+// MakeBoundedPlane Make a plane limited to the given extents along the X and Z axis
+func MakeBoundedPlane(minX, maxX, minZ, maxZ float64) *Plane {
+	return &Plane{ShapeBase: MakeShapeBase(), bounded: true, minX: minX, maxX: maxX, minZ: minZ, maxZ: maxZ}
 }
 
 // GetBounds Return an axis aligned bounding box for the sphere
 func (p *Plane) GetBounds() *Bounds {
+	if p.bounded {
+		bounds := &Bounds{Min: primitives.MakePoint(p.minX, -primitives.EPSILON, p.minZ),
+			Max: primitives.MakePoint(p.maxX, primitives.EPSILON, p.maxZ)}
+		return bounds.Transform(p.transform)
+	}
 	bounds := &Bounds{Min: primitives.MakePoint(math.Inf(-1), -primitives.EPSILON, math.Inf(-1)),
 		Max: primitives.MakePoint(math.Inf(1), primitives.EPSILON, math.Inf(1))}
 	return bounds.Transform(p.transform)
@@ -25,11 +37,23 @@ func (p *Plane) GetBounds() *Bounds {
 
 // Intersect Check if a ray intersects
 func (p *Plane) Intersect(r primitives.Ray) Intersections {
-	hits := Intersections{}
+	return p.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of a ray to the end of hits, reusing its space
+func (p *Plane) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
 	// convert ray to object space
 	objectRay := r.Transform(p.Inverse())
 	if math.Abs(objectRay.Direction.Y) > primitives.EPSILON {
-		hits = append(hits, Intersection{Distance: (-objectRay.Origin.Y / objectRay.Direction.Y), Obj: p})
+		t := -objectRay.Origin.Y / objectRay.Direction.Y
+		if p.bounded {
+			x := objectRay.Origin.X + (t * objectRay.Direction.X)
+			z := objectRay.Origin.Z + (t * objectRay.Direction.Z)
+			if (x < p.minX) || (x > p.maxX) || (z < p.minZ) || (z > p.maxZ) {
+				return hits
+			}
+		}
+		hits = append(hits, Intersection{Distance: t, Obj: p})
 	}
 	return hits
 }
@@ -42,8 +66,22 @@ func (p *Plane) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
 	return worldNormal.Normalize()
 }
 
-// UVMapping Return the 2D coordinates of an intersected point
+// UVMapping Return the 2D coordinates of an intersected point, a bounded plane maps its extents to [0, 1]
 func (p *Plane) UVMapping(point primitives.PV) primitives.PV {
 	objectPoint := p.WorldToObjectPV(point)
+	if p.bounded {
+		return primitives.MakePoint((objectPoint.X-p.minX)/(p.maxX-p.minX),
+			(objectPoint.Z-p.minZ)/(p.maxZ-p.minZ), 0)
+	}
 	return primitives.MakePoint(objectPoint.X, objectPoint.Z, 0)
 }
+
+// SamplePoint Map U and V to a world-space point within the extents, an infinite plane
+// samples the unit square from the origin
+func (p *Plane) SamplePoint(u, v float64) primitives.PV {
+	if p.bounded {
+		return p.objectToWorldPoint(primitives.MakePoint(p.minX+(u*(p.maxX-p.minX)), 0,
+			p.minZ+(v*(p.maxZ-p.minZ))))
+	}
+	return p.objectToWorldPoint(primitives.MakePoint(u, 0, v))
+}
@@ -23,6 +23,11 @@ func TestPlaneGetBounds(t *testing.T) {
 			primitives.RotationX(math.Pi / 2),
 			primitives.MakePoint(math.Inf(-1), math.Inf(-1), -primitives.EPSILON),
 			primitives.MakePoint(math.Inf(1), math.Inf(1), primitives.EPSILON)},
+
+		{shapes.MakeBoundedPlane(-1, 1, -2, 2),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1, -primitives.EPSILON, -2),
+			primitives.MakePoint(1, primitives.EPSILON, 2)},
 	}
 	for _, table := range tables {
 		table.plane.SetTransform(table.transform)
@@ -58,6 +63,14 @@ func TestPlaneIntersection(t *testing.T) {
 		{shapes.MakePlane(),
 			primitives.Ray{Origin: primitives.MakePoint(0, -1, 0), Direction: primitives.MakeVector(0, 1, 0)},
 			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{shapes.MakeBoundedPlane(-1, 1, -2, 2),
+			primitives.Ray{Origin: primitives.MakePoint(0, 1, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{shapes.MakeBoundedPlane(-1, 1, -2, 2),
+			primitives.Ray{Origin: primitives.MakePoint(0, 1, 3), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
 	}
 	for _, table := range tables {
 		table.p.SetTransform(table.transform)
@@ -112,3 +125,37 @@ func BenchmarkPlaneNormal(b *testing.B) {
 		plane.Normal(point, 0.0, 0.0)
 	}
 }
+
+func TestPlaneUVMapping(t *testing.T) {
+	tables := []struct {
+		p         *shapes.Plane
+		point, uv primitives.PV
+	}{
+		{shapes.MakePlane(), primitives.MakePoint(2.5, 0, -3), primitives.MakePoint(2.5, -3, 0)},
+		{shapes.MakeBoundedPlane(-1, 1, -2, 2), primitives.MakePoint(0, 0, 0), primitives.MakePoint(0.5, 0.5, 0)},
+		{shapes.MakeBoundedPlane(-1, 1, -2, 2), primitives.MakePoint(1, 0, -2), primitives.MakePoint(1, 0, 0)},
+	}
+	for _, table := range tables {
+		uv := table.p.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
+
+func TestPlaneSamplePoint(t *testing.T) {
+	tables := []struct {
+		p     *shapes.Plane
+		u, v  float64
+		point primitives.PV
+	}{
+		{shapes.MakePlane(), 0.5, 0.25, primitives.MakePoint(0.5, 0, 0.25)},
+		{shapes.MakeBoundedPlane(-1, 1, -2, 2), 0.5, 0.25, primitives.MakePoint(0, 0, -1)},
+	}
+	for _, table := range tables {
+		point := table.p.SamplePoint(table.u, table.v)
+		if !point.Equals(table.point) {
+			t.Errorf("U: %v, V: %v, Expected %v, got %v", table.u, table.v, table.point, point)
+		}
+	}
+}
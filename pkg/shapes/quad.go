@@ -0,0 +1,88 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Quad Flat parallelogram, or rectangle when the edges are perpendicular, spanned by two edges from a corner
+type Quad struct {
+	ShapeBase
+	Corner, Edge1, Edge2 primitives.PV
+	normal, planar       primitives.PV
+}
+
+// MakeQuad Create a quad from a corner point and the two edges leaving it. Edges that are parallel or of no length
+// span no area and are an error
+func MakeQuad(corner, edge1, edge2 primitives.PV) (*Quad, error) {
+	edge1.W = 0
+	edge2.W = 0
+	cross := edge2.CrossProduct(edge1)
+	// The sine of the angle between the edges, which is NaN when either has no length
+	if sine := cross.Magnitude() / (edge1.Magnitude() * edge2.Magnitude()); !(sine >= primitives.EPSILON) {
+		return nil, errors.New("quad edges must have a length and must not be parallel")
+	}
+	// Scaled normal used to project a point onto the edges
+	planar := cross.Scalar(1 / cross.DotProduct(cross))
+	return &Quad{MakeShapeBase(), corner, edge1, edge2, cross.Normalize(), planar}, nil
+}
+
+// MakeRectangle Create a rectangle of the given width and depth centered on the origin along the XZ axis, a width
+// or depth of 0 is an error
+func MakeRectangle(width, depth float64) (*Quad, error) {
+	return MakeQuad(primitives.MakePoint(-width/2, 0, -depth/2),
+		primitives.MakeVector(width, 0, 0), primitives.MakeVector(0, 0, depth))
+}
+
+// GetBounds Return an axis aligned bounding box for the quad
+func (q *Quad) GetBounds() *Bounds {
+	opposite := q.Corner.Add(q.Edge1).Add(q.Edge2)
+	x_min, x_max := MinMax([]float64{q.Corner.X, q.Corner.X + q.Edge1.X, q.Corner.X + q.Edge2.X, opposite.X})
+	y_min, y_max := MinMax([]float64{q.Corner.Y, q.Corner.Y + q.Edge1.Y, q.Corner.Y + q.Edge2.Y, opposite.Y})
+	z_min, z_max := MinMax([]float64{q.Corner.Z, q.Corner.Z + q.Edge1.Z, q.Corner.Z + q.Edge2.Z, opposite.Z})
+	bounds := &Bounds{Min: primitives.MakePoint(x_min, y_min, z_min), Max: primitives.MakePoint(x_max, y_max, z_max)}
+	return bounds.Transform(q.transform)
+}
+
+// Intersect Check if a ray intersects
+func (q *Quad) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	// convert ray to object space
+	oray := r.Transform(q.inverse)
+	denominator := q.normal.DotProduct(oray.Direction)
+	if math.Abs(denominator) < primitives.EPSILON {
+		return hits
+	}
+	t := q.normal.DotProduct(q.Corner.Subtract(oray.Origin)) / denominator
+	uv := q.project(oray.Position(t))
+	if (uv.X < 0) || (uv.X > 1) || (uv.Y < 0) || (uv.Y > 1) {
+		return hits
+	}
+	return append(hits, Intersection{Distance: t, Obj: q, U: uv.X, V: uv.Y})
+}
+
+// project Express an object-space point on the plane of the quad in terms of its two edges
+func (q *Quad) project(point primitives.PV) primitives.PV {
+	offset := point.Subtract(q.Corner)
+	return primitives.MakePoint(q.planar.DotProduct(q.Edge2.CrossProduct(offset)),
+		q.planar.DotProduct(offset.CrossProduct(q.Edge1)), 0)
+}
+
+// Normal Calculate the normal at a given point on the quad
+func (q *Quad) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	worldNormal := q.ObjectToWorldPV(q.normal)
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the 2D coordinates of an intersected point, U along the first edge and V along the second
+func (q *Quad) UVMapping(point primitives.PV) primitives.PV {
+	return q.project(q.WorldToObjectPV(point))
+}
+
+// SamplePoint Map U and V to a world-space point along the two edges
+func (q *Quad) SamplePoint(u, v float64) primitives.PV {
+	return q.objectToWorldPoint(q.Corner.Add(q.Edge1.Scalar(u)).Add(q.Edge2.Scalar(v)))
+}
@@ -0,0 +1,190 @@
+package shapes_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+// mustQuad Make a quad that must not be an error
+func mustQuad(corner, edge1, edge2 primitives.PV) *shapes.Quad {
+	quad, err := shapes.MakeQuad(corner, edge1, edge2)
+	if err != nil {
+		panic(err)
+	}
+	return quad
+}
+
+// mustRectangle Make a rectangle that must not be an error
+func mustRectangle(width, depth float64) *shapes.Quad {
+	quad, err := shapes.MakeRectangle(width, depth)
+	if err != nil {
+		panic(err)
+	}
+	return quad
+}
+
+func TestQuadGetBounds(t *testing.T) {
+	tables := []struct {
+		quad      *shapes.Quad
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(2, 0, 1)},
+
+		{mustRectangle(2, 4),
+			primitives.Translation(0, 1, 0),
+			primitives.MakePoint(-1, 1, -2), primitives.MakePoint(1, 1, 2)},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(1, 0, 0), primitives.MakeVector(1, 1, 0)),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, 0), primitives.MakePoint(2, 1, 0)},
+	}
+	for _, table := range tables {
+		table.quad.SetTransform(table.transform)
+		bounds := table.quad.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+}
+
+func TestQuadIntersection(t *testing.T) {
+	tables := []struct {
+		q         *shapes.Quad
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.Ray{Origin: primitives.MakePoint(1, 1, 0.5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.Ray{Origin: primitives.MakePoint(1, -1, 0.5), Direction: primitives.MakeVector(0, 1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{1}},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.Ray{Origin: primitives.MakePoint(3, 1, 0.5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.Ray{Origin: primitives.MakePoint(1, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(1, 0, 0), primitives.MakeVector(1, 0, 1)),
+			primitives.Ray{Origin: primitives.MakePoint(0.2, 1, 0.5), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{mustRectangle(2, 2),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 1, 0)},
+			primitives.Translation(0, 5, 0), []float64{5}},
+	}
+	for _, table := range tables {
+		table.q.SetTransform(table.transform)
+		hits := table.q.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+	}
+}
+
+func BenchmarkQuadIntersection(b *testing.B) {
+	quad := mustRectangle(2, 2)
+	ray := primitives.Ray{Origin: primitives.MakePoint(0.5, 1, 0.5), Direction: primitives.MakeVector(0, -1, 0)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		quad.Intersect(ray)
+	}
+}
+
+func TestQuadNormal(t *testing.T) {
+	tables := []struct {
+		q             *shapes.Quad
+		point, normal primitives.PV
+	}{
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.MakePoint(1, 0, 0.5), primitives.MakeVector(0, 1, 0)},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(1, 0, 0), primitives.MakeVector(0, 1, 0)),
+			primitives.MakePoint(0.5, 0.5, 0), primitives.MakeVector(0, 0, -1)},
+	}
+	for _, table := range tables {
+		normal := table.q.Normal(table.point, 0.0, 0.0)
+		if !normal.Equals(table.normal) {
+			t.Errorf("Expected %v, got %v", table.normal, normal)
+		}
+	}
+}
+
+func TestQuadUVMapping(t *testing.T) {
+	tables := []struct {
+		q         *shapes.Quad
+		point, uv primitives.PV
+	}{
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.MakePoint(1, 0, 0.25), primitives.MakePoint(0.5, 0.25, 0)},
+
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(1, 0, 0), primitives.MakeVector(1, 0, 1)),
+			primitives.MakePoint(1.5, 0, 0.5), primitives.MakePoint(1, 0.5, 0)},
+
+		{mustRectangle(2, 2),
+			primitives.MakePoint(-1, 0, 1), primitives.MakePoint(0, 1, 0)},
+	}
+	for _, table := range tables {
+		uv := table.q.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
+
+func TestQuadSamplePoint(t *testing.T) {
+	tables := []struct {
+		q         *shapes.Quad
+		transform primitives.Matrix
+		u, v      float64
+		point     primitives.PV
+	}{
+		{mustRectangle(2, 2), primitives.MakeIdentityMatrix(4), 0.5, 0.5, primitives.MakePoint(0, 0, 0)},
+		{mustQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(2, 0, 0), primitives.MakeVector(0, 0, 1)),
+			primitives.MakeIdentityMatrix(4), 1, 1, primitives.MakePoint(2, 0, 1)},
+		{mustRectangle(2, 2), primitives.Translation(0, 5, 0), 0, 1, primitives.MakePoint(-1, 5, 1)},
+	}
+	for _, table := range tables {
+		table.q.SetTransform(table.transform)
+		point := table.q.SamplePoint(table.u, table.v)
+		if !point.Equals(table.point) {
+			t.Errorf("U: %v, V: %v, Expected %v, got %v", table.u, table.v, table.point, point)
+		}
+	}
+}
+
+func TestQuadErrors(t *testing.T) {
+	tables := []struct {
+		name         string
+		edge1, edge2 primitives.PV
+	}{
+		{"Parallel edges", primitives.MakeVector(1, 0, 0), primitives.MakeVector(2, 0, 0)},
+		{"Opposite edges", primitives.MakeVector(0, 1, 1), primitives.MakeVector(0, -1, -1)},
+		{"No length", primitives.MakeVector(0, 0, 0), primitives.MakeVector(0, 0, 1)},
+	}
+	for _, table := range tables {
+		if quad, err := shapes.MakeQuad(primitives.MakePoint(0, 0, 0), table.edge1, table.edge2); err == nil {
+			t.Errorf("%v, expected an error, got %v", table.name, quad)
+		}
+	}
+	if _, err := shapes.MakeRectangle(0, 2); err == nil {
+		t.Error("Expected an error for a rectangle with no width")
+	}
+	if _, err := shapes.MakeQuad(primitives.MakePoint(0, 0, 0), primitives.MakeVector(0.001, 0, 0),
+		primitives.MakeVector(0, 0, 0.001)); err != nil {
+		t.Errorf("Expected a small quad to be made, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// How close to the surface a march counts as touching it
+const sdfHitEpsilon = 1e-4
+
+// Offset for the central differences estimating the gradient of the distance function
+const sdfNormalDelta = 1e-6
+
+// Bisection steps used to refine a crossing once it has been bracketed
+const sdfRefineSteps = 50
+
+// DistanceFunc Signed distance from an object-space point to a surface, negative inside of it
+type DistanceFunc func(point primitives.PV) float64
+
+// SDF Surface defined by a signed distance function and rendered with sphere tracing,
+// the function must stay within the declared bounds
+type SDF struct {
+	ShapeBase
+	distance  DistanceFunc
+	bounds    Bounds
+	maxSteps  int
+	stepScale float64
+}
+
+// MakeSDF Make a shape from a distance function and its object-space bounds with an identity matrix for transform
+func MakeSDF(distance DistanceFunc, min, max primitives.PV) *SDF {
+	return &SDF{MakeShapeBase(), distance, Bounds{Min: min, Max: max}, 256, 1.0}
+}
+
+// SetMaxSteps Set the maximum number of steps taken when marching a ray
+func (sdf *SDF) SetMaxSteps(steps int) {
+	sdf.maxSteps = steps
+}
+
+// SetStepScale Scale each step of the march, values below 1 are needed for
+// functions that overestimate the distance like twisted or smoothly combined ones
+func (sdf *SDF) SetStepScale(scale float64) {
+	sdf.stepScale = scale
+}
+
+// Distance Get the signed distance from an object-space point to the surface
+func (sdf *SDF) Distance(point primitives.PV) float64 {
+	return sdf.distance(point)
+}
+
+// GetBounds Return an axis aligned bounding box for the shape
+func (sdf *SDF) GetBounds() *Bounds {
+	return sdf.bounds.Transform(sdf.transform)
+}
+
+// Intersect Check if a ray intersects
+func (sdf *SDF) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	// convert ray to object space
+	oray := r.Transform(sdf.Inverse())
+	tmin, tmax, ok := sdf.bounds.Clip(oray)
+	if !ok {
+		return hits
+	}
+	// Distances are measured in object space, steps along the ray in units of its direction
+	length := oray.Direction.Magnitude()
+	touch := sdfHitEpsilon / length
+	// Signed distance along the ray, flipped while inside so the march always looks for the next crossing
+	inside := sdf.distance(oray.Position(tmin)) < 0
+	march := func(t float64) float64 {
+		d := sdf.distance(oray.Position(t))
+		if inside {
+			return -d
+		}
+		return d
+	}
+	previous, t := tmin, tmin
+	for step := 0; step < sdf.maxSteps && t <= tmax; step++ {
+		d := march(t)
+		start, end := 0.0, 0.0
+		switch {
+		case d < 0:
+			// Overstepped a crossing
+			start, end = previous, t
+		case d < sdfHitEpsilon:
+			// Touching the surface, only a crossing if the ray passes through it
+			if march(t+(2*touch)) >= 0 {
+				previous, t = t, t+(2*touch)
+				continue
+			}
+			start, end = t, t+(2*touch)
+		default:
+			previous, t = t, t+(sdf.stepScale*d/length)
+			continue
+		}
+		for refine := 0; refine < sdfRefineSteps; refine++ {
+			middle := (start + end) / 2
+			if march(middle) < 0 {
+				end = middle
+			} else {
+				start = middle
+			}
+		}
+		root := (start + end) / 2
+		hits = append(hits, Intersection{Distance: root, Obj: sdf})
+		inside = !inside
+		previous, t = root, root+(2*touch)
+	}
+	return hits
+}
+
+// Normal Calculate the normal at a given point on the shape from the gradient of the distance function
+func (sdf *SDF) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	p := sdf.WorldToObjectPV(worldPoint)
+	gradient := primitives.MakeVector(
+		sdf.distance(primitives.MakePoint(p.X+sdfNormalDelta, p.Y, p.Z))-sdf.distance(primitives.MakePoint(p.X-sdfNormalDelta, p.Y, p.Z)),
+		sdf.distance(primitives.MakePoint(p.X, p.Y+sdfNormalDelta, p.Z))-sdf.distance(primitives.MakePoint(p.X, p.Y-sdfNormalDelta, p.Z)),
+		sdf.distance(primitives.MakePoint(p.X, p.Y, p.Z+sdfNormalDelta))-sdf.distance(primitives.MakePoint(p.X, p.Y, p.Z-sdfNormalDelta)))
+	worldNormal := sdf.ObjectToWorldPV(gradient.Normalize())
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the 2D coordinates of an intersection point, spherically around the object origin
+func (sdf *SDF) UVMapping(point primitives.PV) primitives.PV {
+	objectPoint := sdf.WorldToObjectPV(point)
+	d := primitives.MakePoint(0, 0, 0).Subtract(objectPoint).Normalize()
+	return primitives.MakePoint(0.5+math.Atan2(d.X, d.Z)/(2*math.Pi), 0.5-math.Asin(d.Y)/math.Pi, 0)
+}
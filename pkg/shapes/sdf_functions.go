@@ -0,0 +1,117 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// SphereDistance Distance to a sphere of the radius around the origin
+func SphereDistance(radius float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		return math.Sqrt((p.X*p.X)+(p.Y*p.Y)+(p.Z*p.Z)) - radius
+	}
+}
+
+// BoxDistance Distance to a box around the origin with the half extents of size
+func BoxDistance(size primitives.PV) DistanceFunc {
+	return RoundBoxDistance(size, 0)
+}
+
+// RoundBoxDistance Distance to a box around the origin with the half extents of size and edges rounded
+// by the radius, the radius is taken from inside the box
+func RoundBoxDistance(size primitives.PV, radius float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		qx := math.Abs(p.X) - size.X + radius
+		qy := math.Abs(p.Y) - size.Y + radius
+		qz := math.Abs(p.Z) - size.Z + radius
+		outside := primitives.MakeVector(math.Max(qx, 0), math.Max(qy, 0), math.Max(qz, 0)).Magnitude()
+		return outside + math.Min(math.Max(qx, math.Max(qy, qz)), 0) - radius
+	}
+}
+
+// TorusDistance Distance to a ring around the y-axis with a tube of the minor radius at the major radius
+func TorusDistance(major, minor float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		ring := math.Sqrt((p.X*p.X)+(p.Z*p.Z)) - major
+		return math.Sqrt((ring*ring)+(p.Y*p.Y)) - minor
+	}
+}
+
+// CapsuleDistance Distance to a capsule with rounded ends of the radius around the line from a to b
+func CapsuleDistance(a, b primitives.PV, radius float64) DistanceFunc {
+	ba := b.Subtract(a)
+	length := ba.DotProduct(ba)
+	return func(p primitives.PV) float64 {
+		pa := p.Subtract(a)
+		h := 0.0
+		if length > 0 {
+			h = math.Min(math.Max(pa.DotProduct(ba)/length, 0), 1)
+		}
+		return pa.Subtract(ba.Scalar(h)).Magnitude() - radius
+	}
+}
+
+// smoothBlend Polynomial smooth minimum weight, a k of zero or less gives a sharp edge
+func smoothBlend(a, b, k float64) float64 {
+	if k <= 0 {
+		return 0
+	}
+	h := math.Max(k-math.Abs(a-b), 0) / k
+	return h * h * k / 4
+}
+
+// SmoothUnion Combine two surfaces, blending them together within the distance k of each other
+func SmoothUnion(a, b DistanceFunc, k float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		da, db := a(p), b(p)
+		return math.Min(da, db) - smoothBlend(da, db, k)
+	}
+}
+
+// SmoothSubtraction Carve surface b out of surface a, blending the edge within the distance k
+func SmoothSubtraction(a, b DistanceFunc, k float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		da, db := a(p), -b(p)
+		return math.Max(da, db) + smoothBlend(da, db, k)
+	}
+}
+
+// SmoothIntersection Keep only where both surfaces overlap, blending the edge within the distance k
+func SmoothIntersection(a, b DistanceFunc, k float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		da, db := a(p), b(p)
+		return math.Max(da, db) + smoothBlend(da, db, k)
+	}
+}
+
+// TranslateDistance Move a surface by the offset
+func TranslateDistance(distance DistanceFunc, offset primitives.PV) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		return distance(primitives.MakePoint(p.X-offset.X, p.Y-offset.Y, p.Z-offset.Z))
+	}
+}
+
+// repeatAxis Wrap a coordinate into the cell of the period around the origin, a period of zero or less doesn't repeat
+func repeatAxis(value, period float64) float64 {
+	if period <= 0 {
+		return value
+	}
+	return value - (period * math.Round(value/period))
+}
+
+// Repeat Repeat a surface endlessly with the period on each axis, the surface should fit within one cell
+func Repeat(distance DistanceFunc, period primitives.PV) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		return distance(primitives.MakePoint(repeatAxis(p.X, period.X), repeatAxis(p.Y, period.Y), repeatAxis(p.Z, period.Z)))
+	}
+}
+
+// Twist Rotate a surface around the y-axis by rate radians per unit of height,
+// this overestimates distances so the shape needs a reduced step scale
+func Twist(distance DistanceFunc, rate float64) DistanceFunc {
+	return func(p primitives.PV) float64 {
+		sin, cos := math.Sincos(rate * p.Y)
+		return distance(primitives.MakePoint((cos*p.X)-(sin*p.Z), p.Y, (sin*p.X)+(cos*p.Z)))
+	}
+}
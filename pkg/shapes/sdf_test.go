@@ -0,0 +1,220 @@
+package shapes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func unitBoundsSDF(distance shapes.DistanceFunc, extent float64) *shapes.SDF {
+	return shapes.MakeSDF(distance, primitives.MakePoint(-extent, -extent, -extent),
+		primitives.MakePoint(extent, extent, extent))
+}
+
+func TestSDFGetBounds(t *testing.T) {
+	tables := []struct {
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{primitives.MakeIdentityMatrix(4), primitives.MakePoint(-1, -1, -1), primitives.MakePoint(1, 1, 1)},
+		{primitives.Translation(0, 2, 0), primitives.MakePoint(-1, 1, -1), primitives.MakePoint(1, 3, 1)},
+		{primitives.Scaling(2, 1, 3), primitives.MakePoint(-2, -1, -3), primitives.MakePoint(2, 1, 3)},
+	}
+	for _, table := range tables {
+		sdf := unitBoundsSDF(shapes.SphereDistance(1), 1)
+		sdf.SetTransform(table.transform)
+		bounds := sdf.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+}
+
+func TestSDFIntersection(t *testing.T) {
+	tables := []struct {
+		sdf       *shapes.SDF
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{unitBoundsSDF(shapes.SphereDistance(1), 1),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 6}},
+
+		{unitBoundsSDF(shapes.SphereDistance(1), 1),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{-1, 1}},
+
+		{unitBoundsSDF(shapes.SphereDistance(1), 1),
+			primitives.Ray{Origin: primitives.MakePoint(0, 2, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{unitBoundsSDF(shapes.SphereDistance(1), 1),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.Scaling(2, 2, 2), []float64{3, 7}},
+
+		{unitBoundsSDF(shapes.BoxDistance(primitives.MakeVector(1, 0.5, 0.5)), 1),
+			primitives.Ray{Origin: primitives.MakePoint(-5, 0.25, 0), Direction: primitives.MakeVector(2, 0, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{2, 3}},
+
+		{unitBoundsSDF(shapes.RoundBoxDistance(primitives.MakeVector(1, 1, 1), 0.5), 1),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 6}},
+
+		{unitBoundsSDF(shapes.TorusDistance(1, 0.25), 1.25),
+			primitives.Ray{Origin: primitives.MakePoint(-5, 0, 0), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{3.75, 4.25, 5.75, 6.25}},
+
+		{unitBoundsSDF(shapes.CapsuleDistance(primitives.MakePoint(0, -0.5, 0), primitives.MakePoint(0, 0.5, 0), 0.5), 1),
+			primitives.Ray{Origin: primitives.MakePoint(0, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 6}},
+
+		{shapes.MakeSDF(shapes.Repeat(shapes.SphereDistance(0.25), primitives.MakeVector(1, 0, 0)),
+			primitives.MakePoint(-2.5, -0.25, -0.25), primitives.MakePoint(2.5, 0.25, 0.25)),
+			primitives.Ray{Origin: primitives.MakePoint(-5, 0, 0), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.MakeIdentityMatrix(4),
+			[]float64{2.75, 3.25, 3.75, 4.25, 4.75, 5.25, 5.75, 6.25, 6.75, 7.25}},
+	}
+	for _, table := range tables {
+		table.sdf.SetTransform(table.transform)
+		hits := table.sdf.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+	}
+}
+
+func TestSDFOperators(t *testing.T) {
+	left := shapes.TranslateDistance(shapes.SphereDistance(1), primitives.MakeVector(-0.75, 0, 0))
+	right := shapes.TranslateDistance(shapes.SphereDistance(1), primitives.MakeVector(0.75, 0, 0))
+	tables := []struct {
+		distance shapes.DistanceFunc
+		point    primitives.PV
+		expected float64
+	}{
+		{shapes.SmoothUnion(left, right, 0), primitives.MakePoint(0, 0, 0), -0.25},
+		{shapes.SmoothUnion(left, right, 0.5), primitives.MakePoint(0, 0, 0), -0.375},
+		{shapes.SmoothUnion(left, right, 0.5), primitives.MakePoint(-2.75, 0, 0), 1},
+		{shapes.SmoothIntersection(left, right, 0), primitives.MakePoint(0, 0, 0), -0.25},
+		{shapes.SmoothIntersection(left, right, 0.5), primitives.MakePoint(0, 0, 0), -0.125},
+		{shapes.SmoothSubtraction(left, right, 0), primitives.MakePoint(-1.75, 0, 0), 0},
+		{shapes.SmoothSubtraction(left, right, 0), primitives.MakePoint(0, 0, 0), 0.25},
+		{shapes.Repeat(shapes.SphereDistance(0.5), primitives.MakeVector(2, 0, 2)), primitives.MakePoint(4, 0, -6.5), 0},
+		{shapes.Twist(shapes.BoxDistance(primitives.MakeVector(2, 1, 0.5)), math.Pi/2),
+			primitives.MakePoint(0, 1, 2), 0},
+	}
+	for _, table := range tables {
+		if distance := table.distance(table.point); math.Abs(distance-table.expected) > primitives.EPSILON {
+			t.Errorf("Point %v, expected %v, got %v", table.point, table.expected, distance)
+		}
+	}
+}
+
+func TestSDFSmoothUnion(t *testing.T) {
+	// Two overlapping spheres blended together are hit where the blend fills the gap between them
+	left := shapes.TranslateDistance(shapes.SphereDistance(1), primitives.MakeVector(-0.75, 0, 0))
+	right := shapes.TranslateDistance(shapes.SphereDistance(1), primitives.MakeVector(0.75, 0, 0))
+	sharp := shapes.MakeSDF(shapes.SmoothUnion(left, right, 0),
+		primitives.MakePoint(-2, -2, -2), primitives.MakePoint(2, 2, 2))
+	smooth := shapes.MakeSDF(shapes.SmoothUnion(left, right, 0.5),
+		primitives.MakePoint(-2, -2, -2), primitives.MakePoint(2, 2, 2))
+	smooth.SetStepScale(0.5)
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 5, 0), Direction: primitives.MakeVector(0, -1, 0)}
+	sharpHit, ok := sharp.Intersect(ray).Hit()
+	if !ok {
+		t.Fatalf("Expected the sharp union to be hit")
+	}
+	smoothHit, ok := smooth.Intersect(ray).Hit()
+	if !ok {
+		t.Fatalf("Expected the smooth union to be hit")
+	}
+	if math.Abs(sharpHit.Distance-(5-math.Sqrt(1-(0.75*0.75)))) > primitives.EPSILON {
+		t.Errorf("Expected the sharp union at %v, got %v", 5-math.Sqrt(1-(0.75*0.75)), sharpHit.Distance)
+	}
+	if smoothHit.Distance >= sharpHit.Distance {
+		t.Errorf("Expected the smooth union to fill in above the sharp one, got %v and %v",
+			smoothHit.Distance, sharpHit.Distance)
+	}
+	if distance := smooth.Distance(ray.Position(smoothHit.Distance)); math.Abs(distance) > primitives.EPSILON {
+		t.Errorf("Expected the hit to be on the surface, got distance %v", distance)
+	}
+}
+
+func TestSDFInGroup(t *testing.T) {
+	group := shapes.MakeGroup()
+	group.SetTransform(primitives.Translation(0, 0, 5))
+	sdf := unitBoundsSDF(shapes.SphereDistance(1), 1)
+	group.AddShape(sdf)
+	bounds := group.GetBounds()
+	if !bounds.Min.Equals(primitives.MakePoint(-1, -1, 4)) || !bounds.Max.Equals(primitives.MakePoint(1, 1, 6)) {
+		t.Errorf("Expected group bounds from the declared bounds, got %v", bounds)
+	}
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 0, 1)}
+	hits := group.Intersect(ray)
+	if !shapes.IntersectEquals(hits, []float64{4, 6}) {
+		t.Errorf("Expected hit %v, got %v", []float64{4, 6}, hits)
+	}
+	normal := sdf.Normal(primitives.MakePoint(0, 0, 4), 0, 0)
+	if !normal.Equals(primitives.MakeVector(0, 0, -1)) {
+		t.Errorf("Expected %v, got %v", primitives.MakeVector(0, 0, -1), normal)
+	}
+}
+
+func BenchmarkSDFIntersection(b *testing.B) {
+	sdf := unitBoundsSDF(shapes.TorusDistance(1, 0.25), 1.25)
+	ray := primitives.Ray{Origin: primitives.MakePoint(-5, 0, 0), Direction: primitives.MakeVector(1, 0, 0)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sdf.Intersect(ray)
+	}
+}
+
+func TestSDFNormal(t *testing.T) {
+	tables := []struct {
+		sdf       *shapes.SDF
+		transform primitives.Matrix
+		point     primitives.PV
+		normal    primitives.PV
+	}{
+		{unitBoundsSDF(shapes.SphereDistance(1), 1), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(1, 0, 0), primitives.MakeVector(1, 0, 0)},
+
+		{unitBoundsSDF(shapes.SphereDistance(1), 1), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(math.Sqrt(3)/3, math.Sqrt(3)/3, math.Sqrt(3)/3),
+			primitives.MakeVector(math.Sqrt(3)/3, math.Sqrt(3)/3, math.Sqrt(3)/3)},
+
+		{unitBoundsSDF(shapes.SphereDistance(1), 1), primitives.Translation(0, 1, 0),
+			primitives.MakePoint(0, 1.70711, -0.70711), primitives.MakeVector(0, 0.7071067811865475, -0.7071067811865475)},
+
+		{unitBoundsSDF(shapes.BoxDistance(primitives.MakeVector(1, 1, 1)), 1), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.5, 1, -0.25), primitives.MakeVector(0, 1, 0)},
+
+		{unitBoundsSDF(shapes.TorusDistance(1, 0.25), 1.25), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0.25, 1), primitives.MakeVector(0, 1, 0)},
+	}
+	for _, table := range tables {
+		table.sdf.SetTransform(table.transform)
+		normal := table.sdf.Normal(table.point, 0, 0)
+		if !normal.Equals(table.normal) {
+			t.Errorf("Point %v, Expected %v, got %v", table.point, table.normal, normal)
+		}
+	}
+}
+
+func TestSDFUVMapping(t *testing.T) {
+	sdf := unitBoundsSDF(shapes.SphereDistance(1), 1)
+	sphere := shapes.MakeSphere()
+	for _, point := range []primitives.PV{primitives.MakePoint(0, 0, -1), primitives.MakePoint(1, 0, 0),
+		primitives.MakePoint(0, 1, 0), primitives.MakePoint(0, 0, 1)} {
+		uv := sdf.UVMapping(point)
+		expected := sphere.UVMapping(point)
+		if !uv.Equals(expected) {
+			t.Errorf("Point %v, Expected %v, got %v", point, expected, uv)
+		}
+	}
+}
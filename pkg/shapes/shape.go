@@ -11,6 +11,8 @@ type ShapeBase struct {
 	inverse   primitives.Matrix
 	material  patterns.Material
 	parent    Shape
+	// Matrices from object to world-space through every parent, cached by Compile
+	world, worldInverse, normalMatrix primitives.Matrix
 }
 
 // MakeShapeBase Make a regular sphere with an identity matrix for transform
@@ -26,6 +28,7 @@ func (s *ShapeBase) SetTransform(m primitives.Matrix) {
 	inverse, _ := m.Inverse()
 	s.transform = m
 	s.inverse = inverse 
+	s.clearCompiled()
 }
 
 // Transform Get the transform matrix
@@ -51,6 +54,7 @@ func (s *ShapeBase) Material() patterns.Material {
 // SetParent Set the parent object of the shape
 func (s *ShapeBase) SetParent(parent Shape) {
 	s.parent = parent
+	s.clearCompiled()
 }
 
 // Parent Get the parent object of the shape
@@ -60,6 +64,9 @@ func (s *ShapeBase) Parent() Shape {
 
 // WorldToObjectPV Convert a Point/Vector from world to object-space
 func (s *ShapeBase) WorldToObjectPV(pv primitives.PV) primitives.PV {
+	if s.worldInverse != nil {
+		return pv.Transform(s.worldInverse)
+	}
 	if s.parent != nil {
 		pv = s.parent.WorldToObjectPV(pv)
 	}
@@ -68,6 +75,9 @@ func (s *ShapeBase) WorldToObjectPV(pv primitives.PV) primitives.PV {
 
 // ObjectToWorldPV Convert a Point/Vector from object to world-space
 func (s *ShapeBase) ObjectToWorldPV(pv primitives.PV) primitives.PV {
+	if s.normalMatrix != nil {
+		return pv.Transform(s.normalMatrix)
+	}
 	result := pv.Transform(s.Inverse().Transpose())
 	if s.parent != nil {
 		result = s.parent.ObjectToWorldPV(result)
@@ -75,6 +85,41 @@ func (s *ShapeBase) ObjectToWorldPV(pv primitives.PV) primitives.PV {
 	return result
 }
 
+// objectToWorldPoint Convert a point (or a vector, ignoring translation) from object to world-space
+func (s *ShapeBase) objectToWorldPoint(pv primitives.PV) primitives.PV {
+	if s.world != nil {
+		return pv.Transform(s.world)
+	}
+	result := pv.Transform(s.transform)
+	for parent := s.parent; parent != nil; parent = parent.Parent() {
+		result = result.Transform(parent.Transform())
+	}
+	return result
+}
+
+// ObjectToWorldMatrix Get the matrix taking points from the object-space of a shape to world-space through every
+// parent, a nil shape gives the identity
+func ObjectToWorldMatrix(shape Shape) primitives.Matrix {
+	matrix := primitives.MakeIdentityMatrix(4)
+	for ; shape != nil; shape = shape.Parent() {
+		matrix = shape.Transform().Multiply(matrix)
+	}
+	return matrix
+}
+
+// compile Cache the matrices from object to world-space given the matrix from the parent to world-space, the
+// prototypes already compiled only matter to shapes holding others
+func (s *ShapeBase) compile(parent primitives.Matrix, prototypes map[Shape]bool) {
+	s.world = parent.Multiply(s.transform)
+	s.worldInverse, _ = s.world.Inverse()
+	s.normalMatrix = s.worldInverse.Transpose()
+}
+
+// clearCompiled Drop the cached matrices so conversions walk the parents again
+func (s *ShapeBase) clearCompiled() {
+	s.world, s.worldInverse, s.normalMatrix = nil, nil, nil
+}
+
 // Shape Interface for different 3D and 2D shape modules
 type Shape interface {
 	Intersect(primitives.Ray) Intersections
@@ -90,3 +135,10 @@ type Shape interface {
 	WorldToObjectPV(primitives.PV) primitives.PV
 	ObjectToWorldPV(primitives.PV) primitives.PV
 }
+
+// Surface Flat shape that can be sampled, such as the emitting surface of an area light
+type Surface interface {
+	Shape
+	// SamplePoint Map U and V in [0, 1] to a world-space point spread evenly over the surface
+	SamplePoint(u, v float64) primitives.PV
+}
@@ -96,3 +96,26 @@ func TestObjectToWorldPV(t * testing.T) {
 		}
 	}
 }
+
+func TestObjectToWorldMatrix(t *testing.T) {
+	outer, inner, sphere := shapes.MakeGroup(), shapes.MakeGroup(), shapes.MakeSphere()
+	outer.SetTransform(primitives.Translation(1, 0, 0))
+	inner.SetTransform(primitives.Translation(0, 2, 0).Multiply(primitives.Scaling(2, 2, 2)))
+	sphere.SetTransform(primitives.Translation(0, 0, 3))
+	outer.AddShape(inner)
+	inner.AddShape(sphere)
+	tables := []struct {
+		shape         shapes.Shape
+		point, result primitives.PV
+	}{
+		{nil, primitives.MakePoint(1, 2, 3), primitives.MakePoint(1, 2, 3)},
+		{outer, primitives.MakePoint(1, 1, 1), primitives.MakePoint(2, 1, 1)},
+		{inner, primitives.MakePoint(1, 1, 1), primitives.MakePoint(3, 4, 2)},
+		{sphere, primitives.MakePoint(1, 1, 1), primitives.MakePoint(3, 4, 8)},
+	}
+	for _, table := range tables {
+		if point := table.point.Transform(shapes.ObjectToWorldMatrix(table.shape)); !point.Equals(table.result) {
+			t.Errorf("Shape %v, expected %v, got %v", table.shape, table.result, point)
+		}
+	}
+}
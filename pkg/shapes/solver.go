@@ -0,0 +1,167 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+)
+
+// solverEpsilon Tolerance used to treat polynomial terms as zero
+const solverEpsilon = 1e-12
+
+func isZero(value float64) bool {
+	return math.Abs(value) < solverEpsilon
+}
+
+// SolveQuadratic Return the real roots of ax^2 + bx + c in ascending order
+func SolveQuadratic(a, b, c float64) []float64 {
+	if isZero(a) {
+		if isZero(b) {
+			return []float64{}
+		}
+		return []float64{-c / b}
+	}
+	// Normal form x^2 + 2px + q
+	p := b / (2 * a)
+	q := c / a
+	discriminant := (p * p) - q
+	if isZero(discriminant) {
+		return []float64{-p}
+	}
+	if discriminant < 0 {
+		return []float64{}
+	}
+	// Avoid cancellation by computing the larger root first
+	sqrtD := math.Sqrt(discriminant)
+	root1 := -p - math.Copysign(sqrtD, p)
+	root2 := q / root1
+	if root1 == 0 {
+		root2 = 0
+	}
+	if root1 > root2 {
+		root1, root2 = root2, root1
+	}
+	return []float64{root1, root2}
+}
+
+// SolveCubic Return the real roots of ax^3 + bx^2 + cx + d in ascending order
+func SolveCubic(a, b, c, d float64) []float64 {
+	if isZero(a) {
+		return SolveQuadratic(b, c, d)
+	}
+	// Normal form x^3 + Ax^2 + Bx + C
+	A := b / a
+	B := c / a
+	C := d / a
+	// Substitute x = y - A/3 to eliminate the quadratic term: y^3 + 3py + 2q
+	sqA := A * A
+	p := ((-sqA / 3) + B) / 3
+	q := (((2.0 / 27.0) * A * sqA) - (A * B / 3) + C) / 2
+	cbP := p * p * p
+	discriminant := (q * q) + cbP
+	var roots []float64
+	if isZero(discriminant) {
+		if isZero(q) {
+			roots = []float64{0}
+		} else {
+			u := math.Cbrt(-q)
+			roots = []float64{2 * u, -u}
+		}
+	} else if discriminant < 0 {
+		// Three real roots, use the trigonometric solution
+		phi := math.Acos(math.Max(-1, math.Min(1, -q/math.Sqrt(-cbP)))) / 3
+		t := 2 * math.Sqrt(-p)
+		roots = []float64{t * math.Cos(phi), -t * math.Cos(phi+(math.Pi/3)), -t * math.Cos(phi-(math.Pi/3))}
+	} else {
+		sqrtD := math.Sqrt(discriminant)
+		roots = []float64{math.Cbrt(sqrtD-q) - math.Cbrt(sqrtD+q)}
+	}
+	for i := range roots {
+		roots[i] = polish(roots[i]-(A/3), []float64{1, A, B, C})
+	}
+	return uniqueRoots(roots)
+}
+
+// SolveQuartic Return the real roots of ax^4 + bx^3 + cx^2 + dx + e in ascending order
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	if isZero(a) {
+		return SolveCubic(b, c, d, e)
+	}
+	// Normal form x^4 + Ax^3 + Bx^2 + Cx + D
+	A := b / a
+	B := c / a
+	C := d / a
+	D := e / a
+	// Substitute x = y - A/4 to eliminate the cubic term: y^4 + py^2 + qy + r
+	sqA := A * A
+	p := ((-3.0 / 8.0) * sqA) + B
+	q := ((1.0 / 8.0) * sqA * A) - (0.5 * A * B) + C
+	r := ((-3.0 / 256.0) * sqA * sqA) + ((1.0 / 16.0) * sqA * B) - (0.25 * A * C) + D
+	var roots []float64
+	if isZero(r) {
+		// No absolute term: y(y^3 + py + q) = 0
+		roots = append(SolveCubic(1, 0, p, q), 0)
+	} else {
+		// Solve the resolvent cubic and take one real root to split into two quadratics
+		cubic := SolveCubic(1, -0.5*p, -r, (0.5*r*p)-((1.0/8.0)*q*q))
+		z := cubic[len(cubic)-1]
+		u := (z * z) - r
+		v := (2 * z) - p
+		if isZero(u) {
+			u = 0
+		} else if u > 0 {
+			u = math.Sqrt(u)
+		} else {
+			return []float64{}
+		}
+		if isZero(v) {
+			v = 0
+		} else if v > 0 {
+			v = math.Sqrt(v)
+		} else {
+			return []float64{}
+		}
+		if q < 0 {
+			v = -v
+		}
+		roots = append(SolveQuadratic(1, v, z-u), SolveQuadratic(1, -v, z+u)...)
+	}
+	coefficients := []float64{1, A, B, C, D}
+	for i := range roots {
+		roots[i] = polish(roots[i]-(A/4), coefficients)
+	}
+	return uniqueRoots(roots)
+}
+
+// uniqueRoots Sort roots in ascending order, merging repeated roots into one
+func uniqueRoots(roots []float64) []float64 {
+	sort.Float64s(roots)
+	unique := roots[:0]
+	for _, root := range roots {
+		if len(unique) > 0 && math.Abs(root-unique[len(unique)-1]) < 1e-9 {
+			continue
+		}
+		unique = append(unique, root)
+	}
+	return unique
+}
+
+// polish Refine a root of a polynomial, given highest degree first, with a few Newton iterations
+func polish(root float64, coefficients []float64) float64 {
+	for iteration := 0; iteration < 3; iteration++ {
+		value := 0.0
+		derivative := 0.0
+		for _, coefficient := range coefficients {
+			derivative = (derivative * root) + value
+			value = (value * root) + coefficient
+		}
+		if derivative == 0 {
+			break
+		}
+		next := root - (value / derivative)
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		root = next
+	}
+	return root
+}
@@ -0,0 +1,80 @@
+package shapes_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func rootsEqual(roots, expected []float64) bool {
+	if len(roots) != len(expected) {
+		return false
+	}
+	for i := range roots {
+		if math.Abs(roots[i]-expected[i]) > primitives.EPSILON {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSolveQuadratic(t *testing.T) {
+	tables := []struct {
+		a, b, c float64
+		roots   []float64
+	}{
+		{1, -3, 2, []float64{1, 2}},
+		{1, 2, 1, []float64{-1}},
+		{0, 2, -4, []float64{2}},
+		{1, 0, 1, []float64{}},
+		{1, 0, 0, []float64{0}},
+	}
+	for _, table := range tables {
+		roots := shapes.SolveQuadratic(table.a, table.b, table.c)
+		if !rootsEqual(roots, table.roots) {
+			t.Errorf("Expected roots %v, got %v", table.roots, roots)
+		}
+	}
+}
+
+func TestSolveCubic(t *testing.T) {
+	tables := []struct {
+		a, b, c, d float64
+		roots      []float64
+	}{
+		{1, -6, 11, -6, []float64{1, 2, 3}},
+		{2, -12, 22, -12, []float64{1, 2, 3}},
+		{1, 0, 0, -8, []float64{2}},
+		{1, -3, 3, -1, []float64{1}},
+		{0, 1, -3, 2, []float64{1, 2}},
+	}
+	for _, table := range tables {
+		roots := shapes.SolveCubic(table.a, table.b, table.c, table.d)
+		if !rootsEqual(roots, table.roots) {
+			t.Errorf("Expected roots %v, got %v", table.roots, roots)
+		}
+	}
+}
+
+func TestSolveQuartic(t *testing.T) {
+	tables := []struct {
+		a, b, c, d, e float64
+		roots         []float64
+	}{
+		{1, -10, 35, -50, 24, []float64{1, 2, 3, 4}},
+		{2, 0, -10, 0, 8, []float64{-2, -1, 1, 2}},
+		{1, -6, 13, -12, 4, []float64{1, 2}},
+		{1, 0, 0, 0, -1, []float64{-1, 1}},
+		{1, 0, 0, 0, 1, []float64{}},
+		{1, -2, 0, 0, 0, []float64{0, 2}},
+		{0, 1, -6, 11, -6, []float64{1, 2, 3}},
+	}
+	for _, table := range tables {
+		roots := shapes.SolveQuartic(table.a, table.b, table.c, table.d, table.e)
+		if !rootsEqual(roots, table.roots) {
+			t.Errorf("Expected roots %v, got %v", table.roots, roots)
+		}
+	}
+}
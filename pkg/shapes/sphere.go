@@ -24,7 +24,11 @@ func (s *Sphere) GetBounds() *Bounds {
 
 // Intersect Check if a ray intersects
 func (s *Sphere) Intersect(r primitives.Ray) Intersections {
-	hits := Intersections{}
+	return s.AppendIntersections(r, Intersections{})
+}
+
+// AppendIntersections Add the hits of a ray to the end of hits, reusing its space
+func (s *Sphere) AppendIntersections(r primitives.Ray, hits Intersections) Intersections {
 	// convert ray to object space
 	oray := r.Transform(s.Inverse())
 	// Vector from the sphere's center
@@ -80,6 +80,17 @@ func BenchmarkSphereIntersection(b *testing.B) {
 	}
 }
 
+func BenchmarkSphereAppendIntersections(b *testing.B) {
+	sphere := shapes.MakeSphere()
+	sphere.SetTransform(primitives.Scaling(0.5, 0.5, 0.5))
+	ray := primitives.Ray{Origin: primitives.MakePoint(0, 0, -2), Direction: primitives.MakeVector(0, 0, 1)}
+	hits := shapes.Intersections{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hits = sphere.AppendIntersections(ray, hits[:0])
+	}
+}
+
 func TestSphereNormal(t *testing.T) {
 	tables := []struct {
 		s             *shapes.Sphere
@@ -0,0 +1,61 @@
+package shapes
+
+import (
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+// Kinds of shape intersection tests are counted by, kept so tests don't look them up by name
+var (
+	sphereTests       = stats.ShapeTests("Sphere")
+	planeTests        = stats.ShapeTests("Plane")
+	cubeTests         = stats.ShapeTests("Cube")
+	cylinderTests     = stats.ShapeTests("Cylinder")
+	coneTests         = stats.ShapeTests("Cone")
+	diskTests         = stats.ShapeTests("Disk")
+	quadTests         = stats.ShapeTests("Quad")
+	torusTests        = stats.ShapeTests("Torus")
+	triangleTests     = stats.ShapeTests("Triangle")
+	meshTriangleTests = stats.ShapeTests("MeshTriangle")
+	heightFieldTests  = stats.ShapeTests("HeightField")
+	bezierTests       = stats.ShapeTests("BezierPatch")
+	sdfTests          = stats.ShapeTests("SDF")
+	otherTests        = stats.ShapeTests("Other")
+)
+
+// countTests Count rays tested against a shape, when they are counted. Shapes holding others only pass the rays on,
+// so they are counted by the tests of their bounds and of the shapes they hold instead
+func countTests(shape Shape, counts *stats.Counts, rays uint64) {
+	if counts == nil {
+		return
+	}
+	counter := otherTests
+	switch shape.(type) {
+	case *Sphere:
+		counter = sphereTests
+	case *Plane:
+		counter = planeTests
+	case *Cube:
+		counter = cubeTests
+	case *Cylinder:
+		counter = cylinderTests
+	case *Cone:
+		counter = coneTests
+	case *Disk:
+		counter = diskTests
+	case *Quad:
+		counter = quadTests
+	case *Torus:
+		counter = torusTests
+	case *Triangle:
+		counter = triangleTests
+	case *HeightField:
+		counter = heightFieldTests
+	case *BezierPatch:
+		counter = bezierTests
+	case *SDF:
+		counter = sdfTests
+	case *Group, *Mesh, *Instance, *CSG:
+		return
+	}
+	counts.AddShapeTests(counter, rays)
+}
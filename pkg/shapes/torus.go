@@ -0,0 +1,76 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+)
+
+// Torus Ring around the y-axis with a tube of the minor radius at the major radius from the center
+type Torus struct {
+	ShapeBase
+	major, minor float64
+}
+
+// MakeTorus Make a torus with an identity matrix for transform
+func MakeTorus(major, minor float64) *Torus {
+	return &Torus{MakeShapeBase(), major, minor}
+}
+
+// GetBounds Return an axis aligned bounding box for the torus
+func (torus *Torus) GetBounds() *Bounds {
+	outer := torus.major + torus.minor
+	bounds := &Bounds{Min: primitives.MakePoint(-outer, -torus.minor, -outer),
+		Max: primitives.MakePoint(outer, torus.minor, outer)}
+	return bounds.Transform(torus.transform)
+}
+
+// Intersect Check if a ray intersects
+func (torus *Torus) Intersect(r primitives.Ray) Intersections {
+	hits := Intersections{}
+	// convert ray to object space
+	oray := r.Transform(torus.Inverse())
+	dd := oray.Direction.DotProduct(oray.Direction)
+	// Move the origin to the point on the ray closest to the center, keeping the quartic well conditioned
+	shift := -(oray.Origin.X*oray.Direction.X + oray.Origin.Y*oray.Direction.Y + oray.Origin.Z*oray.Direction.Z) / dd
+	origin := oray.Origin.Add(oray.Direction.Scalar(shift))
+	outer := torus.major + torus.minor
+	oo := (origin.X * origin.X) + (origin.Y * origin.Y) + (origin.Z * origin.Z)
+	// Miss the bounding sphere entirely
+	if oo > outer*outer {
+		return hits
+	}
+	od := (origin.X * oray.Direction.X) + (origin.Y * oray.Direction.Y) + (origin.Z * oray.Direction.Z)
+	e := oo - (torus.major * torus.major) - (torus.minor * torus.minor)
+	fourR2 := 4 * torus.major * torus.major
+	roots := SolveQuartic(dd*dd,
+		4*dd*od,
+		(2*dd*e)+(4*od*od)+(fourR2*oray.Direction.Y*oray.Direction.Y),
+		(4*od*e)+(2*fourR2*origin.Y*oray.Direction.Y),
+		(e*e)-(fourR2*((torus.minor*torus.minor)-(origin.Y*origin.Y))))
+	for _, root := range roots {
+		hits = append(hits, Intersection{Distance: root + shift, Obj: torus})
+	}
+	return hits
+}
+
+// Normal Calculate the normal at a given point on the torus
+func (torus *Torus) Normal(worldPoint primitives.PV, u, v float64) primitives.PV {
+	objectPoint := torus.WorldToObjectPV(worldPoint)
+	param := (objectPoint.X * objectPoint.X) + (objectPoint.Y * objectPoint.Y) + (objectPoint.Z * objectPoint.Z) -
+		(torus.major * torus.major) - (torus.minor * torus.minor)
+	objectNormal := primitives.MakeVector(objectPoint.X*param,
+		objectPoint.Y*(param+(2*torus.major*torus.major)), objectPoint.Z*param)
+	worldNormal := torus.ObjectToWorldPV(objectNormal)
+	worldNormal.W = 0.0
+	return worldNormal.Normalize()
+}
+
+// UVMapping Return the 2D coordinates of an intersection point, U around the ring and V around the tube
+func (torus *Torus) UVMapping(point primitives.PV) primitives.PV {
+	objectPoint := torus.WorldToObjectPV(point)
+	radial := math.Sqrt((objectPoint.X * objectPoint.X) + (objectPoint.Z * objectPoint.Z))
+	u := 0.5 + math.Atan2(objectPoint.X, objectPoint.Z)/(2*math.Pi)
+	v := 0.5 + math.Atan2(objectPoint.Y, radial-torus.major)/(2*math.Pi)
+	return primitives.MakePoint(u, v, 0)
+}
@@ -0,0 +1,148 @@
+package shapes_test
+
+import (
+	"testing"
+
+	"github.com/factorion/graytracer/pkg/primitives"
+	"github.com/factorion/graytracer/pkg/shapes"
+)
+
+func TestTorusGetBounds(t *testing.T) {
+	tables := []struct {
+		torus     *shapes.Torus
+		transform primitives.Matrix
+		min, max  primitives.PV
+	}{
+		{shapes.MakeTorus(1, 0.25),
+			primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(-1.25, -0.25, -1.25), primitives.MakePoint(1.25, 0.25, 1.25)},
+
+		{shapes.MakeTorus(2, 1),
+			primitives.Translation(0, 1, 0),
+			primitives.MakePoint(-3, 0, -3), primitives.MakePoint(3, 2, 3)},
+	}
+	for _, table := range tables {
+		table.torus.SetTransform(table.transform)
+		bounds := table.torus.GetBounds()
+		if !bounds.Min.Equals(table.min) {
+			t.Errorf("Expected Minimum %v, got %v", table.min, bounds.Min)
+		}
+		if !bounds.Max.Equals(table.max) {
+			t.Errorf("Expected Maximum %v, got %v", table.max, bounds.Max)
+		}
+	}
+}
+
+func TestTorusIntersection(t *testing.T) {
+	tables := []struct {
+		torus     *shapes.Torus
+		r         primitives.Ray
+		transform primitives.Matrix
+		hits      []float64
+	}{
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(-5, 0, 0), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{3.75, 4.25, 5.75, 6.25}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(0, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(1, 5, 0), Direction: primitives.MakeVector(0, -1, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{4.75, 5.25}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0.25, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{4, 6}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(0, 0, 0), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{-1.25, -0.75, 0.75, 1.25}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(-1000, 0, 0), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.MakeIdentityMatrix(4), []float64{998.75, 999.25, 1000.75, 1001.25}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(-10, 0, 0), Direction: primitives.MakeVector(1, 0, 0)},
+			primitives.Scaling(2, 2, 2), []float64{7.5, 8.5, 11.5, 12.5}},
+
+		{shapes.MakeTorus(1, 0.25),
+			primitives.Ray{Origin: primitives.MakePoint(0, 3, -5), Direction: primitives.MakeVector(0, 0, 1)},
+			primitives.MakeIdentityMatrix(4), []float64{}},
+	}
+	for _, table := range tables {
+		table.torus.SetTransform(table.transform)
+		hits := table.torus.Intersect(table.r)
+		if !shapes.IntersectEquals(hits, table.hits) {
+			t.Errorf("Expected hit %v, got %v", table.hits, hits)
+		}
+	}
+}
+
+func BenchmarkTorusIntersection(b *testing.B) {
+	torus := shapes.MakeTorus(1, 0.25)
+	ray := primitives.Ray{Origin: primitives.MakePoint(-5, 0, 0), Direction: primitives.MakeVector(1, 0, 0)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		torus.Intersect(ray)
+	}
+}
+
+func TestTorusNormal(t *testing.T) {
+	tables := []struct {
+		torus         *shapes.Torus
+		transform     primitives.Matrix
+		point, normal primitives.PV
+	}{
+		{shapes.MakeTorus(1, 0.25), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(1.25, 0, 0), primitives.MakeVector(1, 0, 0)},
+
+		{shapes.MakeTorus(1, 0.25), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0.75, 0, 0), primitives.MakeVector(-1, 0, 0)},
+
+		{shapes.MakeTorus(1, 0.25), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0.25, 1), primitives.MakeVector(0, 1, 0)},
+
+		{shapes.MakeTorus(1, 0.25), primitives.MakeIdentityMatrix(4),
+			primitives.MakePoint(0, 0, -1.25), primitives.MakeVector(0, 0, -1)},
+
+		{shapes.MakeTorus(1, 0.25), primitives.Translation(0, 1, 0),
+			primitives.MakePoint(1, 0.75, 0), primitives.MakeVector(0, -1, 0)},
+	}
+	for _, table := range tables {
+		table.torus.SetTransform(table.transform)
+		normal := table.torus.Normal(table.point, 0.0, 0.0)
+		if !normal.Equals(table.normal) {
+			t.Errorf("Expected %v, got %v", table.normal, normal)
+		}
+	}
+}
+
+func BenchmarkTorusNormal(b *testing.B) {
+	torus := shapes.MakeTorus(1, 0.25)
+	point := primitives.MakePoint(1.25, 0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		torus.Normal(point, 0.0, 0.0)
+	}
+}
+
+func TestTorusUVMapping(t *testing.T) {
+	tables := []struct {
+		point, uv primitives.PV
+	}{
+		{primitives.MakePoint(0, 0, 1.25), primitives.MakePoint(0.5, 0.5, 0)},
+		{primitives.MakePoint(1.25, 0, 0), primitives.MakePoint(0.75, 0.5, 0)},
+		{primitives.MakePoint(0, 0.25, 1), primitives.MakePoint(0.5, 0.75, 0)},
+		{primitives.MakePoint(0, -0.25, 1), primitives.MakePoint(0.5, 0.25, 0)},
+	}
+	for _, table := range tables {
+		torus := shapes.MakeTorus(1, 0.25)
+		uv := torus.UVMapping(table.point)
+		if !uv.Equals(table.uv) {
+			t.Errorf("Point: %v, Expected %v, got %v", table.point, table.uv, uv)
+		}
+	}
+}
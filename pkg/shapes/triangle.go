@@ -32,6 +32,28 @@ func MakeSmoothTriangle(point1, point2, point3, normal1, normal2, normal3 primit
 	return &Triangle{MakeShapeBase(), point1, point2, point3, edge1, edge2, normal1, normal2, normal3, true}
 }
 
+// bake Move the points and normals of the triangle by the transform, leaving the triangle with an identity matrix
+func (t *Triangle) bake(m primitives.Matrix) {
+	identity := primitives.MakeIdentityMatrix(4)
+	if m.Equals(identity) {
+		t.ShapeBase.SetTransform(identity)
+		return
+	}
+	inverse, _ := m.Inverse()
+	normalMatrix := inverse.Transpose()
+	t.Point1, t.Point2, t.Point3 = t.Point1.Transform(m), t.Point2.Transform(m), t.Point3.Transform(m)
+	t.Edge1 = t.Point2.Subtract(t.Point1)
+	t.Edge1.W = 0
+	t.Edge2 = t.Point3.Subtract(t.Point1)
+	t.Edge2.W = 0
+	// Normals are left unnormalized so interpolating them matches interpolating before the transform
+	for _, normal := range []*primitives.PV{&t.Normal1, &t.Normal2, &t.Normal3} {
+		*normal = normal.Transform(normalMatrix)
+		normal.W = 0
+	}
+	t.ShapeBase.SetTransform(identity)
+}
+
 // GetBounds Return an axis aligned bounding box for the triangle
 func (t *Triangle) GetBounds() *Bounds {
 	x_min, x_max := MinMax([]float64{t.Point1.X, t.Point2.X, t.Point3.X})
@@ -43,7 +65,11 @@ func (t *Triangle) GetBounds() *Bounds {
 
 // Intersect Check if a ray intersects
 func (t *Triangle) Intersect(ray primitives.Ray) Intersections {
-	hits := Intersections{}
+	return t.AppendIntersections(ray, Intersections{})
+}
+
+// AppendIntersections Add the hits of a ray to the end of hits, reusing its space
+func (t *Triangle) AppendIntersections(ray primitives.Ray, hits Intersections) Intersections {
 	// convert ray to object space
 	oray := ray.Transform(t.inverse)
 	dce2 := oray.Direction.CrossProduct(t.Edge2) // Direction crossed with edge 2
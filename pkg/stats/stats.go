@@ -0,0 +1,240 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RayKind Kind of ray, which rays are counted by
+type RayKind int
+
+const (
+	// PrimaryRay Ray from the camera through a pixel
+	PrimaryRay RayKind = iota
+	// ShadowRay Ray from a hit towards a light
+	ShadowRay
+	// ReflectionRay Ray bouncing off a reflective surface
+	ReflectionRay
+	// RefractionRay Ray passing into or out of a transparent surface
+	RefractionRay
+	// rayKinds Number of kinds of ray
+	rayKinds
+)
+
+// ShapeKind Kind of shape, whose intersection tests are counted together
+type ShapeKind int
+
+// Counts Rays and tests counted by one rendering goroutine, which updates them without any atomics and merges them
+// into the Collector of the render when it is done. A nil Counts counts nothing, so tracing without counting costs
+// a nil check
+type Counts struct {
+	// Rays Rays traced by kind, counted when the ray is traced rather than when it is made
+	Rays [rayKinds]uint64
+	// BoundsTests Rays tested against axis aligned bounding boxes, for groups, CSGs and the nodes of hierarchies
+	BoundsTests uint64
+	// ShapeTests Intersection tests by kind of shape, groups, meshes, instances and CSGs only count the tests of
+	// their bounds and of the shapes they hold
+	ShapeTests []uint64
+	// MaxDepth Deepest bounce reached, primary rays are at depth 0
+	MaxDepth uint64
+}
+
+// AddRay Count a ray of a kind
+func (c *Counts) AddRay(kind RayKind) {
+	if c != nil {
+		c.Rays[kind]++
+	}
+}
+
+// ObserveDepth Raise the deepest bounce to depth when it is deeper
+func (c *Counts) ObserveDepth(depth uint64) {
+	if c != nil && depth > c.MaxDepth {
+		c.MaxDepth = depth
+	}
+}
+
+// AddBoundsTests Count rays tested against a bounding box
+func (c *Counts) AddBoundsTests(tests uint64) {
+	if c != nil {
+		c.BoundsTests += tests
+	}
+}
+
+// AddShapeTests Count rays tested against a kind of shape
+func (c *Counts) AddShapeTests(kind ShapeKind, tests uint64) {
+	if c == nil {
+		return
+	}
+	if int(kind) >= len(c.ShapeTests) {
+		c.ShapeTests = append(c.ShapeTests, make([]uint64, int(kind)+1-len(c.ShapeTests))...)
+	}
+	c.ShapeTests[kind] += tests
+}
+
+// Tests Get the total number of bounding box and intersection tests counted
+func (c *Counts) Tests() uint64 {
+	if c == nil {
+		return 0
+	}
+	total := c.BoundsTests
+	for _, tests := range c.ShapeTests {
+		total += tests
+	}
+	return total
+}
+
+// Add Add the counts of another goroutine to these
+func (c *Counts) Add(other *Counts) {
+	if c == nil || other == nil {
+		return
+	}
+	for kind, rays := range other.Rays {
+		c.Rays[kind] += rays
+	}
+	c.BoundsTests += other.BoundsTests
+	for kind, tests := range other.ShapeTests {
+		c.AddShapeTests(ShapeKind(kind), tests)
+	}
+	c.ObserveDepth(other.MaxDepth)
+}
+
+// Timer Total time spent in a phase, which may be added to by several goroutines
+type Timer struct {
+	nanos int64
+}
+
+// Add Add a duration to the total
+func (t *Timer) Add(duration time.Duration) {
+	atomic.AddInt64(&t.nanos, int64(duration))
+}
+
+// Since Add the time since start to the total
+func (t *Timer) Since(start time.Time) {
+	t.Add(time.Since(start))
+}
+
+// Load Get the total time
+func (t *Timer) Load() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.nanos))
+}
+
+// Collector Counts and times of one render, owned by whoever runs it. The goroutines rendering it merge their
+// counts when they are done, and timers may be added to from any goroutine. The zero value is empty and ready to use
+type Collector struct {
+	mutex  sync.Mutex
+	counts Counts
+	// ParseTime, BuildTime, RenderTime, DenoiseTime, EncodeTime Time spent parsing files, building hierarchies,
+	// rendering, denoising and encoding the image
+	ParseTime, BuildTime, RenderTime, DenoiseTime, EncodeTime Timer
+}
+
+// Merge Add the counts of a goroutine that is done to the collector
+func (c *Collector) Merge(counts *Counts) {
+	c.mutex.Lock()
+	c.counts.Add(counts)
+	c.mutex.Unlock()
+}
+
+// shapeNames Names of the kinds of shape, indexed by kind
+var shapeNames = struct {
+	sync.Mutex
+	names []string
+}{}
+
+// ShapeTests Get the kind of shape with a name, registering it the first time. Meant to be called once when a
+// package starts so the kind can be kept rather than looked up for every test
+func ShapeTests(name string) ShapeKind {
+	shapeNames.Lock()
+	defer shapeNames.Unlock()
+	for kind, known := range shapeNames.names {
+		if known == name {
+			return ShapeKind(kind)
+		}
+	}
+	shapeNames.names = append(shapeNames.names, name)
+	return ShapeKind(len(shapeNames.names) - 1)
+}
+
+// Report Copy of every count and time at one moment, durations are written to JSON in nanoseconds
+type Report struct {
+	PrimaryRays    uint64            `json:"primary_rays"`
+	ShadowRays     uint64            `json:"shadow_rays"`
+	ReflectionRays uint64            `json:"reflection_rays"`
+	RefractionRays uint64            `json:"refraction_rays"`
+	BoundsTests    uint64            `json:"bounds_tests"`
+	ShapeTests     map[string]uint64 `json:"shape_tests"`
+	MaxDepth       uint64            `json:"max_depth"`
+	ParseTime      time.Duration     `json:"parse_time"`
+	BuildTime      time.Duration     `json:"build_time"`
+	RenderTime     time.Duration     `json:"render_time"`
+	DenoiseTime    time.Duration     `json:"denoise_time"`
+	EncodeTime     time.Duration     `json:"encode_time"`
+}
+
+// Report Read every count merged into the collector so far and every time, shapes that were never tested are left
+// out
+func (c *Collector) Report() Report {
+	report := Report{ShapeTests: map[string]uint64{}, ParseTime: c.ParseTime.Load(), BuildTime: c.BuildTime.Load(),
+		RenderTime: c.RenderTime.Load(), DenoiseTime: c.DenoiseTime.Load(), EncodeTime: c.EncodeTime.Load()}
+	c.mutex.Lock()
+	counts := c.counts
+	report.PrimaryRays, report.ShadowRays = counts.Rays[PrimaryRay], counts.Rays[ShadowRay]
+	report.ReflectionRays, report.RefractionRays = counts.Rays[ReflectionRay], counts.Rays[RefractionRay]
+	report.BoundsTests, report.MaxDepth = counts.BoundsTests, counts.MaxDepth
+	shapeNames.Lock()
+	for kind, tests := range counts.ShapeTests {
+		if tests > 0 {
+			report.ShapeTests[shapeNames.names[kind]] = tests
+		}
+	}
+	shapeNames.Unlock()
+	c.mutex.Unlock()
+	return report
+}
+
+// Rays Get the total number of rays traced
+func (r Report) Rays() uint64 {
+	return r.PrimaryRays + r.ShadowRays + r.ReflectionRays + r.RefractionRays
+}
+
+// JSON Encode the report as indented JSON
+func (r Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// String Format the report as a table, with the shapes tested most first
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rays                : %d\n", r.Rays())
+	fmt.Fprintf(&b, "  Primary           : %d\n", r.PrimaryRays)
+	fmt.Fprintf(&b, "  Shadow            : %d\n", r.ShadowRays)
+	fmt.Fprintf(&b, "  Reflection        : %d\n", r.ReflectionRays)
+	fmt.Fprintf(&b, "  Refraction        : %d\n", r.RefractionRays)
+	fmt.Fprintf(&b, "Max depth           : %d\n", r.MaxDepth)
+	fmt.Fprintf(&b, "Bounding box tests  : %d\n", r.BoundsTests)
+	names := make([]string, 0, len(r.ShapeTests))
+	for name := range r.ShapeTests {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if r.ShapeTests[names[i]] != r.ShapeTests[names[j]] {
+			return r.ShapeTests[names[i]] > r.ShapeTests[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	b.WriteString("Intersection tests\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-18s: %d\n", name, r.ShapeTests[name])
+	}
+	fmt.Fprintf(&b, "Parse time          : %v\n", r.ParseTime)
+	fmt.Fprintf(&b, "Build time          : %v\n", r.BuildTime)
+	fmt.Fprintf(&b, "Render time         : %v\n", r.RenderTime)
+	fmt.Fprintf(&b, "Denoise time        : %v\n", r.DenoiseTime)
+	fmt.Fprintf(&b, "Encode time         : %v\n", r.EncodeTime)
+	return b.String()
+}
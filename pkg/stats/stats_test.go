@@ -0,0 +1,88 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/factorion/graytracer/pkg/stats"
+)
+
+func TestCounts(t *testing.T) {
+	collector := &stats.Collector{}
+	tests := stats.ShapeTests("Test shape")
+	if stats.ShapeTests("Test shape") != tests {
+		t.Error("Expected the same kind for the same shape")
+	}
+	unused := stats.ShapeTests("Unused shape")
+	// Nothing is counted without any counts
+	var none *stats.Counts
+	none.AddRay(stats.PrimaryRay)
+	none.AddShapeTests(tests, 2)
+	none.ObserveDepth(3)
+	if none.Tests() != 0 {
+		t.Error("Expected nothing to be counted without any counts")
+	}
+	collector.Merge(none)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			counts := &stats.Counts{}
+			for i := 0; i < 1000; i++ {
+				counts.AddRay(stats.ShadowRay)
+				counts.AddShapeTests(tests, 2)
+			}
+			counts.AddShapeTests(unused, 0)
+			counts.ObserveDepth(uint64(worker))
+			if counts.Tests() != 2000 {
+				t.Errorf("Worker %v, expected 2000 tests, got %v", worker, counts.Tests())
+			}
+			collector.Merge(counts)
+			collector.RenderTime.Add(time.Millisecond)
+		}(worker)
+	}
+	wg.Wait()
+	report := collector.Report()
+	if report.ShadowRays != 8000 || report.ShapeTests["Test shape"] != 16000 || report.MaxDepth != 7 ||
+		report.RenderTime != 8*time.Millisecond || report.Rays() != 8000 {
+		t.Errorf("Expected the counts of every goroutine, got %+v", report)
+	}
+	if _, ok := report.ShapeTests["Unused shape"]; ok {
+		t.Error("Expected shapes that were never tested to be left out")
+	}
+	if !strings.Contains(report.String(), "Test shape") {
+		t.Errorf("Expected the report to list the shape, got %v", report)
+	}
+	data, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded stats.Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ShadowRays != 8000 ||
+		decoded.RenderTime != report.RenderTime || decoded.ShapeTests["Test shape"] != 16000 {
+		t.Errorf("Expected the report back from %s, got %+v, %v", data, decoded, err)
+	}
+	// Every render collects its own counts and times
+	if report := (&stats.Collector{}).Report(); report.ShadowRays != 0 || len(report.ShapeTests) != 0 ||
+		report.MaxDepth != 0 || report.RenderTime != 0 {
+		t.Errorf("Expected an empty report from a new collector, got %+v", report)
+	}
+}
+
+func BenchmarkCountsNil(b *testing.B) {
+	var counts *stats.Counts
+	for i := 0; i < b.N; i++ {
+		counts.AddBoundsTests(1)
+	}
+}
+
+func BenchmarkCounts(b *testing.B) {
+	counts := &stats.Counts{}
+	for i := 0; i < b.N; i++ {
+		counts.AddBoundsTests(1)
+	}
+}